@@ -4,9 +4,12 @@ import (
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"os"
+	"os/signal"
 	"path"
 	"path/filepath"
 	"sync/atomic"
+	"syscall"
 	"time"
 
 	"github.com/hashicorp/go-multierror"
@@ -41,6 +44,28 @@ func (d *Framework) Shutdown() error {
 	return result
 }
 
+// ShutdownOnSignal calls Shutdown when the process gets one of the signals,
+// SIGINT and SIGTERM by default, then exits with 128 plus the signal number.
+// The async loggers are synced by Shutdown, so the lines queued are written.
+func (d *Framework) ShutdownOnSignal(sigs ...os.Signal) {
+	if len(sigs) == 0 {
+		sigs = []os.Signal{os.Interrupt, syscall.SIGTERM}
+	}
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, sigs...)
+	go func() {
+		sig := <-ch
+		signal.Stop(ch)
+		if err := d.Shutdown(); err != nil {
+			logging.GenLogf("shutdown on signal %v, error:%v", sig, err)
+		}
+		if n, ok := sig.(syscall.Signal); ok {
+			os.Exit(128 + int(n))
+		}
+		os.Exit(1)
+	}()
+}
+
 func (d *Framework) shutdown() error {
 	var result error
 	d.producerClients.Range(func(key, value interface{}) bool {
@@ -84,7 +109,10 @@ func (d *Framework) Remote(paths ...string) error {
 		if len(p) == 0 {
 			continue
 		}
-		p = filepath.Join(getRegistryKVPath(d.localAppServiceName), p)
+		// http and etcd urls are loaded as is
+		if !config.IsRemoteURL(p) {
+			p = filepath.Join(getRegistryKVPath(d.localAppServiceName), p)
+		}
 		err := d.configInstance.LoadPath(p, false, "toml")
 		if err != nil {
 			return err
@@ -322,7 +350,46 @@ func (d *Framework) HTTPServer() httpserver.Server {
 				c.JSON(nil, ecode.ServerErr)
 				return
 			}
-			logging.SetLevelByString(r.LogLevel)
+			if err := logging.SetLoggerLevel(logging.DefaultLoggerName, r.LogLevel, 0, "admin "+c.Request.RemoteAddr); err != nil {
+				c.JSON(nil, ecode.ParamErr)
+				return
+			}
+			c.JSON(nil, ecode.OK)
+		})
+		// the levels of the named loggers
+		httpServer.GET(_logLevelURI, func(c *httpserver.Context) {
+			c.JSON(logging.Levels(), nil)
+		})
+		// change the level of a named logger, temporarily with a ttl like "10m"
+		httpServer.POST(_logLevelURI, func(c *httpserver.Context) {
+			var r struct {
+				Logger string `json:"logger"`
+				Level  string `json:"level"`
+				TTL    string `json:"ttl"`
+			}
+			buf, err := ioutil.ReadAll(c.Request.Body)
+			if err != nil {
+				c.JSON(nil, ecode.ServerErr)
+				return
+			}
+			if err = json.Unmarshal(buf, &r); err != nil {
+				c.JSON(nil, ecode.ParamErr)
+				return
+			}
+			if r.Logger == "" {
+				r.Logger = logging.DefaultLoggerName
+			}
+			var ttl time.Duration
+			if r.TTL != "" {
+				if ttl, err = time.ParseDuration(r.TTL); err != nil {
+					c.JSON(nil, ecode.ParamErr)
+					return
+				}
+			}
+			if err = logging.SetLoggerLevel(r.Logger, r.Level, ttl, "admin "+c.Request.RemoteAddr); err != nil {
+				c.JSON(nil, ecode.ParamErr)
+				return
+			}
 			c.JSON(nil, ecode.OK)
 		})
 	}
@@ -0,0 +1,53 @@
+package breaker
+
+import (
+	"context"
+
+	"github.com/yunfeiyang1916/toolkit/go-upstream/circuit"
+)
+
+// ErrOverload 超过自适应并发上限, 请求被直接拒绝
+var ErrOverload = circuit.ErrAdaptiveConcurrency
+
+// AdaptiveConfig 自适应并发限制配置, 根据请求延迟调整允许的并发数, 超出的请求直接拒绝
+type AdaptiveConfig struct {
+	AdaptiveConcurrency  bool   `toml:"adaptive_concurrency"`   // 自适应并发限制开关
+	AdaptiveAlgorithm    string `toml:"adaptive_algorithm"`     // gradient或vegas, 默认gradient
+	AdaptiveInitialLimit int64  `toml:"adaptive_initial_limit"` // 初始并发数, 默认20
+	AdaptiveMinLimit     int64  `toml:"adaptive_min_limit"`     // 最小并发数, 默认1
+	AdaptiveMaxLimit     int64  `toml:"adaptive_max_limit"`     // 最大并发数, 默认1000
+}
+
+func (c AdaptiveConfig) newLimiter(name string) *circuit.AdaptiveLimiter {
+	if !c.AdaptiveConcurrency {
+		return nil
+	}
+	return circuit.NewAdaptiveLimiter(name, circuit.AdaptiveOptions{
+		Algorithm:    c.AdaptiveAlgorithm,
+		InitialLimit: c.AdaptiveInitialLimit,
+		MinLimit:     c.AdaptiveMinLimit,
+		MaxLimit:     c.AdaptiveMaxLimit,
+	})
+}
+
+// Adaptive returns the adaptive concurrency limiter of the breaker, nil when
+// adaptive_concurrency is off.
+func (cb *Breaker) Adaptive() *circuit.AdaptiveLimiter {
+	return cb.adaptive
+}
+
+// Shed calls fn unless the in-flight calls reached the adaptive concurrency
+// limit, in which case fn is not called and ErrOverload is returned. A fn
+// which times out lowers the limit.
+func (cb *Breaker) Shed(fn func() error) error {
+	if cb.adaptive == nil {
+		return fn()
+	}
+	release, ok := cb.adaptive.Acquire()
+	if !ok {
+		return ErrOverload
+	}
+	err := fn()
+	release(err == ErrTimeout || err == context.DeadlineExceeded)
+	return err
+}
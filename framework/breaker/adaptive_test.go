@@ -0,0 +1,70 @@
+package breaker
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yunfeiyang1916/toolkit/toml"
+)
+
+func TestAdaptiveConfigDecode(t *testing.T) {
+	var c struct {
+		Breaker map[string]BreakerConfig `toml:"breaker"`
+	}
+	_, err := toml.Decode(`
+[breaker."/api/sms/send"]
+error_percent_threshold = 50
+adaptive_concurrency = true
+adaptive_algorithm = "vegas"
+adaptive_max_limit = 100
+`, &c)
+	assert.Nil(t, err)
+	cfg := c.Breaker["/api/sms/send"]
+	assert.Equal(t, 50, cfg.ErrorPercentThreshold)
+	assert.Equal(t, AdaptiveConfig{
+		AdaptiveConcurrency: true,
+		AdaptiveAlgorithm:   "vegas",
+		AdaptiveMaxLimit:    100,
+	}, cfg.AdaptiveConfig)
+}
+
+func TestBreakerShed(t *testing.T) {
+	c := NewConfig([]BreakerConfig{
+		{
+			Name: "adaptive@server@*",
+			AdaptiveConfig: AdaptiveConfig{
+				AdaptiveConcurrency:  true,
+				AdaptiveInitialLimit: 1,
+				AdaptiveMaxLimit:     1,
+			},
+		},
+	})
+	brk := c.GetBreaker(ServerBreakerType, "adaptive", "", "/shed")
+	assert.NotNil(t, brk.Adaptive())
+	assert.Equal(t, int64(1), brk.Adaptive().Limit())
+
+	// the call past the limit is shed without running
+	called := false
+	err := brk.Shed(func() error {
+		return brk.Shed(func() error {
+			called = true
+			return nil
+		})
+	})
+	assert.Equal(t, ErrOverload, err)
+	assert.False(t, called)
+	assert.Equal(t, int64(0), brk.Adaptive().Inflight())
+
+	// errors of fn are returned as is and free the slot
+	fail := errors.New("fail")
+	assert.Equal(t, fail, brk.Shed(func() error { return fail }))
+	assert.Nil(t, brk.Shed(func() error { return nil }))
+
+	// without adaptive_concurrency nothing is shed
+	brk = NewConfig([]BreakerConfig{{Name: "plain@server@*"}}).GetBreaker(ServerBreakerType, "plain", "", "/shed")
+	assert.Nil(t, brk.Adaptive())
+	assert.Nil(t, brk.Shed(func() error {
+		return brk.Shed(func() error { return nil })
+	}))
+}
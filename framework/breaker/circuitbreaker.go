@@ -38,6 +38,7 @@ import (
 
 	"github.com/cenk/backoff"
 	"github.com/facebookgo/clock"
+	"github.com/yunfeiyang1916/toolkit/go-upstream/circuit"
 )
 
 type state int
@@ -92,6 +93,9 @@ type Breaker struct {
 	// A breaker created with NewBreaker will not have a ShouldTrip by default, and thus will
 	// never automatically trip.
 	shouldTrip []TripFunc
+
+	// adaptive sheds the calls beyond the adaptive concurrency limit, see Shed.
+	adaptive *circuit.AdaptiveLimiter
 }
 
 // Options holds breaker configuration options.
@@ -43,6 +43,7 @@ func (c *Config) GetBreaker(brkType int, namespace, clientname, resource string)
 		ConsecutiveErrorThreshold: cfg.ConsecutiveErrorThreshold,
 		MinSamples:                cfg.MinSamples,
 		Break:                     cfg.Break,
+		AdaptiveConfig:            cfg.AdaptiveConfig,
 	})
 
 	watcher.Store(brkName, brk)
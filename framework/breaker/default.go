@@ -26,6 +26,7 @@ func InitDefaultConfig(configs []BreakerConfig) {
 			ConsecutiveErrorThreshold: v.ConsecutiveErrorThreshold,
 			MinSamples:                v.MinSamples,
 			Break:                     v.Break,
+			AdaptiveConfig:            v.AdaptiveConfig,
 		}
 		templateConfigs.Store(v.Name, &c)
 
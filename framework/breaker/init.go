@@ -20,6 +20,7 @@ type BreakerConfig struct {
 	ConsecutiveErrorThreshold int    `toml:"consecutive_error_threshold"`
 	MinSamples                int    `toml:"minsamples"`
 	Break                     bool   `toml:"break"`
+	AdaptiveConfig
 }
 
 func init() {
@@ -36,8 +37,9 @@ func initBreaker(c BreakerConfig) *Breaker {
 	if c.Break {
 		brk.Break()
 	}
+	brk.adaptive = c.newLimiter(c.Name)
 
-	logging.GenLogf("on breaker, name:%s, break:%v, error_percent:%d, consecutive_error:%d, minsamples:%d", c.Name, c.Break, c.ErrorPercentThreshold, c.ConsecutiveErrorThreshold, c.MinSamples)
+	logging.GenLogf("on breaker, name:%s, break:%v, error_percent:%d, consecutive_error:%d, minsamples:%d, adaptive_concurrency:%v", c.Name, c.Break, c.ErrorPercentThreshold, c.ConsecutiveErrorThreshold, c.MinSamples, c.AdaptiveConcurrency)
 	return brk
 }
 
@@ -57,6 +59,7 @@ func initBreakers(configs []BreakerConfig) {
 				ConsecutiveErrorThreshold: v.ConsecutiveErrorThreshold,
 				MinSamples:                v.MinSamples,
 				Break:                     v.Break,
+				AdaptiveConfig:            v.AdaptiveConfig,
 			})
 			logging.GenLogf("on breaker, template name:%s, break:%v, error_percent:%d, consecutive_error:%d, minsamples:%d", v.Name, v.Break, v.ErrorPercentThreshold, v.ConsecutiveErrorThreshold, v.MinSamples)
 			continue
@@ -84,6 +87,7 @@ func injectBreakConfig(configs []BreakerConfig) {
 					ConsecutiveErrorThreshold: v.ConsecutiveErrorThreshold,
 					MinSamples:                v.MinSamples,
 					Break:                     v.Break,
+					AdaptiveConfig:            v.AdaptiveConfig,
 				})
 				continue
 			}
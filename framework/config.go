@@ -66,6 +66,9 @@ type ServerClient struct {
 	LoadBalanceStat     bool   `toml:"loadbalance_stat"`
 	DC                  string `toml:"dc,omitempty"`
 
+	// endpoints_from="etcd"时使用的etcd地址,为空时使用[registry.etcd]
+	EtcdEndpoints []string `toml:"etcd_endpoints"`
+
 	// checker config
 	CheckInterval      upstreamconfig.Duration `toml:"check_interval"`
 	UnHealthyThreshold uint32                  `toml:"check_unhealth_threshold"`
@@ -127,6 +130,10 @@ type frameworkConfig struct {
 		AliveInterval int `toml:"alive_interval"`
 	} `toml:"monitor"`
 
+	Registry struct {
+		Etcd EtcdConfig `toml:"etcd"` // endpoints_from="etcd"的server_client使用的etcd
+	} `toml:"registry"`
+
 	Log struct {
 		Level              string `toml:"level"`
 		Rotate             string `toml:"rotate"`
@@ -144,6 +151,20 @@ type frameworkConfig struct {
 		RequestBodyLogOff  bool   `toml:"request_log_off"`
 		RespBodyLogMaxSize int    `toml:"response_log_max_size"` // -1:不限制;默认1024字节;
 		SuccStatCode       []int  `toml:"succ_stat_code"`
+		MaxSize            int    `toml:"max_size"`          // 单个日志文件的最大MB数,0:不限制
+		MaxBackups         int    `toml:"max_backups"`       // 保留的切割文件数,0:全部保留
+		MaxAge             int    `toml:"max_age"`           // 切割文件的保留天数,0:全部保留
+		Compress           bool   `toml:"compress"`          // 后台gzip压缩切割文件
+		Symlink            bool   `toml:"symlink"`           // 维护指向当前文件的<name>-latest.log软链
+		AsyncPolicy        string `toml:"async_policy"`      // 异步写日志,队列满时的策略:block,drop_newest,drop_oldest,sample;默认同步写
+		AsyncQueueSize     int    `toml:"async_queue_size"`  // 异步写日志的队列长度,默认8192
+		AsyncSampleRate    int    `toml:"async_sample_rate"` // sample策略下每N条日志保留1条,默认10
+		Encoding           string `toml:"encoding"`          // 日志格式:console,json,logfmt;默认console
+		KitEncoding        string `toml:"kit_encoding"`      // access,business,error日志的格式:json,logfmt;默认json
+
+		Sampling []LogSamplingConfig `toml:"sampling"` // 按日志名配置的采样与限流,[[log.sampling]]
+		Levels   map[string]string   `toml:"levels"`   // 按日志名配置的级别,如[log.levels] access="error",远程配置变更时生效
+		Redact   []LogRedactConfig   `toml:"redact"`   // 日志字段与请求响应body的脱敏规则,[[log.redact]],远程配置变更时生效
 	} `toml:"log"`
 
 	ServerClient        []ServerClient             `toml:"server_client"`
@@ -166,6 +187,34 @@ type JSONDataLogOption struct {
 	TaskName string `toml:"task_name"`
 }
 
+// LogSamplingConfig 日志采样与按调用位置限流
+type LogSamplingConfig struct {
+	Logger          string   `toml:"logger"`           // 日志名:_default,_gen,_slow,_crash,_balance,access,business,error;为空时是_default
+	First           int      `toml:"first"`            // 每个周期内同级别同消息的前N条全部输出,0:不采样
+	Thereafter      int      `toml:"thereafter"`       // 之后每M条输出1条,0:之后全部丢弃
+	RateLimit       int      `toml:"rate_limit"`       // 每个周期内每个调用位置最多输出的条数,0:不限流
+	Interval        duration `toml:"interval"`         // 采样和限流的周期,默认1s
+	SummaryInterval duration `toml:"summary_interval"` // 输出被丢弃日志统计的周期,默认1m
+}
+
+// LogRedactConfig 日志脱敏规则
+type LogRedactConfig struct {
+	Fields   []string `toml:"fields"`   // 脱敏的日志字段、JSON body字段与表单/query参数名,不区分大小写
+	Headers  []string `toml:"headers"`  // 脱敏的header名
+	Patterns []string `toml:"patterns"` // 任意字符串中脱敏的正则,预置:card,phone,email
+	Mode     string   `toml:"mode"`     // 脱敏方式:mask,partial(保留首尾),hash(加盐sha256前缀);默认mask
+	Salt     string   `toml:"salt"`     // hash的盐
+}
+
+// EtcdConfig etcd注册中心配置
+type EtcdConfig struct {
+	Endpoints   []string `toml:"endpoints"`    // etcd地址,如["127.0.0.1:2379"]
+	DialTimeout duration `toml:"dial_timeout"` // 连接超时,默认5s
+	Username    string   `toml:"username"`
+	Password    string   `toml:"password"`
+	Prefix      string   `toml:"prefix"` // 服务注册的key前缀,默认/services
+}
+
 type CircuitConfig struct {
 	Type       string   `toml:"type"`
 	Service    string   `toml:"service"`
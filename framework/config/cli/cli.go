@@ -0,0 +1,380 @@
+// Package cli is the toolkit-config command, it loads the config sources like
+// a service does, and renders, diffs or validates the merged config.
+//
+// A service validates its config with its own struct by building the command
+// with the struct registered:
+//
+//	func main() {
+//		cli.Register("app", &conf.Config{})
+//		cli.Main()
+//	}
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/yunfeiyang1916/toolkit/framework/config/loader"
+	"github.com/yunfeiyang1916/toolkit/framework/config/loader/memory"
+	"github.com/yunfeiyang1916/toolkit/framework/config/reader"
+	"github.com/yunfeiyang1916/toolkit/framework/config/reader/toml"
+	"github.com/yunfeiyang1916/toolkit/framework/config/source"
+	"github.com/yunfeiyang1916/toolkit/framework/config/source/env"
+	"github.com/yunfeiyang1916/toolkit/framework/config/source/file"
+	fsource "github.com/yunfeiyang1916/toolkit/framework/config/source/flag"
+	"github.com/yunfeiyang1916/toolkit/framework/config/validate"
+)
+
+var (
+	structsMu sync.RWMutex
+	structs   = map[string]interface{}{}
+)
+
+// Register registers a config struct to validate by the name, structPtr is a
+// pointer to the struct, a new value is decoded for each validation.
+func Register(name string, structPtr interface{}) {
+	structsMu.Lock()
+	structs[name] = structPtr
+	structsMu.Unlock()
+}
+
+const usage = `usage: toolkit-config <command> [flags] [sources]
+
+commands:
+  render    print the merged config
+  diff      print the differences of two configs, the sources are separated by --
+  validate  validate the merged config with the registered structs
+
+sources, merged in order like a service loads them:
+  -f file           a config file, the format is the file extension
+  -remote path=file a local file standing in for a consul path
+  -env prefix       the environment variables with the prefix
+  -set key=value    a command line config flag
+`
+
+// Main runs the command of os.Args and exits.
+func Main() {
+	if err := Run(os.Args[1:], os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// Run runs the command of args and writes the result to w.
+func Run(args []string, w io.Writer) error {
+	if len(args) == 0 {
+		return errors.New(usage)
+	}
+	switch args[0] {
+	case "render":
+		return render(args[1:], w)
+	case "diff":
+		return diff(args[1:], w)
+	case "validate":
+		return validateCmd(args[1:], w)
+	}
+	return errors.New(usage)
+}
+
+// entry is a source with the label shown as the provenance of its keys
+type entry struct {
+	label string
+	src   source.Source
+}
+
+type sourcesFlag struct {
+	entries *[]entry
+	kind    string
+}
+
+func (f sourcesFlag) String() string {
+	return ""
+}
+
+func (f sourcesFlag) Set(v string) error {
+	switch f.kind {
+	case "f":
+		*f.entries = append(*f.entries, entry{label: "file:" + v, src: file.NewSource(file.WithPath(v))})
+	case "remote":
+		idx := strings.Index(v, "=")
+		if idx <= 0 {
+			return fmt.Errorf("bad remote %q, want path=file", v)
+		}
+		*f.entries = append(*f.entries, entry{
+			label: "remote:" + v[:idx] + "(" + v[idx+1:] + ")",
+			src:   file.NewSource(file.WithPath(v[idx+1:])),
+		})
+	case "env":
+		*f.entries = append(*f.entries, entry{label: "env:" + v, src: env.NewSource(env.WithPrefix(v))})
+	case "set":
+		key := v
+		if idx := strings.Index(v, "="); idx > 0 {
+			key = v[:idx]
+		}
+		*f.entries = append(*f.entries, entry{label: "flag:" + key, src: fsource.NewSource(fsource.WithArgs([]string{"--" + v}))})
+	}
+	return nil
+}
+
+func newFlagSet(name string, entries *[]entry) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.Var(sourcesFlag{entries, "f"}, "f", "a config file")
+	fs.Var(sourcesFlag{entries, "remote"}, "remote", "a local file standing in for a consul path")
+	fs.Var(sourcesFlag{entries, "env"}, "env", "the environment variables with the prefix")
+	fs.Var(sourcesFlag{entries, "set"}, "set", "a command line config flag")
+	return fs
+}
+
+func parse(fs *flag.FlagSet, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return errors.New(usage)
+		}
+		return err
+	}
+	if fs.NArg() > 0 {
+		return fmt.Errorf("unexpected argument %q\n%s", fs.Arg(0), usage)
+	}
+	return nil
+}
+
+// merged is the merged config of the sources
+type merged struct {
+	vals       reader.Values
+	redacted   map[string]interface{} // the merged config with the secrets redacted
+	provenance map[string]string      // key path -> the label of the source
+}
+
+func load(entries []entry) (*merged, error) {
+	if len(entries) == 0 {
+		return nil, errors.New("no source, use -f, -remote, -env or -set")
+	}
+	m := memory.NewLoader()
+	defer m.Close()
+	srcs := make([]source.Source, len(entries))
+	for i, e := range entries {
+		srcs[i] = e.src
+	}
+	if err := m.Load(srcs...); err != nil {
+		return nil, err
+	}
+	snap, err := m.Snapshot()
+	if err != nil {
+		return nil, err
+	}
+	vals, err := toml.NewReader().Values(snap.ChangeSet)
+	if err != nil {
+		return nil, err
+	}
+	res := &merged{vals: vals}
+	if err := reader.Encoder("toml").Decode([]byte(vals.String()), &res.redacted); err != nil {
+		return nil, err
+	}
+	if res.provenance, err = provenance(entries); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// provenance returns the label of the source which supplies each key, the
+// sources are merged by priority and then in order like the memory loader.
+func provenance(entries []entry) (map[string]string, error) {
+	type set struct {
+		label string
+		cs    *source.ChangeSet
+	}
+	var sets []set
+	for _, e := range entries {
+		cs, err := e.src.Read()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", e.label, err)
+		}
+		if cs != nil && len(cs.Data) > 0 {
+			sets = append(sets, set{e.label, cs})
+		}
+	}
+	sort.SliceStable(sets, func(i, j int) bool { return sets[i].cs.Priority < sets[j].cs.Priority })
+
+	res := map[string]string{}
+	for _, s := range sets {
+		var data map[string]interface{}
+		if err := reader.Encoder(s.cs.Format).Decode(s.cs.Data, &data); err != nil {
+			return nil, fmt.Errorf("%s: %v", s.label, err)
+		}
+		// an array of tables replaces the one of the previous sources
+		for _, p := range tableArrays(data, "") {
+			for k := range res {
+				if loader.HasPrefix(k, p) {
+					delete(res, k)
+				}
+			}
+		}
+		for k := range loader.Flatten(data) {
+			res[k] = s.label
+		}
+	}
+	return res, nil
+}
+
+func tableArrays(m map[string]interface{}, path string) []string {
+	var res []string
+	for k, v := range m {
+		p := k
+		if len(path) > 0 {
+			p = path + "." + k
+		}
+		switch vv := v.(type) {
+		case map[string]interface{}:
+			res = append(res, tableArrays(vv, p)...)
+		case []map[string]interface{}:
+			res = append(res, p)
+		}
+	}
+	return res
+}
+
+func render(args []string, w io.Writer) error {
+	var entries []entry
+	fs := newFlagSet("render", &entries)
+	format := fs.String("o", "toml", "the output format, toml, json or yaml")
+	prov := fs.Bool("p", false, "print the flattened keys with the source of each key")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	m, err := load(entries)
+	if err != nil {
+		return err
+	}
+	if *prov {
+		flat := loader.Flatten(m.redacted)
+		for _, k := range sortedKeys(flat) {
+			fmt.Fprintf(w, "%s = %s\t# %s\n", k, show(flat[k]), m.provenance[k])
+		}
+		return nil
+	}
+	b, err := encode(m, *format)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(b)
+	return err
+}
+
+func encode(m *merged, format string) ([]byte, error) {
+	switch format {
+	case "toml":
+		return []byte(m.vals.String()), nil
+	case "json":
+		b, err := json.MarshalIndent(m.redacted, "", "  ")
+		return append(b, '\n'), err
+	case "yaml", "yml":
+		return reader.Encoder("yaml").Encode(m.redacted)
+	}
+	return nil, fmt.Errorf("unknown output format %q", format)
+}
+
+func diff(args []string, w io.Writer) error {
+	idx := len(args)
+	for i, a := range args {
+		if a == "--" {
+			idx = i
+			break
+		}
+	}
+	if idx == len(args) {
+		return errors.New("diff needs two configs separated by --")
+	}
+	var a, b []entry
+	if err := parse(newFlagSet("diff", &a), args[:idx]); err != nil {
+		return err
+	}
+	if err := parse(newFlagSet("diff", &b), args[idx+1:]); err != nil {
+		return err
+	}
+	ma, err := load(a)
+	if err != nil {
+		return err
+	}
+	mb, err := load(b)
+	if err != nil {
+		return err
+	}
+	for _, c := range loader.Diff(ma.redacted, mb.redacted) {
+		switch c.Type {
+		case loader.Added:
+			fmt.Fprintf(w, "+ %s = %s\t# %s\n", c.Path, show(c.New), mb.provenance[c.Path])
+		case loader.Removed:
+			fmt.Fprintf(w, "- %s = %s\t# %s\n", c.Path, show(c.Old), ma.provenance[c.Path])
+		case loader.Modified:
+			fmt.Fprintf(w, "~ %s = %s -> %s\t# %s -> %s\n", c.Path, show(c.Old), show(c.New),
+				ma.provenance[c.Path], mb.provenance[c.Path])
+		}
+	}
+	return nil
+}
+
+func validateCmd(args []string, w io.Writer) error {
+	var entries []entry
+	fs := newFlagSet("validate", &entries)
+	name := fs.String("s", "", "the registered struct to validate with, all the structs by default")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	m, err := load(entries)
+	if err != nil {
+		return err
+	}
+
+	structsMu.RLock()
+	defer structsMu.RUnlock()
+	names := make([]string, 0, len(structs))
+	for n := range structs {
+		if len(*name) == 0 || n == *name {
+			names = append(names, n)
+		}
+	}
+	if len(names) == 0 {
+		return fmt.Errorf("no registered struct %q, register it with cli.Register", *name)
+	}
+	sort.Strings(names)
+	var failed bool
+	for _, n := range names {
+		v := reflect.New(reflect.TypeOf(structs[n]).Elem()).Interface()
+		if err := validate.Decode(v, m.vals.Scan); err != nil {
+			failed = true
+			fmt.Fprintf(w, "%s: %v\n", n, err)
+			continue
+		}
+		fmt.Fprintf(w, "%s: ok\n", n)
+	}
+	if failed {
+		return errors.New("validation failed")
+	}
+	return nil
+}
+
+func show(v interface{}) string {
+	if s, ok := v.(string); ok {
+		return strconv.Quote(s)
+	}
+	return fmt.Sprint(v)
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
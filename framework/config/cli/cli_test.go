@@ -0,0 +1,78 @@
+package cli
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeFile(t *testing.T, dir, name, content string) string {
+	p := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(p, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestCLI(t *testing.T) {
+	dir := t.TempDir()
+	base := writeFile(t, dir, "config.toml", "[server]\nport = 80\nmode = \"debug\"\n\n[[server_client]]\nservice_name = \"a\"\n")
+	remote := writeFile(t, dir, "remote.toml", "[server]\nmode = \"release\"\n\n[[server_client]]\nservice_name = \"b\"\n")
+	os.Setenv("CLITEST_SERVER__PORT", "8080")
+	defer os.Unsetenv("CLITEST_SERVER__PORT")
+
+	var out bytes.Buffer
+	err := Run([]string{"render", "-p", "-env", "CLITEST_", "-f", base, "-remote", "/service_config/a=" + remote, "-set", "server.name=x"}, &out)
+	assert.Nil(t, err)
+	assert.Equal(t, `server.mode = "release"	# remote:/service_config/a(`+remote+`)
+server.name = "x"	# flag:server.name
+server.port = 8080	# env:CLITEST_
+server_client.0.service_name = "b"	# remote:/service_config/a(`+remote+`)
+`, out.String())
+
+	out.Reset()
+	assert.Nil(t, Run([]string{"render", "-o", "yaml", "-f", base}, &out))
+	assert.Contains(t, out.String(), "port: 80")
+
+	out.Reset()
+	assert.Nil(t, Run([]string{"diff", "-f", base, "--", "-f", base, "-f", remote}, &out))
+	assert.Equal(t, `~ server.mode = "debug" -> "release"	# file:`+base+` -> file:`+remote+`
+~ server_client.0.service_name = "a" -> "b"	# file:`+base+` -> file:`+remote+`
+`, out.String())
+
+	assert.NotNil(t, Run([]string{"render"}, &out))
+	assert.NotNil(t, Run([]string{"render", "-f", base, "extra"}, &out))
+	assert.NotNil(t, Run([]string{"diff", "-f", base}, &out))
+	assert.NotNil(t, Run([]string{"unknown"}, &out))
+}
+
+func TestValidate(t *testing.T) {
+	type Server struct {
+		Port int    `toml:"port" validate:"min=1"`
+		Mode string `toml:"mode" default:"release" validate:"enum=debug|release"`
+	}
+	type Config struct {
+		Server Server `toml:"server"`
+	}
+	Register("app", &Config{})
+	defer func() { structs = map[string]interface{}{} }()
+
+	dir := t.TempDir()
+	good := writeFile(t, dir, "good.toml", "[server]\nport = 80\n")
+	bad := writeFile(t, dir, "bad.toml", "[server]\nport = 0\nmode = \"test\"\n")
+
+	var out bytes.Buffer
+	assert.Nil(t, Run([]string{"validate", "-f", good}, &out))
+	assert.Equal(t, "app: ok\n", out.String())
+
+	out.Reset()
+	assert.NotNil(t, Run([]string{"validate", "-s", "app", "-f", bad}, &out))
+	assert.Contains(t, out.String(), "server.port")
+	assert.Contains(t, out.String(), "server.mode")
+
+	assert.NotNil(t, Run([]string{"validate", "-s", "other", "-f", good}, &out))
+}
@@ -12,8 +12,14 @@ type Config interface {
 	reader.Values
 	LoadFile(f ...string) error
 	LoadPath(p string, isPrefix bool, format string) error
+	LoadEnv(prefix ...string) error
+	LoadFlag(args ...string) error
 	Sync() error
 	Listen(interface{}) loader.Refresher
+	Subscribe(prefix string, fn func(changes []loader.Change)) (cancel func())
+	SubscribeSection(prefix string, structPtr interface{}, fn func(v interface{})) (cancel func(), err error)
+	History() []loader.Record
+	Rollback(version string) error
 }
 
 // Default is a default config instance
@@ -75,6 +81,19 @@ func Consul(paths ...string) error {
 	return nil
 }
 
+// Env wrap Default LoadEnv func, it's represents Default load the environment
+// variables with the prefixes, they override the file and consul configs.
+// At least one prefix is required.
+func Env(prefix ...string) error {
+	return Default.LoadEnv(prefix...)
+}
+
+// Flag wrap Default LoadFlag func, it's represents Default load the command
+// line flags like --server.port=8080, they override the env, file and consul configs
+func Flag(args ...string) error {
+	return Default.LoadFlag(args...)
+}
+
 // Sync wrap Default's Sync func, use for reloading config
 func Sync() error {
 	return Default.Sync()
@@ -84,3 +103,23 @@ func Sync() error {
 func Listen(structPtr interface{}) loader.Refresher {
 	return Default.Listen(structPtr)
 }
+
+// Subscribe wrap Default's Subscribe func, use for receiving the key changes under a prefix
+func Subscribe(prefix string, fn func(changes []loader.Change)) (cancel func()) {
+	return Default.Subscribe(prefix, fn)
+}
+
+// SubscribeSection wrap Default's SubscribeSection func, use for reloading a struct section only when it changes
+func SubscribeSection(prefix string, structPtr interface{}, fn func(v interface{})) (cancel func(), err error) {
+	return Default.SubscribeSection(prefix, structPtr, fn)
+}
+
+// History wrap Default's History func, it returns the applied config snapshots
+func History() []loader.Record {
+	return Default.History()
+}
+
+// Rollback wrap Default's Rollback func, use for re-applying a previous config version
+func Rollback(version string) error {
+	return Default.Rollback(version)
+}
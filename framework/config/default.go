@@ -1,7 +1,9 @@
 package config
 
 import (
+	"errors"
 	"path"
+	"reflect"
 	"strings"
 	"sync"
 	"time"
@@ -11,8 +13,10 @@ import (
 	"github.com/yunfeiyang1916/toolkit/framework/config/reader"
 	"github.com/yunfeiyang1916/toolkit/framework/config/reader/toml"
 	"github.com/yunfeiyang1916/toolkit/framework/config/source"
-	"github.com/yunfeiyang1916/toolkit/framework/config/source/consul"
+	"github.com/yunfeiyang1916/toolkit/framework/config/source/env"
 	"github.com/yunfeiyang1916/toolkit/framework/config/source/file"
+	"github.com/yunfeiyang1916/toolkit/framework/config/source/flag"
+	"github.com/yunfeiyang1916/toolkit/framework/config/validate"
 )
 
 type defaultConfig struct {
@@ -22,6 +26,7 @@ type defaultConfig struct {
 	vals   reader.Values
 	loader loader.Loader // use memory loader
 	reader reader.Reader // default use toml reader to read data from memory loader
+	subs   subscribers
 }
 
 func newDefaultConfig(opts ...Option) *defaultConfig {
@@ -30,7 +35,7 @@ func newDefaultConfig(opts ...Option) *defaultConfig {
 		o(&ops)
 	}
 	c := &defaultConfig{
-		loader: memory.NewLoader(),
+		loader: memory.NewLoader(ops.Loader...),
 		reader: toml.NewReader(),
 	}
 	c.loader.Load(ops.Source...)
@@ -66,18 +71,31 @@ func (c *defaultConfig) LoadFile(files ...string) error {
 	return c.load(s...)
 }
 
+// LoadPath loads a consul path, or an http or etcd url.
 func (c *defaultConfig) LoadPath(path string, usePrefix bool, format string) error {
 	if len(path) == 0 {
 		return nil
 	}
-	s := consul.NewSource(
-		consul.WithAddress(ConsulAddr),
-		consul.WithAbsPath(path),
-		consul.UsePrefix(usePrefix),
-		source.WithEncoder(reader.Encoder(format)))
+	s, err := remoteSource(path, usePrefix, format)
+	if err != nil {
+		return err
+	}
 	return c.load(s)
 }
 
+func (c *defaultConfig) LoadEnv(prefix ...string) error {
+	return c.load(env.NewSource(env.WithPrefix(prefix...)))
+}
+
+// LoadFlag loads the config flags of args, or of os.Args when args is empty.
+func (c *defaultConfig) LoadFlag(args ...string) error {
+	var opts []source.Option
+	if len(args) > 0 {
+		opts = append(opts, flag.WithArgs(args))
+	}
+	return c.load(flag.NewSource(opts...))
+}
+
 func (c *defaultConfig) Sync() error {
 	if err := c.loader.Sync(); err != nil {
 		return err
@@ -91,6 +109,33 @@ func (c *defaultConfig) Listen(v interface{}) loader.Refresher {
 	return c.loader.Listen(v)
 }
 
+// History returns the applied snapshots, the latest one is the last.
+func (c *defaultConfig) History() []loader.Record {
+	if h, ok := c.loader.(loader.Historian); ok {
+		return h.History()
+	}
+	return nil
+}
+
+// Rollback re-applies the snapshot of a version in the history, it stays
+// applied until a source changes or Sync is called.
+func (c *defaultConfig) Rollback(version string) error {
+	h, ok := c.loader.(loader.Historian)
+	if !ok {
+		return errors.New("config: the loader keeps no history")
+	}
+	snap, err := h.Rollback(version)
+	if err != nil {
+		return err
+	}
+	vals, err := c.reader.Values(snap.ChangeSet)
+	if err != nil {
+		return err
+	}
+	c.set(snap, vals)
+	return nil
+}
+
 // implements reader.Values
 func (c *defaultConfig) Bytes() []byte {
 	c.RLock()
@@ -134,10 +179,15 @@ func (c *defaultConfig) Range(f func(k string, v interface{})) {
 	}
 }
 
+// Scan decodes the config into v, v gets the default tag values of the absent
+// keys and must pass the validate tag constraints.
 func (c *defaultConfig) Scan(v interface{}) error {
 	c.Lock()
 	defer c.Unlock()
-	return c.vals.Scan(v)
+	if rt := reflect.TypeOf(v); rt == nil || rt.Kind() != reflect.Ptr || rt.Elem().Kind() != reflect.Struct {
+		return c.vals.Scan(v)
+	}
+	return validate.Decode(v, c.vals.Scan)
 }
 
 func (c *defaultConfig) load(sources ...source.Source) error {
@@ -152,18 +202,30 @@ func (c *defaultConfig) update() error {
 	if err != nil {
 		return err
 	}
-	c.Lock()
-	defer c.Unlock()
-
-	c.snap = snap
 	vals, err := c.reader.Values(snap.ChangeSet)
 	if err != nil {
 		return err
 	}
-	c.vals = vals
+	c.set(snap, vals)
 	return nil
 }
 
+// set stores the snapshot and notifies the subscribers of the key changes.
+func (c *defaultConfig) set(snap *loader.Snapshot, vals reader.Values) {
+	c.Lock()
+	var old, new map[string]interface{}
+	if c.vals != nil {
+		old = c.vals.Map()
+	}
+	if vals != nil {
+		new = vals.Map()
+	}
+	c.snap = snap
+	c.vals = vals
+	c.Unlock()
+	c.subs.notify(loader.Diff(old, new))
+}
+
 func (c *defaultConfig) run() {
 	watch := func(w loader.Watcher) error {
 		for {
@@ -171,10 +233,8 @@ func (c *defaultConfig) run() {
 			if err != nil {
 				return err
 			}
-			c.Lock()
-			c.snap = snap
-			c.vals, _ = c.reader.Values(snap.ChangeSet)
-			c.Unlock()
+			vals, _ := c.reader.Values(snap.ChangeSet)
+			c.set(snap, vals)
 		}
 	}
 
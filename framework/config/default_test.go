@@ -10,6 +10,9 @@ import (
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/yunfeiyang1916/toolkit/framework/config/loader"
+	"github.com/yunfeiyang1916/toolkit/framework/config/source"
+	"github.com/yunfeiyang1916/toolkit/framework/config/source/memory"
 )
 
 func createFileForTest(t *testing.T) *os.File {
@@ -427,3 +430,165 @@ func TestGet(t *testing.T) {
 	fmt.Println(v.Duration(0))
 
 }
+
+func TestPriority(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.toml")
+	assert.Nil(t, ioutil.WriteFile(path, []byte("[server]\nport = 80\nhost = \"file\"\nname = \"file\"\n"), 0644))
+	os.Setenv("PRIORITY_SERVER__PORT", "8080")
+	os.Setenv("PRIORITY_SERVER__HOST", "env")
+	defer os.Unsetenv("PRIORITY_SERVER__PORT")
+	defer os.Unsetenv("PRIORITY_SERVER__HOST")
+
+	c := New()
+	assert.Nil(t, c.LoadFlag("--server.port=9090"))
+	assert.Nil(t, c.LoadEnv("PRIORITY_"))
+	// the file is loaded last but has the lowest priority
+	assert.Nil(t, c.LoadFile(path))
+
+	assert.Equal(t, 9090, c.Get("server", "port").Int(0))
+	assert.Equal(t, "env", c.Get("server", "host").String(""))
+	assert.Equal(t, "file", c.Get("server", "name").String(""))
+}
+
+type validated struct {
+	Server struct {
+		Port    int           `toml:"port" validate:"min=1,max=65535"`
+		Timeout time.Duration `toml:"timeout" default:"30s"`
+	} `toml:"server"`
+}
+
+func TestScanValidate(t *testing.T) {
+	mem := memory.NewSource(memory.WithDataToml([]byte("[server]\nport = 8080\n")))
+	c := New(WithSource(mem))
+
+	v := &validated{}
+	assert.Nil(t, c.Scan(v))
+	assert.Equal(t, 8080, v.Server.Port)
+	assert.Equal(t, 30*time.Second, v.Server.Timeout)
+
+	r := c.Listen(&validated{})
+	assert.Equal(t, 30*time.Second, r.Load().(*validated).Server.Timeout)
+	errs := r.(loader.ErrorNotifier).Errors()
+
+	// a broken reload is rejected and the previous value kept
+	time.Sleep(100 * time.Millisecond) // wait the loader watching the source
+	mem.(interface{ Update(*source.ChangeSet) }).Update(&source.ChangeSet{Data: []byte("[server]\nport = 0\n"), Format: "toml"})
+	select {
+	case err := <-errs:
+		assert.Contains(t, err.Error(), "server.port")
+	case <-time.After(time.Second):
+		t.Fatal("wait reload error timeout")
+	}
+	assert.Equal(t, 8080, r.Load().(*validated).Server.Port)
+	for i := 0; i < 100 && c.Get("server", "port").Int(0) != 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.NotNil(t, c.Scan(&validated{}))
+}
+
+func TestListenInitialRejected(t *testing.T) {
+	mem := memory.NewSource(memory.WithDataToml([]byte("[server]\nport = 0\n")))
+	c := New(WithSource(mem))
+
+	// the rejected initial value is reported like the reloads
+	r := c.Listen(&validated{})
+	select {
+	case err := <-r.(loader.ErrorNotifier).Errors():
+		assert.Contains(t, err.Error(), "server.port")
+	case <-time.After(time.Second):
+		t.Fatal("wait initial error timeout")
+	}
+	assert.Equal(t, 0, r.Load().(*validated).Server.Port)
+}
+
+func TestInterpolateReload(t *testing.T) {
+	mem := memory.NewSource(memory.WithDataToml([]byte("host = \"10.0.0.1\"\naddr = \"${host}:80\"\n")))
+	c := New(WithSource(mem))
+	assert.Equal(t, "10.0.0.1:80", c.Get("addr").String(""))
+
+	// the referencing key follows the referenced one
+	time.Sleep(100 * time.Millisecond) // wait the loader watching the source
+	mem.(interface{ Update(*source.ChangeSet) }).Update(&source.ChangeSet{Data: []byte("host = \"10.0.0.2\"\naddr = \"${host}:80\"\n"), Format: "toml"})
+	for i := 0; i < 100 && c.Get("addr").String("") != "10.0.0.2:80"; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.Equal(t, "10.0.0.2:80", c.Get("addr").String(""))
+}
+
+func TestSubscribe(t *testing.T) {
+	type Limiter struct {
+		Rate  int `toml:"rate" validate:"min=1"`
+		Burst int `toml:"burst" default:"10"`
+	}
+	mem := memory.NewSource(memory.WithDataToml([]byte("[limiter]\nrate = 5\n[breaker]\nmin_samples = 3\n")))
+	c := New(WithSource(mem))
+
+	changes := make(chan []loader.Change, 10)
+	cancel := c.Subscribe("breaker", func(cs []loader.Change) { changes <- cs })
+	defer cancel()
+
+	lim := &Limiter{}
+	sections := make(chan *Limiter, 10)
+	cancelSection, err := c.SubscribeSection("limiter", lim, func(v interface{}) { sections <- v.(*Limiter) })
+	assert.Nil(t, err)
+	defer cancelSection()
+	assert.Equal(t, Limiter{Rate: 5, Burst: 10}, *lim)
+
+	_, err = c.SubscribeSection("limiter", Limiter{}, func(interface{}) {})
+	assert.NotNil(t, err)
+
+	update := func(data string) {
+		mem.(interface{ Update(*source.ChangeSet) }).Update(&source.ChangeSet{Data: []byte(data), Format: "toml"})
+	}
+	time.Sleep(100 * time.Millisecond) // wait the loader watching the source
+
+	// only the limiter section changes
+	update("[limiter]\nrate = 8\n[breaker]\nmin_samples = 3\n")
+	select {
+	case v := <-sections:
+		assert.Equal(t, Limiter{Rate: 8, Burst: 10}, *v)
+	case <-time.After(time.Second):
+		t.Fatal("no limiter section")
+	}
+	assert.Len(t, changes, 0)
+
+	// the breaker section changes, the invalid limiter section is rejected
+	update("[limiter]\nrate = 0\n[breaker]\nmin_samples = 4\nbreak = true\n")
+	select {
+	case cs := <-changes:
+		assert.Equal(t, []loader.Change{
+			{Path: "breaker.break", Type: loader.Added, New: true},
+			{Path: "breaker.min_samples", Type: loader.Modified, Old: int64(3), New: int64(4)},
+		}, cs)
+	case <-time.After(time.Second):
+		t.Fatal("no breaker changes")
+	}
+	assert.Len(t, sections, 0)
+
+	// no more changes after canceled
+	cancel()
+	update("[limiter]\nrate = 0\n")
+	time.Sleep(100 * time.Millisecond)
+	assert.Len(t, changes, 0)
+}
+
+func TestRollback(t *testing.T) {
+	mem := memory.NewSource(memory.WithDataToml([]byte("port = 80\n")))
+	c := New(WithSource(mem), WithHistory(5))
+	assert.Nil(t, c.LoadFile(createFileForTest(t).Name()))
+	history := c.History()
+	assert.NotEmpty(t, history)
+	version := history[len(history)-1].Version
+
+	time.Sleep(100 * time.Millisecond) // wait the loader watching the source
+	mem.(interface{ Update(*source.ChangeSet) }).Update(&source.ChangeSet{Data: []byte("port = 81\n"), Format: "toml"})
+	for i := 0; i < 100 && c.Get("port").Int(0) != 81; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.Equal(t, 81, c.Get("port").Int(0))
+
+	assert.Nil(t, c.Rollback(version))
+	assert.Equal(t, 80, c.Get("port").Int(0))
+	assert.Equal(t, "bar", c.Get("foo").String(""))
+	assert.NotNil(t, c.Rollback("0"))
+}
@@ -3,8 +3,8 @@ package toml
 import (
 	"bytes"
 
-	"github.com/BurntSushi/toml"
 	"github.com/yunfeiyang1916/toolkit/framework/config/encoder"
+	"github.com/yunfeiyang1916/toolkit/toml"
 )
 
 type tomlEncoder struct{}
@@ -0,0 +1,115 @@
+package loader
+
+import (
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ChangeType is the type of a key change between two snapshots
+type ChangeType int
+
+const (
+	Added ChangeType = iota + 1
+	Modified
+	Removed
+)
+
+func (t ChangeType) String() string {
+	switch t {
+	case Added:
+		return "added"
+	case Modified:
+		return "modified"
+	case Removed:
+		return "removed"
+	}
+	return "unknown"
+}
+
+// Change is the change of a leaf key, Old is nil when the key is added and
+// New is nil when the key is removed.
+type Change struct {
+	Path string
+	Type ChangeType
+	Old  interface{}
+	New  interface{}
+}
+
+// Diff returns the leaf key changes from old to new sorted by the key path.
+// The key paths are dotted like server.port, the tables of an array of tables
+// are indexed like server_client.0.service_name, other arrays are leaf values.
+func Diff(old, new map[string]interface{}) []Change {
+	o, n := Flatten(old), Flatten(new)
+
+	var changes []Change
+	for p, ov := range o {
+		nv, ok := n[p]
+		if !ok {
+			changes = append(changes, Change{Path: p, Type: Removed, Old: ov})
+			continue
+		}
+		if !reflect.DeepEqual(ov, nv) {
+			changes = append(changes, Change{Path: p, Type: Modified, Old: ov, New: nv})
+		}
+	}
+	for p, nv := range n {
+		if _, ok := o[p]; !ok {
+			changes = append(changes, Change{Path: p, Type: Added, New: nv})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+	return changes
+}
+
+// HasPrefix reports whether the key path is the prefix or under it, an empty
+// prefix matches all the paths.
+func HasPrefix(path, prefix string) bool {
+	if len(prefix) == 0 || path == prefix {
+		return true
+	}
+	return strings.HasPrefix(path, prefix+".")
+}
+
+// Filter returns the changes of the key paths under the prefix.
+func Filter(changes []Change, prefix string) []Change {
+	var res []Change
+	for _, c := range changes {
+		if HasPrefix(c.Path, prefix) {
+			res = append(res, c)
+		}
+	}
+	return res
+}
+
+// Flatten returns the leaf values of m by the key paths, the paths are the
+// ones of Diff.
+func Flatten(m map[string]interface{}) map[string]interface{} {
+	dst := map[string]interface{}{}
+	flatten(dst, "", m)
+	return dst
+}
+
+func flatten(dst map[string]interface{}, path string, v interface{}) {
+	join := func(key string) string {
+		if len(path) == 0 {
+			return key
+		}
+		return path + "." + key
+	}
+	switch node := v.(type) {
+	case map[string]interface{}:
+		for k, vv := range node {
+			flatten(dst, join(k), vv)
+		}
+	case []map[string]interface{}:
+		for i, vv := range node {
+			flatten(dst, join(strconv.Itoa(i)), vv)
+		}
+	default:
+		if len(path) > 0 {
+			dst[path] = v
+		}
+	}
+}
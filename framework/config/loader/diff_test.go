@@ -0,0 +1,44 @@
+package loader
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiff(t *testing.T) {
+	old := map[string]interface{}{
+		"port": int64(80),
+		"tags": []interface{}{"a"},
+		"server": map[string]interface{}{
+			"name": "a",
+			"http": map[string]interface{}{"timeout": int64(1)},
+		},
+		"server_client": []map[string]interface{}{{"service_name": "a"}, {"service_name": "b"}},
+	}
+	new := map[string]interface{}{
+		"port": int64(80),
+		"tags": []interface{}{"a", "b"},
+		"server": map[string]interface{}{
+			"name": "b",
+			"tcp":  map[string]interface{}{"idle_timeout": int64(2)},
+		},
+		"server_client": []map[string]interface{}{{"service_name": "a"}},
+	}
+	assert.Equal(t, []Change{
+		{Path: "server.http.timeout", Type: Removed, Old: int64(1)},
+		{Path: "server.name", Type: Modified, Old: "a", New: "b"},
+		{Path: "server.tcp.idle_timeout", Type: Added, New: int64(2)},
+		{Path: "server_client.1.service_name", Type: Removed, Old: "b"},
+		{Path: "tags", Type: Modified, Old: []interface{}{"a"}, New: []interface{}{"a", "b"}},
+	}, Diff(old, new))
+	assert.Len(t, Diff(old, old), 0)
+	assert.Len(t, Diff(nil, old), 6)
+
+	changes := Diff(old, new)
+	assert.Len(t, Filter(changes, "server"), 3)
+	assert.Len(t, Filter(changes, "server.http"), 1)
+	assert.Len(t, Filter(changes, "serv"), 0)
+	assert.Len(t, Filter(changes, ""), 5)
+	assert.Equal(t, "modified", Modified.String())
+}
@@ -3,11 +3,14 @@ package loader
 
 import (
 	"reflect"
+	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/yunfeiyang1916/toolkit/framework/config/encoder/toml"
 	"github.com/yunfeiyang1916/toolkit/framework/config/reader"
 	"github.com/yunfeiyang1916/toolkit/framework/config/source"
+	"github.com/yunfeiyang1916/toolkit/framework/config/validate"
 	"golang.org/x/net/context"
 )
 
@@ -32,6 +35,24 @@ type Snapshot struct {
 	Version   string
 }
 
+// Record is an applied snapshot kept in the history
+type Record struct {
+	Version   string
+	Source    string // what applied the snapshot, a source name, sync or rollback
+	Checksum  string
+	Timestamp time.Time
+	Snapshot  *Snapshot
+}
+
+// Historian is implemented by the loaders which keep the applied snapshots
+type Historian interface {
+	// History returns the applied snapshots, the latest one is the last
+	History() []Record
+	// Rollback re-applies the snapshot of the version, it stays applied
+	// until a source changes or the loader syncs
+	Rollback(version string) (*Snapshot, error)
+}
+
 type Options struct {
 	Reader  reader.Reader
 	Source  []source.Source
@@ -58,20 +79,40 @@ type Refresher interface {
 	Load() interface{}
 }
 
+// ErrorNotifier is implemented by the refreshers which report the rejected
+// values, the initial one included, the previous value is kept when a value
+// is rejected.
+type ErrorNotifier interface {
+	Errors() <-chan error
+}
+
 type Value struct {
 	raw    atomic.Value
 	Value  atomic.Value
 	Tp     reflect.Type
 	Format string
+
+	errOnce sync.Once
+	errs    chan error
 }
 
+// Decode decodes b into a new value with the default tag values, the value is
+// only stored when it passes the validate tag constraints.
 func (v *Value) Decode(b []byte) error {
 	codec, ok := reader.Encoding[v.Format]
 	if !ok {
 		codec = toml.NewEncoder()
 	}
 	ins := reflect.New(v.Tp.Elem()).Interface()
-	if err := codec.Decode(b, ins); err != nil {
+	decode := func(i interface{}) error { return codec.Decode(b, i) }
+	var err error
+	if v.Tp.Elem().Kind() == reflect.Struct {
+		err = validate.Decode(ins, decode)
+	} else {
+		err = decode(ins)
+	}
+	if err != nil {
+		v.notify(err)
 		return err
 	}
 	v.raw.Store(b)
@@ -79,6 +120,25 @@ func (v *Value) Decode(b []byte) error {
 	return nil
 }
 
+// Errors returns the errors of the rejected values, the initial one included,
+// the errors are dropped when nobody reads them and the channel is full.
+func (v *Value) Errors() <-chan error {
+	v.errOnce.Do(v.initErrs)
+	return v.errs
+}
+
+func (v *Value) initErrs() {
+	v.errs = make(chan error, 16)
+}
+
+func (v *Value) notify(err error) {
+	v.errOnce.Do(v.initErrs)
+	select {
+	case v.errs <- err:
+	default:
+	}
+}
+
 func (v *Value) Load() interface{} {
 	return v.Value.Load()
 }
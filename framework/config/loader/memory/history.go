@@ -0,0 +1,158 @@
+package memory
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/yunfeiyang1916/toolkit/framework/config/loader"
+	"github.com/yunfeiyang1916/toolkit/framework/config/reader"
+	"github.com/yunfeiyang1916/toolkit/framework/config/source"
+	"github.com/yunfeiyang1916/toolkit/logging"
+)
+
+// apply stores the merged set and its values, the set is recorded in the
+// history when its checksum differs from the current one. It returns nil
+// when nothing changes, the caller must hold the lock.
+func (m *memory) apply(set *source.ChangeSet, vals reader.Values, src string) (*loader.Record, []loader.Change) {
+	var old map[string]interface{}
+	if m.vals != nil {
+		old = m.vals.Map()
+	}
+	m.vals = vals
+	if n := len(m.history); n > 0 && m.history[n-1].Checksum == set.Checksum {
+		m.snap = &loader.Snapshot{ChangeSet: set, Version: m.history[n-1].Version}
+		return nil, nil
+	}
+
+	// the versions are unique even if two sets are applied in a nanosecond
+	version := time.Now().UnixNano()
+	if version <= m.version {
+		version = m.version + 1
+	}
+	m.version = version
+	m.snap = &loader.Snapshot{ChangeSet: set, Version: strconv.FormatInt(version, 10)}
+
+	rec := loader.Record{
+		Version:   m.snap.Version,
+		Source:    src,
+		Checksum:  set.Checksum,
+		Timestamp: time.Now(),
+		Snapshot:  loader.Copy(m.snap),
+	}
+	if m.maxHistory > 0 {
+		m.history = append(m.history, rec)
+		if len(m.history) > m.maxHistory {
+			m.history = append(m.history[:0:0], m.history[len(m.history)-m.maxHistory:]...)
+		}
+	}
+	return &rec, loader.Diff(old, vals.Map())
+}
+
+// audit logs the applied record and writes the cache, only the key paths of
+// the changes are logged since the values may be secrets.
+func (m *memory) audit(rec *loader.Record, changes []loader.Change) {
+	if rec == nil {
+		return
+	}
+	keys := make([]string, len(changes))
+	for i, c := range changes {
+		switch c.Type {
+		case loader.Added:
+			keys[i] = "+" + c.Path
+		case loader.Removed:
+			keys[i] = "-" + c.Path
+		default:
+			keys[i] = "~" + c.Path
+		}
+	}
+	logging.GenLogf("on memory apply, version %s, source %s, checksum %s, changes [%s]",
+		rec.Version, rec.Source, rec.Checksum, strings.Join(keys, " "))
+	if err := m.writeCache(rec.Snapshot.ChangeSet); err != nil {
+		logging.GenLogf("on memory apply, write cache %s failed, err %v", m.cache, err)
+	}
+}
+
+// History returns the applied snapshots, the latest one is the last.
+func (m *memory) History() []loader.Record {
+	m.RLock()
+	defer m.RUnlock()
+	return append([]loader.Record(nil), m.history...)
+}
+
+// Rollback re-applies the snapshot of the version in the history.
+func (m *memory) Rollback(version string) (*loader.Snapshot, error) {
+	m.Lock()
+	var set *source.ChangeSet
+	for _, rec := range m.history {
+		if rec.Version == version {
+			cs := *rec.Snapshot.ChangeSet
+			set = &cs
+			break
+		}
+	}
+	if set == nil {
+		m.Unlock()
+		return nil, fmt.Errorf("version %s not found in the history", version)
+	}
+	vals, err := m.opts.Reader.Values(set)
+	if err != nil {
+		m.Unlock()
+		return nil, err
+	}
+	rec, changes := m.apply(set, vals, "rollback "+version)
+	snap := loader.Copy(m.snap)
+	m.Unlock()
+
+	m.audit(rec, changes)
+	select {
+	case m.lisChan <- true:
+	default:
+	}
+	m.notify()
+	return snap, nil
+}
+
+// readCache returns the cached set for a source failing with err. The cache
+// is the one read when the loader is created, since the sets applied at
+// startup before the failing source overwrite the cache file.
+func (m *memory) readCache(err error) (*source.ChangeSet, error) {
+	if len(m.cached) == 0 {
+		return nil, err
+	}
+	logging.GenLogf("on memory load, use the cache %s, err %v", m.cache, err)
+	cs := &source.ChangeSet{
+		Data:      m.cached,
+		Format:    m.opts.Reader.String(),
+		Source:    "cache",
+		Timestamp: time.Now(),
+	}
+	cs.Checksum = cs.Sum()
+	return cs, nil
+}
+
+// writeCache replaces the cache file with the set atomically.
+func (m *memory) writeCache(set *source.ChangeSet) error {
+	if len(m.cache) == 0 {
+		return nil
+	}
+	f, err := ioutil.TempFile(filepath.Dir(m.cache), filepath.Base(m.cache)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err = f.Write(set.Data); err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), m.cache)
+}
@@ -0,0 +1,72 @@
+package memory
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yunfeiyang1916/toolkit/framework/config/loader"
+	"github.com/yunfeiyang1916/toolkit/framework/config/source"
+	mem "github.com/yunfeiyang1916/toolkit/framework/config/source/memory"
+)
+
+// unreachable is a source which always fails to read
+type unreachable struct{}
+
+func (unreachable) Read() (*source.ChangeSet, error) { return nil, errors.New("unreachable") }
+func (unreachable) Watch() (source.Watcher, error)   { return nil, errors.New("unreachable") }
+func (unreachable) String() string                   { return "unreachable" }
+
+func TestHistoryRollback(t *testing.T) {
+	m := NewLoader(WithHistory(2))
+	h := m.(loader.Historian)
+	assert.Nil(t, m.Load(mem.NewSource(mem.WithDataToml([]byte("a = 1\n")))))
+	assert.Nil(t, m.Load(mem.NewSource(mem.WithDataToml([]byte("b = 2\n")))))
+	assert.Nil(t, m.Sync()) // nothing changes
+	assert.Nil(t, m.Load(mem.NewSource(mem.WithDataToml([]byte("a = 3\n")))))
+
+	records := h.History()
+	assert.Len(t, records, 2)
+	assert.Equal(t, "memory", records[1].Source)
+	assert.NotEqual(t, records[0].Version, records[1].Version)
+	assert.NotEmpty(t, records[0].Checksum)
+	assert.False(t, records[0].Timestamp.IsZero())
+
+	version := records[0].Version
+	snap, err := h.Rollback(version)
+	assert.Nil(t, err)
+	assert.Equal(t, records[0].Checksum, snap.ChangeSet.Checksum)
+	records = h.History()
+	assert.Equal(t, "rollback "+version, records[1].Source)
+
+	_, err = h.Rollback("1")
+	assert.NotNil(t, err)
+}
+
+func TestCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config-cache")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	cache := filepath.Join(dir, "config.cache")
+
+	m := NewLoader(WithCache(cache))
+	assert.Nil(t, m.Load(mem.NewSource(mem.WithDataToml([]byte("a = 1\n[consul]\nb = 2\n")))))
+	b, err := ioutil.ReadFile(cache)
+	assert.Nil(t, err)
+	assert.Contains(t, string(b), "b = 2")
+
+	// the cache of the last run is used even if it's overwritten at startup
+	m = NewLoader(WithCache(cache))
+	assert.Nil(t, m.Load(mem.NewSource(mem.WithDataToml([]byte("a = 3\n")))))
+	assert.Nil(t, m.Load(unreachable{}))
+	snap, err := m.Snapshot()
+	assert.Nil(t, err)
+	assert.Contains(t, string(snap.ChangeSet.Data), "b = 2")
+
+	// no cache
+	m = NewLoader()
+	assert.NotNil(t, m.Load(unreachable{}))
+}
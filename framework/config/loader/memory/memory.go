@@ -4,7 +4,9 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"io/ioutil"
 	"reflect"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -28,6 +30,12 @@ type memory struct {
 	sources  []source.Source
 	idx      int
 	watchers map[int]*watcher
+
+	history    []loader.Record
+	maxHistory int
+	version    int64
+	cache      string
+	cached     []byte // the cache content at startup
 }
 
 func NewLoader(opts ...loader.Option) loader.Loader {
@@ -43,6 +51,17 @@ func NewLoader(opts ...loader.Option) loader.Loader {
 		opts:     options,
 		watchers: make(map[int]*watcher),
 		sources:  options.Source,
+
+		maxHistory: DefaultHistory,
+	}
+	if options.Context != nil {
+		if n, ok := options.Context.Value(historyKey{}).(int); ok {
+			m.maxHistory = n
+		}
+		m.cache, _ = options.Context.Value(cacheKey{}).(string)
+	}
+	if len(m.cache) > 0 {
+		m.cached, _ = ioutil.ReadFile(m.cache)
 	}
 	for i, s := range options.Source {
 		go m.watch(i, s) // 启动每个资源的watcher
@@ -62,11 +81,15 @@ func (m *memory) Close() error {
 
 func (m *memory) Load(sources ...source.Source) error {
 	var errs []string
+	var names []string
 	for _, s := range sources {
 		set, err := s.Read()
 		if err != nil {
-			errs = append(errs, fmt.Sprintf("error loading source %s: %v", s, err))
-			continue
+			// the source is watched to replace the cache when it recovers
+			if set, err = m.readCache(err); err != nil {
+				errs = append(errs, fmt.Sprintf("error loading source %s: %v", s, err))
+				continue
+			}
 		}
 		if set == nil {
 			continue
@@ -76,10 +99,11 @@ func (m *memory) Load(sources ...source.Source) error {
 		m.sets = append(m.sets, set)
 		idx := len(m.sets) - 1
 		m.Unlock()
+		names = append(names, s.String())
 		go m.watch(idx, s)
 	}
 
-	if err := m.reload(); err != nil {
+	if err := m.reload(strings.Join(names, ",")); err != nil {
 		errs = append(errs, err.Error())
 	}
 	if len(errs) != 0 {
@@ -102,18 +126,26 @@ func (m *memory) Sync() error {
 	var sets []*source.ChangeSet
 	m.Lock()
 	var errs []string
-	for _, s := range m.sources {
+	for i, s := range m.sources {
 		ch, err := s.Read()
 		if err != nil {
-			errs = append(errs, err.Error())
-			continue
+			// keep the last known set, the sets are indexed like the sources,
+			// it's not an error with the last-known-good cache
+			if i < len(m.sets) {
+				ch = m.sets[i]
+			}
+			if len(m.cache) == 0 || ch == nil {
+				errs = append(errs, err.Error())
+			} else {
+				logging.GenLogf("on memory sync, source %s failed, keep the last set, err %v", s, err)
+			}
 		}
 		sets = append(sets, ch)
 	}
 	m.sets = sets
 	m.Unlock()
 
-	if err := m.reload(); err != nil {
+	if err := m.reload("sync"); err != nil {
 		errs = append(errs, err.Error())
 	}
 	if len(errs) > 0 {
@@ -164,6 +196,15 @@ func (m *memory) Listen(v interface{}) loader.Refresher {
 }
 
 func (m *memory) listen(cc loader.AutoLoader) loader.Refresher {
+	// the initial value gets the defaults and is validated like the reloads
+	if m.loaded() {
+		m.RLock()
+		data := m.vals.Bytes()
+		m.RUnlock()
+		if err := cc.Decode(data); err != nil {
+			logging.GenLogf("on memory listen, initial value rejected, err %v", err)
+		}
+	}
 	go func() {
 		for {
 			select {
@@ -174,7 +215,10 @@ func (m *memory) listen(cc loader.AutoLoader) loader.Refresher {
 				if len(data) == 0 {
 					continue
 				}
-				cc.Decode(data)
+				// a rejected reload keeps the previous value
+				if err := cc.Decode(data); err != nil {
+					logging.GenLogf("on memory listen, reload rejected, err %v", err)
+				}
 			}
 		}
 	}()
@@ -191,26 +235,43 @@ func (m *memory) loaded() bool {
 	return loaded
 }
 
-func (m *memory) flush() error {
+// flush merges the sets and applies the merged snapshot, src is what
+// triggers the flush for the audit log.
+func (m *memory) flush(src string) error {
 	m.Lock()
-	defer m.Unlock()
-	set, err := m.opts.Reader.Merge(m.sets...)
+	set, err := m.opts.Reader.Merge(prioritize(m.sets)...)
 	if err != nil {
+		m.Unlock()
 		return err
 	}
-	m.vals, err = m.opts.Reader.Values(set)
+	vals, err := m.opts.Reader.Values(set)
 	if err != nil {
+		m.Unlock()
 		return err
 	}
-	m.snap = &loader.Snapshot{
-		ChangeSet: set,
-		Version:   fmt.Sprintf("%d", time.Now().Unix()),
-	}
+	rec, changes := m.apply(set, vals, src)
+	m.Unlock()
+	m.audit(rec, changes)
 	return nil
 }
 
-func (m *memory) reload() error {
-	if err := m.flush(); err != nil {
+// prioritize orders the change sets to merge by priority, the sets of the same
+// priority keep the load order.
+func prioritize(sets []*source.ChangeSet) []*source.ChangeSet {
+	sorted := make([]*source.ChangeSet, 0, len(sets))
+	for _, cs := range sets {
+		if cs != nil {
+			sorted = append(sorted, cs)
+		}
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Priority < sorted[j].Priority
+	})
+	return sorted
+}
+
+func (m *memory) reload(src string) error {
+	if err := m.flush(src); err != nil {
 		return err
 	}
 	m.notify()
@@ -276,7 +337,7 @@ func (m *memory) watch(idx int, s source.Source) {
 			m.Lock()
 			m.sets[idx] = cs
 			m.Unlock()
-			if err := m.flush(); err != nil { // 解析错误
+			if err := m.flush(s.String()); err != nil { // 解析错误
 				logging.GenLogf("on memory watch, load value failed, err %v", err)
 				continue
 			}
@@ -0,0 +1,36 @@
+package memory
+
+import (
+	"context"
+
+	"github.com/yunfeiyang1916/toolkit/framework/config/loader"
+)
+
+// DefaultHistory is the number of the applied snapshots kept by default
+const DefaultHistory = 10
+
+type historyKey struct{}
+
+type cacheKey struct{}
+
+// WithHistory sets the number of the applied snapshots kept for rollback
+func WithHistory(n int) loader.Option {
+	return func(o *loader.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, historyKey{}, n)
+	}
+}
+
+// WithCache sets the last-known-good cache file, every applied snapshot is
+// written to it, and it's loaded instead of a source which fails to read,
+// e.g. consul is unreachable at startup.
+func WithCache(path string) loader.Option {
+	return func(o *loader.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, cacheKey{}, path)
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"github.com/yunfeiyang1916/toolkit/framework/config/encoder"
 	"github.com/yunfeiyang1916/toolkit/framework/config/encoder/json"
 	"github.com/yunfeiyang1916/toolkit/framework/config/encoder/toml"
+	"github.com/yunfeiyang1916/toolkit/framework/config/loader"
+	"github.com/yunfeiyang1916/toolkit/framework/config/loader/memory"
 	"github.com/yunfeiyang1916/toolkit/framework/config/source"
 )
 
@@ -13,6 +15,7 @@ type Option func(o *Options)
 // Options represents a option on the source
 type Options struct {
 	Source []source.Source
+	Loader []loader.Option
 }
 
 // WithSource appends a source to list of sources
@@ -22,6 +25,21 @@ func WithSource(s source.Source) Option {
 	}
 }
 
+// WithHistory sets the number of the applied snapshots kept for rollback
+func WithHistory(n int) Option {
+	return func(o *Options) {
+		o.Loader = append(o.Loader, memory.WithHistory(n))
+	}
+}
+
+// WithCache sets the last-known-good cache file, it's loaded instead of a
+// source which fails to read, e.g. consul is unreachable at startup
+func WithCache(path string) Option {
+	return func(o *Options) {
+		o.Loader = append(o.Loader, memory.WithCache(path))
+	}
+}
+
 // TomlEncoder represents a toml encoder
 func TomlEncoder() encoder.Encoder {
 	return toml.NewEncoder()
@@ -0,0 +1,196 @@
+package reader
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Interpolate resolves the ${...} references in the string values of a
+// decoded config tree, it returns the resolved tree and the number of the
+// resolved values, the tree itself when there is nothing to resolve.
+//
+// ${a.b.c} is the value of the key a.b.c, array elements are indexed like
+// ${server_client.0.host}. A name which is not a key is an environment
+// variable, ${NAME:-default} falls back to default when the key does not
+// exist and the variable is unset or empty. A string which is exactly one
+// reference keeps the type of the referenced value. $${ is a literal ${.
+func Interpolate(tree interface{}) (interface{}, int, error) {
+	ip := &interpolator{
+		root:     tree,
+		resolved: make(map[string]interface{}),
+		visiting: make(map[string]bool),
+	}
+	v, err := ip.walk(tree, "")
+	if err != nil {
+		return nil, 0, err
+	}
+	if ip.n == 0 {
+		return tree, 0, nil
+	}
+	return v, ip.n, nil
+}
+
+type interpolator struct {
+	root     interface{}
+	resolved map[string]interface{} // key path -> resolved string value
+	visiting map[string]bool
+	stack    []string
+	n        int
+}
+
+func join(path, key string) string {
+	if len(path) == 0 {
+		return key
+	}
+	return path + "." + key
+}
+
+func (ip *interpolator) walk(v interface{}, path string) (interface{}, error) {
+	switch orig := v.(type) {
+	case map[string]interface{}:
+		typed := make(map[string]interface{}, len(orig))
+		for k, vv := range orig {
+			r, err := ip.value(join(path, k), vv)
+			if err != nil {
+				return nil, err
+			}
+			typed[k] = r
+		}
+		return typed, nil
+	case []map[string]interface{}:
+		typed := make([]map[string]interface{}, len(orig))
+		for i, vv := range orig {
+			r, err := ip.value(join(path, strconv.Itoa(i)), vv)
+			if err != nil {
+				return nil, err
+			}
+			typed[i] = r.(map[string]interface{})
+		}
+		return typed, nil
+	case []interface{}:
+		typed := make([]interface{}, len(orig))
+		for i, vv := range orig {
+			r, err := ip.value(join(path, strconv.Itoa(i)), vv)
+			if err != nil {
+				return nil, err
+			}
+			typed[i] = r
+		}
+		return typed, nil
+	}
+	return v, nil
+}
+
+// value resolves the raw value of the key path.
+func (ip *interpolator) value(path string, raw interface{}) (interface{}, error) {
+	s, ok := raw.(string)
+	if !ok || !strings.Contains(s, "${") {
+		return ip.walk(raw, path)
+	}
+	if v, ok := ip.resolved[path]; ok {
+		return v, nil
+	}
+	if ip.visiting[path] {
+		return nil, fmt.Errorf("config interpolate: reference cycle %s -> %s", strings.Join(ip.stack, " -> "), path)
+	}
+	ip.visiting[path] = true
+	ip.stack = append(ip.stack, path)
+	v, err := ip.expand(s)
+	ip.stack = ip.stack[:len(ip.stack)-1]
+	delete(ip.visiting, path)
+	if err != nil {
+		return nil, err
+	}
+	ip.resolved[path] = v
+	ip.n++
+	return v, nil
+}
+
+func (ip *interpolator) expand(s string) (interface{}, error) {
+	var b strings.Builder
+	for {
+		idx := strings.Index(s, "${")
+		if idx < 0 {
+			b.WriteString(s)
+			return b.String(), nil
+		}
+		if idx > 0 && s[idx-1] == '$' {
+			b.WriteString(s[:idx-1])
+			b.WriteString("${")
+			s = s[idx+2:]
+			continue
+		}
+		end := strings.Index(s[idx:], "}")
+		if end < 0 {
+			return nil, fmt.Errorf("config interpolate: unclosed reference in %q", s)
+		}
+		end += idx
+		v, err := ip.lookup(s[idx+2 : end])
+		if err != nil {
+			return nil, err
+		}
+		// a whole value reference keeps the type
+		if idx == 0 && end == len(s)-1 && b.Len() == 0 {
+			return v, nil
+		}
+		switch v.(type) {
+		case map[string]interface{}, []map[string]interface{}, []interface{}:
+			return nil, fmt.Errorf("config interpolate: %s is not a value", s[idx+2:end])
+		}
+		b.WriteString(s[:idx])
+		fmt.Fprint(&b, v)
+		s = s[end+1:]
+	}
+}
+
+func (ip *interpolator) lookup(expr string) (interface{}, error) {
+	name, def, hasDef := expr, "", false
+	if idx := strings.Index(expr, ":-"); idx >= 0 {
+		name, def, hasDef = expr[:idx], expr[idx+2:], true
+	}
+	name = strings.TrimSpace(name)
+	if len(name) == 0 {
+		return nil, fmt.Errorf("config interpolate: empty reference ${%s}", expr)
+	}
+	if raw, ok := get(ip.root, name); ok {
+		return ip.value(name, raw)
+	}
+	if env := os.Getenv(name); len(env) > 0 {
+		return env, nil
+	}
+	if hasDef {
+		return def, nil
+	}
+	return nil, fmt.Errorf("config interpolate: unresolved reference ${%s}", expr)
+}
+
+// get returns the raw value of a dotted key path.
+func get(v interface{}, path string) (interface{}, bool) {
+	for _, key := range strings.Split(path, ".") {
+		switch node := v.(type) {
+		case map[string]interface{}:
+			vv, ok := node[key]
+			if !ok {
+				return nil, false
+			}
+			v = vv
+		case []map[string]interface{}:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(node) {
+				return nil, false
+			}
+			v = node[i]
+		case []interface{}:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(node) {
+				return nil, false
+			}
+			v = node[i]
+		default:
+			return nil, false
+		}
+	}
+	return v, true
+}
@@ -0,0 +1,60 @@
+package reader
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInterpolate(t *testing.T) {
+	os.Setenv("INTERPOLATE_HOST", "10.0.0.1")
+	defer os.Unsetenv("INTERPOLATE_HOST")
+
+	tree := map[string]interface{}{
+		"host": "${INTERPOLATE_HOST}",
+		"port": int64(8080),
+		"addr": "${host}:${port}",
+		"server_client": []map[string]interface{}{
+			{"addr": "${addr}", "port": "${port}"},
+			{"addr": "${server_client.0.addr}", "mode": "${INTERPOLATE_MODE:-release}"},
+		},
+		"list":    []interface{}{"${host}", "$${literal}"},
+		"backup":  "${server_client.1}",
+		"unknown": "plain",
+	}
+	v, n, err := Interpolate(tree)
+	assert.Nil(t, err)
+	assert.True(t, n > 0)
+	m := v.(map[string]interface{})
+	assert.Equal(t, "10.0.0.1:8080", m["addr"])
+	clients := m["server_client"].([]map[string]interface{})
+	assert.Equal(t, "10.0.0.1:8080", clients[0]["addr"])
+	assert.Equal(t, int64(8080), clients[0]["port"])
+	assert.Equal(t, "10.0.0.1:8080", clients[1]["addr"])
+	assert.Equal(t, "release", clients[1]["mode"])
+	assert.Equal(t, []interface{}{"10.0.0.1", "${literal}"}, m["list"])
+	assert.Equal(t, "10.0.0.1:8080", m["backup"].(map[string]interface{})["addr"])
+	// the input is not modified
+	assert.Equal(t, "${host}:${port}", tree["addr"])
+
+	same, n, err := Interpolate(map[string]interface{}{"a": "b"})
+	assert.Nil(t, err)
+	assert.Equal(t, 0, n)
+	assert.Equal(t, map[string]interface{}{"a": "b"}, same)
+}
+
+func TestInterpolateError(t *testing.T) {
+	for _, tree := range []map[string]interface{}{
+		{"a": "${b}", "b": "${c}", "c": "${a}"},
+		{"a": "${a}"},
+		{"a": "${INTERPOLATE_MISSING}"},
+		{"a": "${b", "b": "1"},
+		{"a": "x${b}", "b": map[string]interface{}{"c": "1"}},
+	} {
+		_, _, err := Interpolate(tree)
+		assert.NotNil(t, err, "%v", tree)
+	}
+	_, _, err := Interpolate(map[string]interface{}{"a": "${b}", "b": "${a}"})
+	assert.Contains(t, err.Error(), "cycle")
+}
@@ -8,14 +8,16 @@ import (
 	"time"
 
 	"github.com/yunfeiyang1916/toolkit/framework/config/reader"
+	"github.com/yunfeiyang1916/toolkit/framework/config/secret"
 	"github.com/yunfeiyang1916/toolkit/framework/config/source"
 
 	simple "github.com/bitly/go-simplejson"
 )
 
 type jsonValues struct {
-	ch *source.ChangeSet
-	sj *simple.Json
+	ch       *source.ChangeSet
+	sj       *simple.Json
+	redacted []byte // data with the secrets redacted
 }
 
 type jsonValue struct {
@@ -27,8 +29,34 @@ func newValues(ch *source.ChangeSet) (reader.Values, error) {
 	var err error
 	if err = sj.UnmarshalJSON(ch.Data); err != nil {
 		sj.SetPath(nil, string(ch.Data))
+		return &jsonValues{ch: ch, sj: sj, redacted: ch.Data}, err
 	}
-	return &jsonValues{ch, sj}, err
+	tree, refs, err := reader.Interpolate(sj.Interface())
+	if err != nil {
+		return &jsonValues{ch: ch, sj: sj, redacted: ch.Data}, err
+	}
+	plain, redacted, secrets, err := secret.Resolve(tree)
+	if err != nil {
+		return &jsonValues{ch: ch, sj: sj, redacted: ch.Data}, err
+	}
+	if refs == 0 && secrets == 0 {
+		return &jsonValues{ch: ch, sj: sj, redacted: ch.Data}, nil
+	}
+	b, err := json.Marshal(plain)
+	if err != nil {
+		return &jsonValues{ch: ch, sj: sj, redacted: ch.Data}, err
+	}
+	resolved := simple.New()
+	if err = resolved.UnmarshalJSON(b); err != nil {
+		return &jsonValues{ch: ch, sj: sj, redacted: ch.Data}, err
+	}
+	jv := &jsonValues{ch: ch, sj: resolved, redacted: b}
+	if secrets > 0 {
+		if jv.redacted, err = json.Marshal(redacted); err != nil {
+			return jv, err
+		}
+	}
+	return jv, nil
 }
 
 func (j *jsonValues) Get(path ...string) reader.Value {
@@ -82,8 +110,9 @@ func (j *jsonValues) Scan(v interface{}) error {
 	return json.Unmarshal(b, v)
 }
 
+// String returns the data with the secrets redacted, it's safe to dump.
 func (j *jsonValues) String() string {
-	return "json"
+	return string(j.redacted)
 }
 
 func (j *jsonValue) Bool(def bool) bool {
@@ -1,8 +1,11 @@
 package json
 
 import (
+	"fmt"
+	"strings"
 	"testing"
 
+	"github.com/yunfeiyang1916/toolkit/framework/config/secret"
 	"github.com/yunfeiyang1916/toolkit/framework/config/source"
 )
 
@@ -37,3 +40,59 @@ func TestValues(t *testing.T) {
 		}
 	}
 }
+
+func TestInterpolateValues(t *testing.T) {
+	values, err := newValues(&source.ChangeSet{
+		Data: []byte(`{"host": "10.0.0.1", "port": 80, "clients": [{"addr": "${host}:${port}"}], "port_ref": "${port}"}`),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := values.Get("clients").Bytes(); string(v) != `[{"addr":"10.0.0.1:80"}]` {
+		t.Fatalf("Expected resolved clients got %s", v)
+	}
+	if v := values.Get("port_ref").Int(0); v != 80 {
+		t.Fatalf("Expected 80 got %d", v)
+	}
+}
+
+func TestSecretValues(t *testing.T) {
+	line, err := secret.GenerateKey("k1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := secret.ReadKeyFile(strings.NewReader(line))
+	if err != nil {
+		t.Fatal(err)
+	}
+	secret.Register(p)
+	enc, err := secret.Encrypt(secret.AESProviderName, "root:pass@tcp(127.0.0.1:3306)/db")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	values, err := newValues(&source.ChangeSet{
+		Data: []byte(fmt.Sprintf(`{"database": [{"name": "db", "master": "%s"}]}`, enc)),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var conf struct {
+		Database []struct {
+			Master string `json:"master"`
+		} `json:"database"`
+	}
+	if err := values.Scan(&conf); err != nil || conf.Database[0].Master != "root:pass@tcp(127.0.0.1:3306)/db" {
+		t.Fatalf("Expected decrypted master got %+v, %v", conf, err)
+	}
+	if v := values.Get("database").Bytes(); !strings.Contains(string(v), "root:pass") {
+		t.Fatalf("Expected decrypted database got %s", v)
+	}
+	if s := values.String(); strings.Contains(s, "root:pass") || strings.Contains(s, enc) || !strings.Contains(s, secret.Redacted) {
+		t.Fatalf("Expected redacted data got %s", s)
+	}
+
+	if _, err := newValues(&source.ChangeSet{Data: []byte(`{"a": "ENC[kms:x]"}`)}); err == nil {
+		t.Fatal("Expected the error of an unknown provider")
+	}
+}
@@ -6,15 +6,18 @@ import (
 	"strconv"
 	"time"
 
-	"github.com/BurntSushi/toml"
 	"github.com/yunfeiyang1916/toolkit/framework/config/reader"
+	"github.com/yunfeiyang1916/toolkit/framework/config/secret"
 	"github.com/yunfeiyang1916/toolkit/framework/config/source"
+	"github.com/yunfeiyang1916/toolkit/toml"
 )
 
 type tomlValues struct {
-	ch   *source.ChangeSet
-	meta *toml.MetaData
-	data interface{} // after translate
+	ch       *source.ChangeSet
+	meta     *toml.MetaData
+	data     interface{} // after translate
+	plain    []byte      // data with the references resolved and the secrets decrypted
+	redacted []byte      // data with the secrets redacted
 }
 
 func newValues(ch *source.ChangeSet) (reader.Values, error) {
@@ -23,17 +26,43 @@ func newValues(ch *source.ChangeSet) (reader.Values, error) {
 	if err != nil {
 		return nil, err
 	}
-	data := translate(tmp)
-	return &tomlValues{data: data, meta: &meta, ch: ch}, nil
+	tree, refs, err := reader.Interpolate(tmp)
+	if err != nil {
+		return nil, err
+	}
+	plain, redacted, secrets, err := secret.Resolve(tree)
+	if err != nil {
+		return nil, err
+	}
+	tm := &tomlValues{data: translate(plain), meta: &meta, ch: ch, plain: ch.Data, redacted: ch.Data}
+	if refs > 0 || secrets > 0 {
+		if tm.plain, err = encode(plain); err != nil {
+			return nil, err
+		}
+		if tm.redacted, err = encode(redacted); err != nil {
+			return nil, err
+		}
+	}
+	return tm, nil
 }
 
+func encode(v interface{}) ([]byte, error) {
+	b := bytes.NewBuffer([]byte{})
+	if err := toml.NewEncoder(b).Encode(v); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// Bytes returns the data with the secrets decrypted.
 func (tm *tomlValues) Bytes() []byte {
-	b := bytes.NewBuffer(tm.ch.Data)
+	b := bytes.NewBuffer(tm.plain)
 	return b.Bytes()
 }
 
+// String returns the data with the secrets redacted, it's safe to dump.
 func (tm *tomlValues) String() string {
-	b := bytes.NewBuffer(tm.ch.Data)
+	b := bytes.NewBuffer(tm.redacted)
 	return b.String()
 }
 
@@ -75,7 +104,7 @@ func (tm *tomlValues) Range(f func(k string, v interface{})) {
 }
 
 func (tm *tomlValues) Scan(v interface{}) error {
-	_, err := toml.DecodeReader(bytes.NewBuffer(tm.ch.Data), v)
+	_, err := toml.DecodeReader(bytes.NewBuffer(tm.plain), v)
 	if err != nil {
 		return err
 	}
@@ -234,7 +263,15 @@ func translate(tomlData interface{}) interface{} {
 		}
 		return typed
 	case time.Time:
-		return orig.Format("2006-01-02T15:04:05Z")
+		switch toml.LocalType(orig) {
+		case toml.LocalDatetime:
+			return orig.Format("2006-01-02T15:04:05.999999999")
+		case toml.LocalDate:
+			return orig.Format("2006-01-02")
+		case toml.LocalTime:
+			return orig.Format("15:04:05.999999999")
+		}
+		return orig.Format(time.RFC3339Nano)
 	case bool:
 		return orig
 	case int64:
@@ -2,10 +2,12 @@ package toml
 
 import (
 	"fmt"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/yunfeiyang1916/toolkit/framework/config/secret"
 	"github.com/yunfeiyang1916/toolkit/framework/config/source"
 )
 
@@ -207,3 +209,48 @@ func TestTomlValues_Scan(t *testing.T) {
 	values.Get("event", "90000", "c.bq").Scan(tt)
 	assert.Equal(t, tt.URI, "/a/b/c")
 }
+
+func TestSecretValues(t *testing.T) {
+	line, err := secret.GenerateKey("k1")
+	assert.Nil(t, err)
+	p, err := secret.ReadKeyFile(strings.NewReader(line))
+	assert.Nil(t, err)
+	secret.Register(p)
+	enc, err := secret.Encrypt(secret.AESProviderName, "root:pass@tcp(127.0.0.1:3306)/db")
+	assert.Nil(t, err)
+
+	data := []byte(fmt.Sprintf("[[database]]\nname = \"db\"\nmaster = \"%s\"\n", enc))
+	v, err := newValues(&source.ChangeSet{Data: data, Format: "toml"})
+	assert.Nil(t, err)
+
+	var conf struct {
+		Database []struct {
+			Name   string `toml:"name"`
+			Master string `toml:"master"`
+		} `toml:"database"`
+	}
+	assert.Nil(t, v.Scan(&conf))
+	assert.Equal(t, "root:pass@tcp(127.0.0.1:3306)/db", conf.Database[0].Master)
+	assert.Contains(t, string(v.Bytes()), "root:pass")
+	assert.NotContains(t, v.String(), "root:pass")
+	assert.NotContains(t, v.String(), enc)
+	assert.Contains(t, v.String(), secret.Redacted)
+
+	_, err = newValues(&source.ChangeSet{Data: []byte(`a = "ENC[kms:x]"`), Format: "toml"})
+	assert.NotNil(t, err)
+}
+
+func TestTomlValues_TOML10(t *testing.T) {
+	v, err := newValues(&source.ChangeSet{Data: []byte(`
+server.port = 0x1f90
+server.hosts = ["a", 1]
+log.rotate = { at = 03:00:00, since = 2021-06-01, next = 2021-06-02 03:00:00 }
+`), Format: "toml"})
+	assert.Nil(t, err)
+
+	assert.Equal(t, 8080, v.Get("server", "port").Int(0))
+	assert.Equal(t, []string{"a", "1"}, v.Get("server", "hosts").StringSlice(nil))
+	assert.Equal(t, "03:00:00", v.Get("log", "rotate", "at").String(""))
+	assert.Equal(t, "2021-06-01", v.Get("log", "rotate", "since").String(""))
+	assert.Equal(t, "2021-06-02T03:00:00", v.Get("log", "rotate", "next").String(""))
+}
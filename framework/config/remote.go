@@ -0,0 +1,52 @@
+package config
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/yunfeiyang1916/toolkit/framework/config/reader"
+	"github.com/yunfeiyang1916/toolkit/framework/config/source"
+	"github.com/yunfeiyang1916/toolkit/framework/config/source/consul"
+	"github.com/yunfeiyang1916/toolkit/framework/config/source/etcd"
+	"github.com/yunfeiyang1916/toolkit/framework/config/source/http"
+)
+
+// IsRemoteURL reports whether the remote path is an http or etcd url rather
+// than a consul path.
+func IsRemoteURL(p string) bool {
+	return strings.HasPrefix(p, "http://") || strings.HasPrefix(p, "https://") || strings.HasPrefix(p, "etcd://")
+}
+
+// remoteSource returns the source of a remote path:
+//
+//	http://host/path, https://host/path   polls the url
+//	etcd://[user:pass@]host1:2379,host2:2379/path   watches the etcd key
+//	/path   watches the consul key
+func remoteSource(p string, usePrefix bool, format string) (source.Source, error) {
+	enc := source.WithEncoder(reader.Encoder(format))
+	if !IsRemoteURL(p) {
+		return consul.NewSource(
+			consul.WithAddress(ConsulAddr),
+			consul.WithAbsPath(p),
+			consul.UsePrefix(usePrefix),
+			enc), nil
+	}
+	u, err := url.Parse(p)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "etcd" {
+		return http.NewSource(http.WithURL(p), enc), nil
+	}
+	opts := []source.Option{
+		etcd.WithEndpoints(strings.Split(u.Host, ",")...),
+		etcd.WithAbsPath(u.Path),
+		etcd.UsePrefix(usePrefix),
+		enc,
+	}
+	if u.User != nil {
+		password, _ := u.User.Password()
+		opts = append(opts, etcd.WithAuth(u.User.Username(), password))
+	}
+	return etcd.NewSource(opts...), nil
+}
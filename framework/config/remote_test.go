@@ -0,0 +1,20 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRemoteSource(t *testing.T) {
+	for p, name := range map[string]string{
+		"/service_config/a/config.toml": "consul",
+		"https://127.0.0.1/config.json": "http",
+	} {
+		s, err := remoteSource(p, false, "toml")
+		assert.Nil(t, err)
+		assert.Equal(t, name, s.String())
+	}
+	assert.True(t, IsRemoteURL("etcd://u:p@127.0.0.1:2379,127.0.0.2:2379/a/config.toml"))
+	assert.False(t, IsRemoteURL("/etcd://a"))
+}
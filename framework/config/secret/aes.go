@@ -0,0 +1,169 @@
+package secret
+
+import (
+	"bufio"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// AESProviderName is the name of the local aes-gcm provider.
+const AESProviderName = "aes"
+
+// KeySize is the size of the aes-256 keys.
+const KeySize = 32
+
+// AESProvider encrypts with aes-256-gcm. The payload is <key id>:<base64 of
+// nonce and ciphertext>, so the values encrypted with an old key can still
+// be decrypted after the primary key is rotated.
+type AESProvider struct {
+	primary string
+	keys    map[string]cipher.AEAD
+}
+
+// NewAESProvider creates an aes provider, primary is the id of the key used
+// to encrypt, all the keys can decrypt.
+func NewAESProvider(primary string, keys map[string][]byte) (*AESProvider, error) {
+	p := &AESProvider{primary: primary, keys: make(map[string]cipher.AEAD, len(keys))}
+	for id, key := range keys {
+		if len(id) == 0 || strings.Contains(id, ":") {
+			return nil, fmt.Errorf("secret: bad key id %q", id)
+		}
+		if len(key) != KeySize {
+			return nil, fmt.Errorf("secret: key %s size %d, want %d", id, len(key), KeySize)
+		}
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, err
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+		p.keys[id] = aead
+	}
+	if _, ok := p.keys[primary]; !ok {
+		return nil, fmt.Errorf("secret: primary key %q not found", primary)
+	}
+	return p, nil
+}
+
+// LoadKeyFile loads an aes provider from a keyfile. Each line of the keyfile
+// is <key id>:<base64 key>, the first key is the primary one, empty lines and
+// lines starting with # are skipped.
+func LoadKeyFile(path string) (*AESProvider, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadKeyFile(f)
+}
+
+// ReadKeyFile reads an aes provider from the keyfile content in r.
+func ReadKeyFile(r io.Reader) (*AESProvider, error) {
+	var primary string
+	keys := make(map[string][]byte)
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+		idx := strings.Index(line, ":")
+		if idx <= 0 {
+			return nil, fmt.Errorf("secret: keyfile line %d: missing key id", n)
+		}
+		id := line[:idx]
+		key, err := base64.StdEncoding.DecodeString(line[idx+1:])
+		if err != nil {
+			return nil, fmt.Errorf("secret: keyfile line %d: %v", n, err)
+		}
+		if _, ok := keys[id]; ok {
+			return nil, fmt.Errorf("secret: keyfile line %d: duplicate key id %s", n, id)
+		}
+		if len(primary) == 0 {
+			primary = id
+		}
+		keys[id] = key
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("secret: keyfile has no key")
+	}
+	return NewAESProvider(primary, keys)
+}
+
+// GenerateKey returns a keyfile line of a new random key.
+func GenerateKey(id string) (string, error) {
+	key := make([]byte, KeySize)
+	if _, err := rand.Read(key); err != nil {
+		return "", err
+	}
+	return id + ":" + base64.StdEncoding.EncodeToString(key), nil
+}
+
+func (p *AESProvider) Name() string {
+	return AESProviderName
+}
+
+// Primary returns the id of the key used to encrypt.
+func (p *AESProvider) Primary() string {
+	return p.primary
+}
+
+func (p *AESProvider) Encrypt(plaintext []byte) (string, error) {
+	aead := p.keys[p.primary]
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nonce, nonce, plaintext, []byte(p.primary))
+	return p.primary + ":" + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+func (p *AESProvider) Decrypt(payload string) ([]byte, error) {
+	idx := strings.Index(payload, ":")
+	if idx <= 0 {
+		return nil, ErrBadValue
+	}
+	id := payload[:idx]
+	aead, ok := p.keys[id]
+	if !ok {
+		return nil, fmt.Errorf("secret: key %q not found", id)
+	}
+	sealed, err := base64.StdEncoding.DecodeString(payload[idx+1:])
+	if err != nil || len(sealed) < aead.NonceSize() {
+		return nil, ErrBadValue
+	}
+	nonce := sealed[:aead.NonceSize()]
+	plaintext, err := aead.Open(nil, nonce, sealed[aead.NonceSize():], []byte(id))
+	if err != nil {
+		return nil, fmt.Errorf("secret: decrypt with key %s: %v", id, err)
+	}
+	return plaintext, nil
+}
+
+// KeyID returns the key id of an aes ENC[...] value.
+func KeyID(s string) (string, error) {
+	name, payload, err := parse(s)
+	if err != nil {
+		return "", err
+	}
+	if name != AESProviderName {
+		return "", fmt.Errorf("%w %q", ErrUnknownProvider, name)
+	}
+	idx := strings.Index(payload, ":")
+	if idx <= 0 {
+		return "", ErrBadValue
+	}
+	return payload[:idx], nil
+}
@@ -0,0 +1,202 @@
+// Package secret decrypts the encrypted config values. An encrypted value is
+// written as ENC[<provider>:<payload>], the provider registered by the name
+// decrypts the payload, e.g. ENC[aes:k1:bm9uY2UuLi4=] is decrypted by the
+// local aes provider with the key k1.
+package secret
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+const (
+	prefix = "ENC["
+	suffix = "]"
+
+	// Redacted replaces the decrypted values in the config dumps.
+	Redacted = "******"
+
+	// KeyFileEnv is the environment variable of the keyfile path, the aes
+	// provider is loaded from it when no aes provider is registered.
+	KeyFileEnv = "CONFIG_SECRET_KEYFILE"
+)
+
+var (
+	ErrUnknownProvider = errors.New("secret: unknown provider")
+	ErrBadValue        = errors.New("secret: bad encrypted value")
+)
+
+// Provider encrypts and decrypts the secret values, a KMS client can be
+// plugged in by implementing it.
+type Provider interface {
+	// Name is the provider name in the ENC[...] values.
+	Name() string
+	// Encrypt returns the payload of the encrypted value.
+	Encrypt(plaintext []byte) (string, error)
+	// Decrypt decrypts the payload of an encrypted value.
+	Decrypt(payload string) ([]byte, error)
+}
+
+var (
+	providersMu sync.RWMutex
+	providers   = map[string]Provider{}
+)
+
+// Register makes a provider available by its name, an already registered
+// provider of the same name is replaced.
+func Register(p Provider) {
+	providersMu.Lock()
+	providers[p.Name()] = p
+	providersMu.Unlock()
+}
+
+func provider(name string) (Provider, error) {
+	providersMu.RLock()
+	p, ok := providers[name]
+	providersMu.RUnlock()
+	if ok {
+		return p, nil
+	}
+	if name == AESProviderName {
+		if path := os.Getenv(KeyFileEnv); len(path) > 0 {
+			p, err := LoadKeyFile(path)
+			if err != nil {
+				return nil, err
+			}
+			Register(p)
+			return p, nil
+		}
+	}
+	return nil, fmt.Errorf("%w %q", ErrUnknownProvider, name)
+}
+
+// IsEncrypted reports whether s is an ENC[...] value.
+func IsEncrypted(s string) bool {
+	return strings.HasPrefix(s, prefix) && strings.HasSuffix(s, suffix)
+}
+
+// Encrypt encrypts plaintext with the named provider into an ENC[...] value.
+func Encrypt(name string, plaintext string) (string, error) {
+	p, err := provider(name)
+	if err != nil {
+		return "", err
+	}
+	return EncryptWith(p, plaintext)
+}
+
+// EncryptWith encrypts plaintext with p into an ENC[...] value.
+func EncryptWith(p Provider, plaintext string) (string, error) {
+	payload, err := p.Encrypt([]byte(plaintext))
+	if err != nil {
+		return "", err
+	}
+	return prefix + p.Name() + ":" + payload + suffix, nil
+}
+
+// Decrypt decrypts an ENC[...] value with the registered providers.
+func Decrypt(s string) (string, error) {
+	name, payload, err := parse(s)
+	if err != nil {
+		return "", err
+	}
+	p, err := provider(name)
+	if err != nil {
+		return "", err
+	}
+	b, err := p.Decrypt(payload)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// DecryptWith decrypts an ENC[...] value with p.
+func DecryptWith(p Provider, s string) (string, error) {
+	name, payload, err := parse(s)
+	if err != nil {
+		return "", err
+	}
+	if name != p.Name() {
+		return "", fmt.Errorf("%w %q", ErrUnknownProvider, name)
+	}
+	b, err := p.Decrypt(payload)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+func parse(s string) (string, string, error) {
+	if !IsEncrypted(s) {
+		return "", "", ErrBadValue
+	}
+	body := s[len(prefix) : len(s)-len(suffix)]
+	idx := strings.Index(body, ":")
+	if idx <= 0 {
+		return "", "", ErrBadValue
+	}
+	return body[:idx], body[idx+1:], nil
+}
+
+// Resolve decrypts the ENC[...] strings of a decoded config tree. It returns
+// the decrypted tree, the tree with the secrets redacted and the number of
+// secrets, the trees are the input itself when there is no secret.
+func Resolve(tree interface{}) (plain, redacted interface{}, n int, err error) {
+	plain, redacted, err = resolve(tree, &n)
+	if err != nil || n == 0 {
+		return tree, tree, n, err
+	}
+	return plain, redacted, n, nil
+}
+
+func resolve(v interface{}, n *int) (interface{}, interface{}, error) {
+	switch orig := v.(type) {
+	case string:
+		if !IsEncrypted(orig) {
+			return orig, orig, nil
+		}
+		s, err := Decrypt(orig)
+		if err != nil {
+			return nil, nil, err
+		}
+		*n++
+		return s, Redacted, nil
+	case map[string]interface{}:
+		plain := make(map[string]interface{}, len(orig))
+		redacted := make(map[string]interface{}, len(orig))
+		for k, vv := range orig {
+			p, r, err := resolve(vv, n)
+			if err != nil {
+				return nil, nil, fmt.Errorf("%s: %w", k, err)
+			}
+			plain[k], redacted[k] = p, r
+		}
+		return plain, redacted, nil
+	case []map[string]interface{}:
+		plain := make([]map[string]interface{}, len(orig))
+		redacted := make([]map[string]interface{}, len(orig))
+		for i, vv := range orig {
+			p, r, err := resolve(vv, n)
+			if err != nil {
+				return nil, nil, err
+			}
+			plain[i], redacted[i] = p.(map[string]interface{}), r.(map[string]interface{})
+		}
+		return plain, redacted, nil
+	case []interface{}:
+		plain := make([]interface{}, len(orig))
+		redacted := make([]interface{}, len(orig))
+		for i, vv := range orig {
+			p, r, err := resolve(vv, n)
+			if err != nil {
+				return nil, nil, err
+			}
+			plain[i], redacted[i] = p, r
+		}
+		return plain, redacted, nil
+	}
+	return v, v, nil
+}
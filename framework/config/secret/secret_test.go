@@ -0,0 +1,126 @@
+package secret
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newProvider(t *testing.T, ids ...string) *AESProvider {
+	var lines []string
+	for _, id := range ids {
+		line, err := GenerateKey(id)
+		assert.Nil(t, err)
+		lines = append(lines, line)
+	}
+	p, err := ReadKeyFile(strings.NewReader("# keys\n\n" + strings.Join(lines, "\n")))
+	assert.Nil(t, err)
+	return p
+}
+
+func TestAESProvider(t *testing.T) {
+	p := newProvider(t, "k1")
+	enc, err := EncryptWith(p, "root:pass@tcp(127.0.0.1:3306)/db")
+	assert.Nil(t, err)
+	assert.True(t, IsEncrypted(enc))
+	assert.True(t, strings.HasPrefix(enc, "ENC[aes:k1:"))
+
+	plain, err := DecryptWith(p, enc)
+	assert.Nil(t, err)
+	assert.Equal(t, "root:pass@tcp(127.0.0.1:3306)/db", plain)
+
+	// tampered ciphertext fails
+	_, err = DecryptWith(p, enc[:len(enc)-3]+"AA]")
+	assert.NotNil(t, err)
+	_, err = DecryptWith(p, "ENC[kms:xxx]")
+	assert.NotNil(t, err)
+
+	_, err = ReadKeyFile(strings.NewReader("k1:c2hvcnQ="))
+	assert.NotNil(t, err)
+	_, err = ReadKeyFile(strings.NewReader(""))
+	assert.NotNil(t, err)
+}
+
+func TestRotate(t *testing.T) {
+	k1, err := GenerateKey("k1")
+	assert.Nil(t, err)
+	k2, err := GenerateKey("k2")
+	assert.Nil(t, err)
+	path := filepath.Join(t.TempDir(), "keyfile")
+
+	assert.Nil(t, ioutil.WriteFile(path, []byte(k1+"\n"), 0600))
+	old, err := LoadKeyFile(path)
+	assert.Nil(t, err)
+	enc, err := EncryptWith(old, "secret")
+	assert.Nil(t, err)
+
+	// the new key is added in front of the old one
+	assert.Nil(t, ioutil.WriteFile(path, []byte(k2+"\n"+k1+"\n"), 0600))
+	p, err := LoadKeyFile(path)
+	assert.Nil(t, err)
+	assert.Equal(t, "k2", p.Primary())
+
+	// the old values are still readable
+	plain, err := DecryptWith(p, enc)
+	assert.Nil(t, err)
+	assert.Equal(t, "secret", plain)
+	enc, err = EncryptWith(p, plain)
+	assert.Nil(t, err)
+	id, err := KeyID(enc)
+	assert.Nil(t, err)
+	assert.Equal(t, "k2", id)
+}
+
+func TestResolve(t *testing.T) {
+	p := newProvider(t, "k1")
+	Register(p)
+	defer func() {
+		providersMu.Lock()
+		delete(providers, AESProviderName)
+		providersMu.Unlock()
+	}()
+	enc, err := Encrypt(AESProviderName, "pass")
+	assert.Nil(t, err)
+
+	tree := map[string]interface{}{
+		"name": "app",
+		"redis": []map[string]interface{}{
+			{"addr": "127.0.0.1:6379", "password": enc},
+		},
+		"list": []interface{}{enc, "plain"},
+	}
+	plain, redacted, n, err := Resolve(tree)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, n)
+	assert.Equal(t, "pass", plain.(map[string]interface{})["redis"].([]map[string]interface{})[0]["password"])
+	assert.Equal(t, Redacted, redacted.(map[string]interface{})["redis"].([]map[string]interface{})[0]["password"])
+	assert.Equal(t, []interface{}{"pass", "plain"}, plain.(map[string]interface{})["list"])
+	assert.Equal(t, enc, tree["list"].([]interface{})[0])
+
+	_, _, _, err = Resolve(map[string]interface{}{"a": "ENC[kms:x]"})
+	assert.NotNil(t, err)
+}
+
+func TestKeyFileEnv(t *testing.T) {
+	line, err := GenerateKey("env")
+	assert.Nil(t, err)
+	path := filepath.Join(t.TempDir(), "keyfile")
+	assert.Nil(t, ioutil.WriteFile(path, []byte(line), 0600))
+	os.Setenv(KeyFileEnv, path)
+	defer os.Unsetenv(KeyFileEnv)
+	defer func() {
+		providersMu.Lock()
+		delete(providers, AESProviderName)
+		providersMu.Unlock()
+	}()
+
+	enc, err := Encrypt(AESProviderName, "v")
+	assert.Nil(t, err)
+	plain, err := Decrypt(enc)
+	assert.Nil(t, err)
+	assert.Equal(t, "v", plain)
+}
@@ -0,0 +1,95 @@
+// Package env is an environment variable source. APP_SERVER__PORT=8080 with
+// prefix "APP_" is read as server.port = 8080.
+package env
+
+import (
+	"errors"
+	"os"
+	"strings"
+
+	"github.com/yunfeiyang1916/toolkit/framework/config/source"
+	"github.com/yunfeiyang1916/toolkit/framework/config/source/internal/kv"
+)
+
+// Separator separates the nested keys in a variable name.
+const Separator = "__"
+
+// ErrNoPrefix is returned by the sources without a prefix, which would read
+// the whole environment unless WithAll is set.
+var ErrNoPrefix = errors.New("env source without prefix, use WithPrefix or WithAll")
+
+type env struct {
+	prefixes []string
+	all      bool
+	opts     source.Options
+}
+
+func (e *env) Read() (*source.ChangeSet, error) {
+	if len(e.prefixes) == 0 && !e.all {
+		return nil, ErrNoPrefix
+	}
+	data := make(map[string]interface{})
+	for _, kvs := range os.Environ() {
+		pair := strings.SplitN(kvs, "=", 2)
+		if len(pair) != 2 {
+			continue
+		}
+		name, ok := e.trim(pair[0])
+		if !ok || len(name) == 0 {
+			continue
+		}
+		keys := strings.Split(strings.ToLower(name), Separator)
+		if hasEmpty(keys) {
+			continue
+		}
+		kv.Set(data, keys, kv.Coerce(pair[1]))
+	}
+	return kv.ChangeSet(data, e.String(), source.PriorityEnv)
+}
+
+// trim strips the first matched prefix from name, every name matches when no
+// prefix is set with WithAll.
+func (e *env) trim(name string) (string, bool) {
+	if len(e.prefixes) == 0 {
+		return name, true
+	}
+	for _, p := range e.prefixes {
+		if strings.HasPrefix(name, p) {
+			return strings.TrimPrefix(name, p), true
+		}
+	}
+	return "", false
+}
+
+func hasEmpty(keys []string) bool {
+	for _, k := range keys {
+		if len(k) == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Watch returns a watcher which never fires, environment variables do not
+// change after the process started.
+func (e *env) Watch() (source.Watcher, error) {
+	return kv.NewWatcher(), nil
+}
+
+func (e *env) String() string {
+	return "env"
+}
+
+func NewSource(opts ...source.Option) source.Source {
+	options := source.NewOptions(opts...)
+	var prefixes []string
+	if p, ok := options.Context.Value(prefixKey{}).([]string); ok {
+		for _, v := range p {
+			if len(v) > 0 {
+				prefixes = append(prefixes, v)
+			}
+		}
+	}
+	all, _ := options.Context.Value(allKey{}).(bool)
+	return &env{opts: options, prefixes: prefixes, all: all}
+}
@@ -0,0 +1,54 @@
+package env
+
+import (
+	"os"
+	"testing"
+
+	"github.com/BurntSushi/toml"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEnv(t *testing.T) {
+	os.Setenv("APP_SERVER__PORT", "8080")
+	os.Setenv("APP_SERVER__DEBUG", "true")
+	os.Setenv("APP_SERVER__RATIO", "0.5")
+	os.Setenv("APP_NAME", "demo")
+	os.Setenv("APP_BAD__", "x")
+	os.Setenv("OTHER_NAME", "other")
+	defer func() {
+		for _, k := range []string{"APP_SERVER__PORT", "APP_SERVER__DEBUG", "APP_SERVER__RATIO", "APP_NAME", "APP_BAD__", "OTHER_NAME"} {
+			os.Unsetenv(k)
+		}
+	}()
+
+	cs, err := NewSource(WithPrefix("APP_")).Read()
+	assert.Nil(t, err)
+	assert.Equal(t, "toml", cs.Format)
+
+	var data map[string]interface{}
+	assert.Nil(t, toml.Unmarshal(cs.Data, &data))
+	assert.Equal(t, map[string]interface{}{
+		"name": "demo",
+		"server": map[string]interface{}{
+			"port":  int64(8080),
+			"debug": true,
+			"ratio": 0.5,
+		},
+	}, data)
+}
+
+func TestEnvWithoutPrefix(t *testing.T) {
+	os.Setenv("APP_NAME", "demo")
+	defer os.Unsetenv("APP_NAME")
+
+	_, err := NewSource().Read()
+	assert.Equal(t, ErrNoPrefix, err)
+	_, err = NewSource(WithPrefix("")).Read()
+	assert.Equal(t, ErrNoPrefix, err)
+
+	cs, err := NewSource(WithAll()).Read()
+	assert.Nil(t, err)
+	var data map[string]interface{}
+	assert.Nil(t, toml.Unmarshal(cs.Data, &data))
+	assert.Equal(t, "demo", data["app_name"])
+}
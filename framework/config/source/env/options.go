@@ -0,0 +1,31 @@
+package env
+
+import (
+	"github.com/yunfeiyang1916/toolkit/framework/config/source"
+	"golang.org/x/net/context"
+)
+
+type prefixKey struct{}
+
+type allKey struct{}
+
+// WithPrefix only reads the variables with one of the prefixes, the prefix is
+// stripped from the key. The empty prefixes are ignored.
+func WithPrefix(p ...string) source.Option {
+	return func(o *source.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, prefixKey{}, p)
+	}
+}
+
+// WithAll reads all the variables of the environment when no prefix is set.
+func WithAll() source.Option {
+	return func(o *source.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, allKey{}, true)
+	}
+}
@@ -0,0 +1,142 @@
+// Package etcd is an etcd v3 source, changes are watched natively.
+package etcd
+
+import (
+	"bytes"
+	"context"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yunfeiyang1916/toolkit/framework/config/source"
+	"github.com/yunfeiyang1916/toolkit/logging"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+const (
+	defaultDialTimeout = 5 * time.Second
+	requestTimeout     = 5 * time.Second
+)
+
+type etcd struct {
+	path   string
+	prefix bool
+	client *clientv3.Client
+	err    error // the error of creating the client
+	opts   source.Options
+
+	mu   sync.Mutex
+	last string // the checksum of the last change set returned by Read
+}
+
+func (e *etcd) Read() (*source.ChangeSet, error) {
+	cs, _, err := e.read()
+	if err != nil {
+		logging.GenLogf("etcd Read failed, key %s, err %v", e.path, err)
+		return nil, err
+	}
+	e.mu.Lock()
+	e.last = cs.Checksum
+	e.mu.Unlock()
+	return cs, nil
+}
+
+// delivered returns the checksum of the last change set returned by Read,
+// it's empty if no Read has succeeded.
+func (e *etcd) delivered() string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.last
+}
+
+// read returns the change set and the store revision to watch from.
+func (e *etcd) read() (*source.ChangeSet, int64, error) {
+	if e.err != nil {
+		return nil, 0, e.err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+	var opts []clientv3.OpOption
+	if e.prefix {
+		opts = append(opts, clientv3.WithPrefix())
+	}
+	rsp, err := e.client.Get(ctx, e.path, opts...)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	buf := bytes.NewBuffer(nil)
+	if e.prefix {
+		value := make(map[string]string, len(rsp.Kvs))
+		for _, kv := range rsp.Kvs {
+			if len(kv.Value) == 0 {
+				continue
+			}
+			value[strings.TrimPrefix(string(kv.Key), e.path)] = string(kv.Value)
+		}
+		if len(value) > 0 {
+			b, err := e.opts.Encoder.Encode(value)
+			if err != nil {
+				return nil, 0, err
+			}
+			buf.Write(b)
+		}
+	} else if len(rsp.Kvs) > 0 {
+		buf.Write(rsp.Kvs[0].Value)
+	}
+	cs := &source.ChangeSet{
+		Timestamp: time.Now(),
+		Format:    e.format(),
+		Source:    e.String(),
+		Data:      buf.Bytes(),
+	}
+	cs.Checksum = cs.Sum()
+	return cs, rsp.Header.Revision, nil
+}
+
+// format is the extension of the key, or the encoder format.
+func (e *etcd) format() string {
+	if ext := strings.TrimPrefix(path.Ext(e.path), "."); !e.prefix && len(ext) > 0 {
+		return ext
+	}
+	return e.opts.Encoder.String()
+}
+
+func (e *etcd) String() string {
+	return "etcd"
+}
+
+func (e *etcd) Watch() (source.Watcher, error) {
+	if e.err != nil {
+		return nil, e.err
+	}
+	return newWatcher(e), nil
+}
+
+func NewSource(opts ...source.Option) source.Source {
+	options := source.NewOptions(opts...)
+	e := &etcd{opts: options}
+	e.path, _ = options.Context.Value(pathKey{}).(string)
+	e.prefix, _ = options.Context.Value(prefixKey{}).(bool)
+	if c, ok := options.Context.Value(clientKey{}).(*clientv3.Client); ok && c != nil {
+		e.client = c
+		return e
+	}
+
+	cfg := clientv3.Config{
+		Endpoints:   []string{"127.0.0.1:2379"},
+		DialTimeout: defaultDialTimeout,
+	}
+	if eps, ok := options.Context.Value(endpointsKey{}).([]string); ok && len(eps) > 0 {
+		cfg.Endpoints = eps
+	}
+	if a, ok := options.Context.Value(authKey{}).(auth); ok {
+		cfg.Username, cfg.Password = a.username, a.password
+	}
+	if d, ok := options.Context.Value(dialTimeoutKey{}).(time.Duration); ok && d > 0 {
+		cfg.DialTimeout = d
+	}
+	e.client, e.err = clientv3.New(cfg)
+	return e
+}
@@ -0,0 +1,93 @@
+package etcd
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yunfeiyang1916/toolkit/framework/config/source"
+	"github.com/yunfeiyang1916/toolkit/internal/etcdtest"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+func next(t *testing.T, w source.Watcher) *source.ChangeSet {
+	ch := make(chan *source.ChangeSet, 1)
+	go func() {
+		cs, _ := w.Next()
+		ch <- cs
+	}()
+	select {
+	case cs := <-ch:
+		return cs
+	case <-time.After(5 * time.Second):
+		t.Fatal("wait change set timeout")
+	}
+	return nil
+}
+
+func TestEtcd(t *testing.T) {
+	endpoint := etcdtest.Start(t)
+	c, err := clientv3.New(clientv3.Config{Endpoints: []string{endpoint}})
+	assert.Nil(t, err)
+	defer c.Close()
+	_, err = c.Put(context.Background(), "/service_config/a/config.json", `{"port":80}`)
+	assert.Nil(t, err)
+
+	s := NewSource(WithEndpoints(endpoint), WithAbsPath("/service_config/a/config.json"))
+	cs, err := s.Read()
+	assert.Nil(t, err)
+	assert.Equal(t, "json", cs.Format)
+	assert.Equal(t, `{"port":80}`, string(cs.Data))
+
+	w, err := s.Watch()
+	assert.Nil(t, err)
+	defer w.Stop()
+	time.Sleep(100 * time.Millisecond) // wait the watcher reading the key
+	_, err = c.Put(context.Background(), "/service_config/a/config.json", `{"port":81}`)
+	assert.Nil(t, err)
+	cs = next(t, w)
+	assert.Equal(t, `{"port":81}`, string(cs.Data))
+}
+
+func TestEtcdPrefix(t *testing.T) {
+	endpoint := etcdtest.Start(t)
+	c, err := clientv3.New(clientv3.Config{Endpoints: []string{endpoint}})
+	assert.Nil(t, err)
+	defer c.Close()
+	_, err = c.Put(context.Background(), "/conf/a", "1")
+	assert.Nil(t, err)
+
+	s := NewSource(WithClient(c), WithAbsPath("/conf/"), UsePrefix(true))
+	cs, err := s.Read()
+	assert.Nil(t, err)
+	assert.Equal(t, "toml", cs.Format)
+	assert.Equal(t, "a = \"1\"\n", string(cs.Data))
+
+	w, err := s.Watch()
+	assert.Nil(t, err)
+	time.Sleep(100 * time.Millisecond)
+	_, err = c.Put(context.Background(), "/conf/b", "2")
+	assert.Nil(t, err)
+	assert.Equal(t, "a = \"1\"\nb = \"2\"\n", string(next(t, w).Data))
+
+	assert.Nil(t, w.Stop())
+	_, err = w.Next()
+	assert.NotNil(t, err)
+}
+
+func TestEtcdWatchWithoutRead(t *testing.T) {
+	endpoint := etcdtest.Start(t)
+	c, err := clientv3.New(clientv3.Config{Endpoints: []string{endpoint}})
+	assert.Nil(t, err)
+	defer c.Close()
+	_, err = c.Put(context.Background(), "/service_config/a/config.json", `{"port":80}`)
+	assert.Nil(t, err)
+
+	// nothing has been delivered, the first read of the watcher is sent
+	s := NewSource(WithClient(c), WithAbsPath("/service_config/a/config.json"))
+	w, err := s.Watch()
+	assert.Nil(t, err)
+	defer w.Stop()
+	assert.Equal(t, `{"port":80}`, string(next(t, w).Data))
+}
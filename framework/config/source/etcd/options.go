@@ -0,0 +1,82 @@
+package etcd
+
+import (
+	"time"
+
+	"github.com/yunfeiyang1916/toolkit/framework/config/source"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"golang.org/x/net/context"
+)
+
+type endpointsKey struct{}
+type pathKey struct{}
+type prefixKey struct{}
+type authKey struct{}
+type dialTimeoutKey struct{}
+type clientKey struct{}
+
+type auth struct {
+	username string
+	password string
+}
+
+// WithEndpoints sets the etcd endpoints, 127.0.0.1:2379 by default
+func WithEndpoints(e ...string) source.Option {
+	return func(o *source.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, endpointsKey{}, e)
+	}
+}
+
+// WithAbsPath sets the key to read
+func WithAbsPath(p string) source.Option {
+	return func(o *source.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, pathKey{}, p)
+	}
+}
+
+// UsePrefix reads all the keys under the path, the data is the map of the
+// keys without the path to the values
+func UsePrefix(prefix bool) source.Option {
+	return func(o *source.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, prefixKey{}, prefix)
+	}
+}
+
+// WithAuth sets the etcd username and password
+func WithAuth(username, password string) source.Option {
+	return func(o *source.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, authKey{}, auth{username: username, password: password})
+	}
+}
+
+// WithDialTimeout sets the dial timeout of the etcd client
+func WithDialTimeout(d time.Duration) source.Option {
+	return func(o *source.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, dialTimeoutKey{}, d)
+	}
+}
+
+// WithClient uses an existing etcd client, the other client options are ignored
+func WithClient(c *clientv3.Client) source.Option {
+	return func(o *source.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, clientKey{}, c)
+	}
+}
@@ -0,0 +1,112 @@
+package etcd
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/yunfeiyang1916/toolkit/framework/config/source"
+	"github.com/yunfeiyang1916/toolkit/logging"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+type watcher struct {
+	e      *etcd
+	ch     chan *source.ChangeSet
+	exit   chan bool
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+func newWatcher(e *etcd) source.Watcher {
+	ctx, cancel := context.WithCancel(context.Background())
+	w := &watcher{
+		e:      e,
+		ch:     make(chan *source.ChangeSet),
+		exit:   make(chan bool),
+		ctx:    ctx,
+		cancel: cancel,
+	}
+	go w.run()
+	return w
+}
+
+// run watches the key from the revision of the last read, the key is read
+// again on the events, so a prefix change set has all the keys. The first
+// successful read is sent if it differs from what Read delivered, or if Read
+// delivered nothing.
+func (w *watcher) run() {
+	last := w.e.delivered()
+	for {
+		cs, rev, err := w.e.read()
+		if err != nil {
+			logging.GenLogf("etcd watcher, read key %s failed, err %v", w.e.path, err)
+		} else {
+			if cs.Checksum != last && !w.send(cs) {
+				return
+			}
+			last = cs.Checksum
+
+			opts := []clientv3.OpOption{clientv3.WithRev(rev + 1)}
+			if w.e.prefix {
+				opts = append(opts, clientv3.WithPrefix())
+			}
+			for rsp := range w.e.client.Watch(clientv3.WithRequireLeader(w.ctx), w.e.path, opts...) {
+				if err := rsp.Err(); err != nil {
+					logging.GenLogf("etcd watcher, watch key %s failed, err %v", w.e.path, err)
+					break
+				}
+				if len(rsp.Events) == 0 {
+					continue
+				}
+				cs, _, err := w.e.read()
+				if err != nil {
+					logging.GenLogf("etcd watcher, read key %s failed, err %v", w.e.path, err)
+					break
+				}
+				if cs.Checksum == last {
+					continue
+				}
+				if !w.send(cs) {
+					return
+				}
+				last = cs.Checksum
+			}
+		}
+		select {
+		case <-w.exit:
+			logging.GenLogf("etcd watcher closed, key %s", w.e.path)
+			return
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+func (w *watcher) send(cs *source.ChangeSet) bool {
+	select {
+	case w.ch <- cs:
+		return true
+	case <-w.exit:
+		return false
+	}
+}
+
+func (w *watcher) Next() (*source.ChangeSet, error) {
+	select {
+	case cs := <-w.ch:
+		return cs, nil
+	case <-w.exit:
+		return nil, errors.New("watcher stopped")
+	}
+}
+
+func (w *watcher) Stop() error {
+	select {
+	case <-w.exit:
+		return nil
+	default:
+		close(w.exit)
+		w.cancel()
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+// Package flag is a command line flag source. --server.port=8080 is read as
+// server.port = 8080.
+package flag
+
+import (
+	"flag"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/yunfeiyang1916/toolkit/framework/config/source"
+	"github.com/yunfeiyang1916/toolkit/framework/config/source/internal/kv"
+)
+
+type flagSource struct {
+	args []string
+	fs   *flag.FlagSet
+	opts source.Options
+}
+
+func (f *flagSource) Read() (*source.ChangeSet, error) {
+	data := make(map[string]interface{})
+	if f.fs != nil {
+		f.fs.Visit(func(fl *flag.Flag) {
+			set(data, fl.Name, fl.Value.String())
+		})
+	} else {
+		for name, value := range parse(f.args) {
+			set(data, name, value)
+		}
+	}
+	return kv.ChangeSet(data, f.String(), source.PriorityFlag)
+}
+
+func set(data map[string]interface{}, name, value string) {
+	keys := strings.Split(name, ".")
+	for _, k := range keys {
+		if len(k) == 0 {
+			return
+		}
+	}
+	kv.Set(data, keys, kv.Coerce(value))
+}
+
+// parse returns the config flags of args. Only the flags with a dotted name
+// are config keys, so the application flags like --config are skipped. A flag
+// without value is true, the next argument is the value unless it's a flag,
+// a negative number like -5 is a value. The parsing stops at "--".
+func parse(args []string) map[string]string {
+	flags := make(map[string]string)
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			break
+		}
+		if len(arg) < 2 || arg[0] != '-' {
+			continue
+		}
+		name := strings.TrimLeft(arg, "-")
+		value, hasValue := "", false
+		if idx := strings.Index(name, "="); idx >= 0 {
+			name, value, hasValue = name[:idx], name[idx+1:], true
+		} else if i+1 < len(args) && isValue(args[i+1]) {
+			i++
+			value, hasValue = args[i], true
+		}
+		if !strings.Contains(name, ".") {
+			continue
+		}
+		if !hasValue {
+			value = "true"
+		}
+		flags[name] = value
+	}
+	return flags
+}
+
+// isValue tells whether arg is the value of the flag before it, it's not a flag
+// or it's a number.
+func isValue(arg string) bool {
+	if !strings.HasPrefix(arg, "-") {
+		return true
+	}
+	_, err := strconv.ParseFloat(arg, 64)
+	return err == nil
+}
+
+// Watch returns a watcher which never fires, the command line does not change.
+func (f *flagSource) Watch() (source.Watcher, error) {
+	return kv.NewWatcher(), nil
+}
+
+func (f *flagSource) String() string {
+	return "flag"
+}
+
+// NewSource reads the command line arguments, or the flags set on the flag
+// set given by WithFlagSet.
+func NewSource(opts ...source.Option) source.Source {
+	options := source.NewOptions(opts...)
+	f := &flagSource{opts: options, args: os.Args[1:]}
+	if args, ok := options.Context.Value(argsKey{}).([]string); ok {
+		f.args = args
+	}
+	if fs, ok := options.Context.Value(flagSetKey{}).(*flag.FlagSet); ok {
+		f.fs = fs
+	}
+	return f
+}
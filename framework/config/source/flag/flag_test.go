@@ -0,0 +1,42 @@
+package flag
+
+import (
+	"flag"
+	"testing"
+
+	"github.com/BurntSushi/toml"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	flags := parse([]string{
+		"--config", "config/config.toml",
+		"--server.port=8080",
+		"-server.host", "127.0.0.1",
+		"--server.debug",
+		"--server.offset", "-5",
+		"--server.ratio", "-0.5",
+		"--log.level", "--", "--db.name=x",
+	})
+	assert.Equal(t, map[string]string{
+		"server.port":   "8080",
+		"server.host":   "127.0.0.1",
+		"server.debug":  "true",
+		"server.offset": "-5",
+		"server.ratio":  "-0.5",
+		"log.level":     "true",
+	}, flags)
+}
+
+func TestFlagSet(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.Int("port", 80, "")
+	fs.String("name", "", "")
+	assert.Nil(t, fs.Parse([]string{"-port", "8080"}))
+
+	cs, err := NewSource(WithFlagSet(fs)).Read()
+	assert.Nil(t, err)
+	var data map[string]interface{}
+	assert.Nil(t, toml.Unmarshal(cs.Data, &data))
+	assert.Equal(t, map[string]interface{}{"port": int64(8080)}, data)
+}
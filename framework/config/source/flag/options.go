@@ -0,0 +1,32 @@
+package flag
+
+import (
+	"flag"
+
+	"github.com/yunfeiyang1916/toolkit/framework/config/source"
+	"golang.org/x/net/context"
+)
+
+type argsKey struct{}
+type flagSetKey struct{}
+
+// WithArgs sets the arguments to parse, os.Args[1:] by default.
+func WithArgs(args []string) source.Option {
+	return func(o *source.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, argsKey{}, args)
+	}
+}
+
+// WithFlagSet reads the flags set on a parsed flag set instead of the
+// arguments, every flag set on the command line is a config key.
+func WithFlagSet(fs *flag.FlagSet) source.Option {
+	return func(o *source.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, flagSetKey{}, fs)
+	}
+}
@@ -0,0 +1,182 @@
+// Package http is a source polling a url, the requests are conditional with
+// the ETag and Last-Modified of the last response.
+package http
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	stdhttp "net/http"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yunfeiyang1916/toolkit/framework/config/reader"
+	"github.com/yunfeiyang1916/toolkit/framework/config/source"
+	"github.com/yunfeiyang1916/toolkit/logging"
+)
+
+const (
+	DefaultInterval   = 10 * time.Second
+	DefaultMaxBackoff = 5 * time.Minute
+	DefaultTimeout    = 5 * time.Second
+	DefaultMaxSize    = 10 << 20
+)
+
+// the formats of the content types
+var contentTypes = map[string]string{
+	"application/json":   "json",
+	"text/json":          "json",
+	"application/toml":   "toml",
+	"text/toml":          "toml",
+	"application/yaml":   "yaml",
+	"application/x-yaml": "yaml",
+	"text/yaml":          "yaml",
+	"text/x-yaml":        "yaml",
+	"application/xml":    "xml",
+	"text/xml":           "xml",
+}
+
+type httpSource struct {
+	url        string
+	logURL     string // the url without the password, for the logs
+	maxSize    int64
+	interval   time.Duration
+	maxBackoff time.Duration
+	header     stdhttp.Header
+	client     *stdhttp.Client
+	opts       source.Options
+
+	mu           sync.Mutex
+	etag         string
+	lastModified string
+	last         *source.ChangeSet
+}
+
+func (h *httpSource) Read() (*source.ChangeSet, error) {
+	cs, _, err := h.fetch()
+	if err != nil {
+		logging.GenLogf("http Read, url %s, err:%v", h.logURL, err)
+		return nil, err
+	}
+	return cs, nil
+}
+
+// fetch requests the url, it returns the last change set and false when the
+// content is not modified.
+func (h *httpSource) fetch() (*source.ChangeSet, bool, error) {
+	req, err := stdhttp.NewRequest(stdhttp.MethodGet, h.url, nil)
+	if err != nil {
+		return nil, false, err
+	}
+	for k, v := range h.header {
+		req.Header[k] = v
+	}
+	h.mu.Lock()
+	if h.last != nil {
+		if len(h.etag) > 0 {
+			req.Header.Set("If-None-Match", h.etag)
+		}
+		if len(h.lastModified) > 0 {
+			req.Header.Set("If-Modified-Since", h.lastModified)
+		}
+	}
+	h.mu.Unlock()
+
+	rsp, err := h.client.Do(req)
+	if err != nil {
+		return nil, false, err
+	}
+	defer rsp.Body.Close()
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if rsp.StatusCode == stdhttp.StatusNotModified && h.last != nil {
+		return copySet(h.last), false, nil
+	}
+	if rsp.StatusCode != stdhttp.StatusOK {
+		return nil, false, fmt.Errorf("http source: %s %s", h.logURL, rsp.Status)
+	}
+	b, err := ioutil.ReadAll(io.LimitReader(rsp.Body, h.maxSize+1))
+	if err != nil {
+		return nil, false, err
+	}
+	if int64(len(b)) > h.maxSize {
+		return nil, false, fmt.Errorf("http source: %s body larger than %d bytes", h.logURL, h.maxSize)
+	}
+	cs := &source.ChangeSet{
+		Format:    h.format(rsp.Header.Get("Content-Type")),
+		Source:    h.String(),
+		Timestamp: time.Now(),
+		Data:      b,
+	}
+	cs.Checksum = cs.Sum()
+	changed := h.last == nil || h.last.Checksum != cs.Checksum
+	h.etag = rsp.Header.Get("ETag")
+	h.lastModified = rsp.Header.Get("Last-Modified")
+	h.last = cs
+	return copySet(cs), changed, nil
+}
+
+// format is the extension of the url path if it's a known encoding, or the
+// format of the content type, or the encoder format.
+func (h *httpSource) format(contentType string) string {
+	if u, err := url.Parse(h.url); err == nil {
+		ext := strings.TrimPrefix(path.Ext(u.Path), ".")
+		if _, ok := reader.Encoding[ext]; ok {
+			return ext
+		}
+	}
+	if mt, _, err := mime.ParseMediaType(contentType); err == nil {
+		if f, ok := contentTypes[mt]; ok {
+			return f
+		}
+	}
+	return h.opts.Encoder.String()
+}
+
+func copySet(cs *source.ChangeSet) *source.ChangeSet {
+	c := *cs
+	return &c
+}
+
+func (h *httpSource) String() string {
+	return "http"
+}
+
+func (h *httpSource) Watch() (source.Watcher, error) {
+	return newWatcher(h), nil
+}
+
+func NewSource(opts ...source.Option) source.Source {
+	options := source.NewOptions(opts...)
+	h := &httpSource{
+		interval:   DefaultInterval,
+		maxBackoff: DefaultMaxBackoff,
+		maxSize:    DefaultMaxSize,
+		opts:       options,
+	}
+	h.url, _ = options.Context.Value(urlKey{}).(string)
+	h.logURL = h.url
+	if u, err := url.Parse(h.url); err == nil {
+		h.logURL = u.Redacted()
+	}
+	if n, ok := options.Context.Value(maxSizeKey{}).(int64); ok && n > 0 {
+		h.maxSize = n
+	}
+	if d, ok := options.Context.Value(intervalKey{}).(time.Duration); ok && d > 0 {
+		h.interval = d
+	}
+	if d, ok := options.Context.Value(maxBackoffKey{}).(time.Duration); ok && d > 0 {
+		h.maxBackoff = d
+	}
+	h.header, _ = options.Context.Value(headerKey{}).(stdhttp.Header)
+	h.client, _ = options.Context.Value(clientKey{}).(*stdhttp.Client)
+	if h.client == nil {
+		h.client = &stdhttp.Client{Timeout: DefaultTimeout}
+	}
+	return h
+}
@@ -0,0 +1,78 @@
+package http
+
+import (
+	stdhttp "net/http"
+	"time"
+
+	"github.com/yunfeiyang1916/toolkit/framework/config/source"
+	"golang.org/x/net/context"
+)
+
+type urlKey struct{}
+type intervalKey struct{}
+type maxBackoffKey struct{}
+type headerKey struct{}
+type clientKey struct{}
+type maxSizeKey struct{}
+
+// WithURL sets the url to poll
+func WithURL(u string) source.Option {
+	return func(o *source.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, urlKey{}, u)
+	}
+}
+
+// WithInterval sets the polling interval, DefaultInterval by default
+func WithInterval(d time.Duration) source.Option {
+	return func(o *source.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, intervalKey{}, d)
+	}
+}
+
+// WithMaxBackoff sets the max polling interval after errors, the interval
+// doubles on each error, DefaultMaxBackoff by default
+func WithMaxBackoff(d time.Duration) source.Option {
+	return func(o *source.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, maxBackoffKey{}, d)
+	}
+}
+
+// WithHeader sets the request headers, e.g. an authorization token
+func WithHeader(h stdhttp.Header) source.Option {
+	return func(o *source.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, headerKey{}, h)
+	}
+}
+
+// WithClient sets the http client, a client with DefaultTimeout by default
+func WithClient(c *stdhttp.Client) source.Option {
+	return func(o *source.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, clientKey{}, c)
+	}
+}
+
+// WithMaxSize sets the max size of the response body, a larger body is an
+// error, DefaultMaxSize by default
+func WithMaxSize(n int64) source.Option {
+	return func(o *source.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, maxSizeKey{}, n)
+	}
+}
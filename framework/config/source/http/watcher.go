@@ -0,0 +1,78 @@
+package http
+
+import (
+	"errors"
+	"time"
+
+	"github.com/yunfeiyang1916/toolkit/framework/config/source"
+	"github.com/yunfeiyang1916/toolkit/logging"
+)
+
+type watcher struct {
+	h    *httpSource
+	exit chan bool
+	ch   chan *source.ChangeSet
+}
+
+func newWatcher(h *httpSource) source.Watcher {
+	w := &watcher{
+		h:    h,
+		exit: make(chan bool),
+		ch:   make(chan *source.ChangeSet),
+	}
+	go w.run()
+	return w
+}
+
+// run polls the url every interval, the interval doubles on each error up
+// to the max backoff.
+func (w *watcher) run() {
+	wait := w.h.interval
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	for {
+		select {
+		case <-w.exit:
+			logging.GenLogf("http watcher closed, url %s", w.h.logURL)
+			return
+		case <-timer.C:
+		}
+		cs, changed, err := w.h.fetch()
+		switch {
+		case err != nil:
+			if wait *= 2; wait > w.h.maxBackoff {
+				wait = w.h.maxBackoff
+			}
+			logging.GenLogf("http watcher, url %s, retry in %v, err:%v", w.h.logURL, wait, err)
+		case changed:
+			wait = w.h.interval
+			select {
+			case w.ch <- cs:
+			case <-w.exit:
+				return
+			}
+		default:
+			wait = w.h.interval
+		}
+		timer.Reset(wait)
+	}
+}
+
+func (w *watcher) Next() (*source.ChangeSet, error) {
+	select {
+	case <-w.exit:
+		return nil, errors.New("watcher stopped")
+	case cs := <-w.ch:
+		return cs, nil
+	}
+}
+
+func (w *watcher) Stop() error {
+	select {
+	case <-w.exit:
+		return nil
+	default:
+		close(w.exit)
+	}
+	return nil
+}
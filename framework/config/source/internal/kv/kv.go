@@ -0,0 +1,103 @@
+// Package kv builds change sets from flat key value pairs, used by the env
+// and flag sources.
+package kv
+
+import (
+	"errors"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/yunfeiyang1916/toolkit/framework/config/encoder/toml"
+	"github.com/yunfeiyang1916/toolkit/framework/config/source"
+	"github.com/yunfeiyang1916/toolkit/logging"
+)
+
+// Set stores value into m under the nested keys, an existing non table value
+// on the path is replaced.
+func Set(m map[string]interface{}, keys []string, value interface{}) {
+	for _, k := range keys[:len(keys)-1] {
+		sub, ok := m[k].(map[string]interface{})
+		if !ok {
+			if _, exist := m[k]; exist {
+				logging.GenLogf("config kv, key %s is overridden by table %s", k, strings.Join(keys, "."))
+			}
+			sub = make(map[string]interface{})
+			m[k] = sub
+		}
+		m = sub
+	}
+	k := keys[len(keys)-1]
+	if _, ok := m[k].(map[string]interface{}); ok {
+		logging.GenLogf("config kv, table %s is overridden by value", strings.Join(keys, "."))
+	}
+	m[k] = value
+}
+
+// the decimal floats of toml, without inf and nan
+var floatRe = regexp.MustCompile(`^-?(0|[1-9][0-9]*)(\.[0-9]+)?([eE][-+]?[0-9]+)?$`)
+
+// Coerce converts a string to a bool, an integer or a float when it is in
+// the canonical form of one, so the string is written back the same: "007",
+// "+1", "0x10", "1_000", "TRUE" or "inf" are kept as strings.
+func Coerce(s string) interface{} {
+	switch s {
+	case "true":
+		return true
+	case "false":
+		return false
+	}
+	if i, err := strconv.ParseInt(s, 10, 64); err == nil && strconv.FormatInt(i, 10) == s {
+		return i
+	}
+	if floatRe.MatchString(s) {
+		if f, err := strconv.ParseFloat(s, 64); err == nil && !math.IsInf(f, 0) {
+			return f
+		}
+	}
+	return s
+}
+
+// ChangeSet encodes m in toml so the values keep their types when merged.
+func ChangeSet(m map[string]interface{}, src string, priority int) (*source.ChangeSet, error) {
+	enc := toml.NewEncoder()
+	b, err := enc.Encode(m)
+	if err != nil {
+		return nil, err
+	}
+	cs := &source.ChangeSet{
+		Data:      b,
+		Format:    enc.String(),
+		Source:    src,
+		Timestamp: time.Now(),
+		Priority:  priority,
+	}
+	cs.Checksum = cs.Sum()
+	return cs, nil
+}
+
+// Watcher is the watcher of a source which never changes, Next blocks until
+// the watcher is stopped.
+type Watcher struct {
+	exit chan bool
+}
+
+func NewWatcher() *Watcher {
+	return &Watcher{exit: make(chan bool)}
+}
+
+func (w *Watcher) Next() (*source.ChangeSet, error) {
+	<-w.exit
+	return nil, errors.New("watcher stopped")
+}
+
+func (w *Watcher) Stop() error {
+	select {
+	case <-w.exit:
+	default:
+		close(w.exit)
+	}
+	return nil
+}
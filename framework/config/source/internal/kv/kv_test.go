@@ -0,0 +1,31 @@
+package kv
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCoerce(t *testing.T) {
+	for s, want := range map[string]interface{}{
+		"true":    true,
+		"false":   false,
+		"8080":    int64(8080),
+		"-1":      int64(-1),
+		"0.5":     0.5,
+		"-1.5e3":  -1500.0,
+		"TRUE":    "TRUE",
+		"007":     "007",
+		"+1":      "+1",
+		"0x10":    "0x10",
+		"1_000":   "1_000",
+		".5":      ".5",
+		"1e400":   "1e400",
+		"inf":     "inf",
+		"NaN":     "NaN",
+		"1.2.3.4": "1.2.3.4",
+		"":        "",
+	} {
+		assert.Equal(t, want, Coerce(s), s)
+	}
+}
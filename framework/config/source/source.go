@@ -18,12 +18,22 @@ type Watcher interface {
 	Stop() error
 }
 
+// Priority of the change sets, the loader merges a change set of higher
+// priority over the lower ones whatever the load order, change sets of the
+// same priority are merged in the load order.
+const (
+	PriorityDefault = 0 // file, consul and memory sources
+	PriorityEnv     = 10
+	PriorityFlag    = 20
+)
+
 type ChangeSet struct {
 	Data      []byte
 	Checksum  string
 	Format    string
 	Source    string
 	Timestamp time.Time
+	Priority  int
 }
 
 func (c *ChangeSet) Sum() string {
@@ -0,0 +1,111 @@
+package config
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/yunfeiyang1916/toolkit/framework/config/encoder/toml"
+	"github.com/yunfeiyang1916/toolkit/framework/config/loader"
+	"github.com/yunfeiyang1916/toolkit/framework/config/validate"
+	"github.com/yunfeiyang1916/toolkit/logging"
+)
+
+// subscriber is a callback registered on a key prefix
+type subscriber struct {
+	id     int
+	prefix string
+	fn     func([]loader.Change)
+}
+
+type subscribers struct {
+	sync.Mutex
+	next int
+	subs []*subscriber
+}
+
+func (s *subscribers) add(prefix string, fn func([]loader.Change)) func() {
+	s.Lock()
+	defer s.Unlock()
+	s.next++
+	id := s.next
+	s.subs = append(s.subs, &subscriber{id: id, prefix: prefix, fn: fn})
+	return func() {
+		s.Lock()
+		defer s.Unlock()
+		for i, sub := range s.subs {
+			if sub.id == id {
+				s.subs = append(s.subs[:i:i], s.subs[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// notify calls the subscribers of the changed prefixes in the registering order.
+func (s *subscribers) notify(changes []loader.Change) {
+	if len(changes) == 0 {
+		return
+	}
+	s.Lock()
+	subs := s.subs
+	s.Unlock()
+	for _, sub := range subs {
+		if cs := loader.Filter(changes, sub.prefix); len(cs) > 0 {
+			sub.fn(cs)
+		}
+	}
+}
+
+// Subscribe calls fn with the key changes under the prefix every time the
+// config changes, an empty prefix subscribes all the keys. The prefix is a
+// dotted key path like server.breaker, call cancel to unsubscribe.
+func (c *defaultConfig) Subscribe(prefix string, fn func(changes []loader.Change)) (cancel func()) {
+	return c.subs.add(strings.Trim(prefix, "."), fn)
+}
+
+// SubscribeSection decodes the section of the prefix into structPtr, and
+// calls fn with a newly decoded value of the same type every time the section
+// changes. The section gets the default tag values and must pass the validate
+// tag constraints, a rejected change is logged and fn is not called.
+func (c *defaultConfig) SubscribeSection(prefix string, structPtr interface{}, fn func(v interface{})) (cancel func(), err error) {
+	rt := reflect.TypeOf(structPtr)
+	if rt == nil || rt.Kind() != reflect.Ptr || rt.Elem().Kind() != reflect.Struct {
+		return nil, errors.New("config: SubscribeSection needs a struct pointer")
+	}
+	prefix = strings.Trim(prefix, ".")
+	if err := decodeSection(c.Map(), prefix, structPtr); err != nil {
+		return nil, err
+	}
+	return c.Subscribe(prefix, func([]loader.Change) {
+		ins := reflect.New(rt.Elem()).Interface()
+		if err := decodeSection(c.Map(), prefix, ins); err != nil {
+			logging.GenLogf("on config subscribe, section %s rejected, err %v", prefix, err)
+			return
+		}
+		fn(ins)
+	}), nil
+}
+
+// decodeSection decodes the table of the dotted key path, an absent table
+// decodes like an empty one.
+func decodeSection(m map[string]interface{}, prefix string, v interface{}) error {
+	section := m
+	if len(prefix) > 0 {
+		for _, key := range strings.Split(prefix, ".") {
+			mm, ok := section[key].(map[string]interface{})
+			if !ok {
+				section = map[string]interface{}{}
+				break
+			}
+			section = mm
+		}
+	}
+	enc := toml.NewEncoder()
+	b, err := enc.Encode(section)
+	if err != nil {
+		return err
+	}
+	return validate.Decode(v, func(i interface{}) error { return enc.Decode(b, i) })
+}
@@ -0,0 +1,351 @@
+// Package validate fills config structs with the defaults in struct tags and
+// checks their constraints.
+//
+//	type Server struct {
+//		Addr    string        `toml:"addr" validate:"required"`
+//		Port    int           `toml:"port" default:"8080" validate:"min=1,max=65535"`
+//		Timeout time.Duration `toml:"timeout" default:"30s" validate:"min=1ms"`
+//		Mode    string        `toml:"mode" default:"release" validate:"enum=debug|release"`
+//		Name    string        `toml:"name" validate:"regex=^[a-z][a-z0-9.]*$"`
+//	}
+//
+// The rules of the validate tag are separated by comma, regex must be the last
+// rule as the expression may contain commas. min and max compare the value of
+// numbers and durations, the length of strings, slices and maps.
+package validate
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultTag  = "default"
+	validateTag = "validate"
+)
+
+var (
+	durationType        = reflect.TypeOf(time.Duration(0))
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+
+	regexps sync.Map // string -> *regexp.Regexp
+)
+
+// Errors holds all the constraint violations of a struct.
+type Errors []error
+
+func (e Errors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return "config validate: " + strings.Join(msgs, "; ")
+}
+
+// FieldError is a violated constraint of a field, Field is the dotted path
+// of the field by its toml names.
+type FieldError struct {
+	Field string
+	Rule  string
+	Msg   string
+}
+
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("%s %s", e.Field, e.Msg)
+}
+
+// SetDefaults sets the default tag values to the zero fields of the struct
+// v points to, nested structs included.
+func SetDefaults(v interface{}) error {
+	rv, err := structValue(v)
+	if err != nil {
+		return err
+	}
+	return setDefaults(rv, "")
+}
+
+// Struct checks the validate tag constraints of the struct v points to, all
+// the violations are returned as Errors.
+func Struct(v interface{}) error {
+	rv, err := structValue(v)
+	if err != nil {
+		return err
+	}
+	var errs Errors
+	validateStruct(rv, "", &errs)
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// Decode fills v with defaults, decodes into it and validates the result.
+func Decode(v interface{}, decode func(v interface{}) error) error {
+	if err := SetDefaults(v); err != nil {
+		return err
+	}
+	if err := decode(v); err != nil {
+		return err
+	}
+	return Struct(v)
+}
+
+func structValue(v interface{}) (reflect.Value, error) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return reflect.Value{}, fmt.Errorf("config validate: %T is not a struct pointer", v)
+	}
+	rv = rv.Elem()
+	if rv.Kind() != reflect.Struct {
+		return reflect.Value{}, fmt.Errorf("config validate: %T is not a struct pointer", v)
+	}
+	return rv, nil
+}
+
+func fieldName(f reflect.StructField, parent string) string {
+	name := f.Name
+	if tag := strings.Split(f.Tag.Get("toml"), ",")[0]; len(tag) > 0 && tag != "-" {
+		name = tag
+	}
+	if len(parent) == 0 {
+		return name
+	}
+	return parent + "." + name
+}
+
+func setDefaults(rv reflect.Value, parent string) error {
+	t := rv.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if len(f.PkgPath) > 0 {
+			continue
+		}
+		fv := rv.Field(i)
+		name := fieldName(f, parent)
+		if def, ok := f.Tag.Lookup(defaultTag); ok && fv.IsZero() {
+			if err := setValue(fv, def); err != nil {
+				return fmt.Errorf("config validate: %s bad default %q: %v", name, def, err)
+			}
+		}
+		if sv, ok := nestedStruct(fv); ok {
+			if err := setDefaults(sv, name); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// nestedStruct returns the struct of a struct or non nil struct pointer field.
+func nestedStruct(fv reflect.Value) (reflect.Value, bool) {
+	if fv.Kind() == reflect.Ptr {
+		if fv.IsNil() {
+			return reflect.Value{}, false
+		}
+		fv = fv.Elem()
+	}
+	if fv.Kind() != reflect.Struct || fv.Type() == reflect.TypeOf(time.Time{}) {
+		return reflect.Value{}, false
+	}
+	return fv, true
+}
+
+func setValue(fv reflect.Value, s string) error {
+	if fv.CanAddr() && fv.Addr().Type().Implements(textUnmarshalerType) {
+		return fv.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s))
+	}
+	switch fv.Kind() {
+	case reflect.String:
+		fv.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		fv.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if fv.Type().ConvertibleTo(durationType) && fv.Kind() == reflect.Int64 {
+			if d, err := time.ParseDuration(s); err == nil {
+				fv.SetInt(int64(d))
+				return nil
+			}
+		}
+		i, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return err
+		}
+		fv.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			return err
+		}
+		fv.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return err
+		}
+		fv.SetFloat(f)
+	case reflect.Slice:
+		parts := strings.Split(s, ",")
+		sl := reflect.MakeSlice(fv.Type(), len(parts), len(parts))
+		for i, p := range parts {
+			if err := setValue(sl.Index(i), strings.TrimSpace(p)); err != nil {
+				return err
+			}
+		}
+		fv.Set(sl)
+	case reflect.Ptr:
+		pv := reflect.New(fv.Type().Elem())
+		if err := setValue(pv.Elem(), s); err != nil {
+			return err
+		}
+		fv.Set(pv)
+	default:
+		return fmt.Errorf("unsupported type %s", fv.Type())
+	}
+	return nil
+}
+
+func validateStruct(rv reflect.Value, parent string, errs *Errors) {
+	t := rv.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if len(f.PkgPath) > 0 {
+			continue
+		}
+		fv := rv.Field(i)
+		name := fieldName(f, parent)
+		if tag, ok := f.Tag.Lookup(validateTag); ok {
+			validateField(fv, name, tag, errs)
+		}
+		if sv, ok := nestedStruct(fv); ok {
+			validateStruct(sv, name, errs)
+		}
+	}
+}
+
+func validateField(fv reflect.Value, name, tag string, errs *Errors) {
+	for len(tag) > 0 {
+		var rule string
+		if strings.HasPrefix(tag, "regex=") {
+			rule, tag = tag, ""
+		} else if idx := strings.Index(tag, ","); idx >= 0 {
+			rule, tag = tag[:idx], tag[idx+1:]
+		} else {
+			rule, tag = tag, ""
+		}
+		rule = strings.TrimSpace(rule)
+		if len(rule) == 0 {
+			continue
+		}
+		key, arg := rule, ""
+		if idx := strings.Index(rule, "="); idx >= 0 {
+			key, arg = rule[:idx], rule[idx+1:]
+		}
+		if msg := check(fv, key, arg); len(msg) > 0 {
+			*errs = append(*errs, &FieldError{Field: name, Rule: rule, Msg: msg})
+		}
+	}
+}
+
+// check returns the violation message of a rule, empty when the value passes.
+func check(fv reflect.Value, key, arg string) string {
+	if key != "required" && fv.Kind() == reflect.Ptr && fv.IsNil() {
+		return ""
+	}
+	switch key {
+	case "required":
+		if fv.IsZero() {
+			return "is required"
+		}
+	case "min", "max":
+		n, bound, err := compare(fv, arg)
+		if err != nil {
+			return err.Error()
+		}
+		if key == "min" && n < bound {
+			return fmt.Sprintf("must be at least %s", arg)
+		}
+		if key == "max" && n > bound {
+			return fmt.Sprintf("must be at most %s", arg)
+		}
+	case "enum":
+		s := fmt.Sprint(indirect(fv).Interface())
+		for _, e := range strings.Split(arg, "|") {
+			if s == e {
+				return ""
+			}
+		}
+		return fmt.Sprintf("must be one of %s, got %q", strings.Replace(arg, "|", ", ", -1), s)
+	case "regex":
+		fv = indirect(fv)
+		if fv.Kind() != reflect.String {
+			return "regex rule on non string field"
+		}
+		re, err := compile(arg)
+		if err != nil {
+			return fmt.Sprintf("bad regex %q: %v", arg, err)
+		}
+		if !re.MatchString(fv.String()) {
+			return fmt.Sprintf("must match %s, got %q", arg, fv.String())
+		}
+	default:
+		return fmt.Sprintf("unknown rule %q", key)
+	}
+	return ""
+}
+
+func indirect(fv reflect.Value) reflect.Value {
+	for fv.Kind() == reflect.Ptr && !fv.IsNil() {
+		fv = fv.Elem()
+	}
+	return fv
+}
+
+// compare returns the value to compare of fv and the parsed bound.
+func compare(fv reflect.Value, arg string) (float64, float64, error) {
+	fv = indirect(fv)
+	var n float64
+	switch fv.Kind() {
+	case reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+		n = float64(fv.Len())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n = float64(fv.Int())
+		if fv.Kind() == reflect.Int64 && fv.Type().ConvertibleTo(durationType) {
+			if d, err := time.ParseDuration(arg); err == nil {
+				return n, float64(d), nil
+			}
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n = float64(fv.Uint())
+	case reflect.Float32, reflect.Float64:
+		n = fv.Float()
+	default:
+		return 0, 0, fmt.Errorf("min/max rule on unsupported type %s", fv.Type())
+	}
+	bound, err := strconv.ParseFloat(arg, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("bad bound %q", arg)
+	}
+	return n, bound, nil
+}
+
+func compile(expr string) (*regexp.Regexp, error) {
+	if re, ok := regexps.Load(expr); ok {
+		return re.(*regexp.Regexp), nil
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, err
+	}
+	regexps.Store(expr, re)
+	return re, nil
+}
@@ -0,0 +1,72 @@
+package validate
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type duration time.Duration
+
+type server struct {
+	Addr    string        `toml:"addr" validate:"required"`
+	Port    int           `toml:"port" default:"8080" validate:"min=1,max=65535"`
+	Timeout time.Duration `toml:"timeout" default:"30s" validate:"min=1ms,max=1m"`
+	Idle    duration      `toml:"idle" default:"1m"`
+	Mode    string        `toml:"mode" default:"release" validate:"enum=debug|release"`
+	Name    string        `toml:"name" validate:"regex=^[a-z]{1,3}(,[a-z]+)?$"`
+	Hosts   []string      `toml:"hosts" default:"a, b" validate:"min=1"`
+	Ratio   *float64      `toml:"ratio" default:"0.5" validate:"max=1"`
+}
+
+type app struct {
+	Server server `toml:"server"`
+	Debug  bool   `toml:"debug" default:"true"`
+	Limit  uint   `default:"10"`
+}
+
+func TestSetDefaults(t *testing.T) {
+	a := &app{}
+	a.Server.Port = 9090
+	assert.Nil(t, SetDefaults(a))
+	assert.Equal(t, 9090, a.Server.Port)
+	assert.Equal(t, 30*time.Second, a.Server.Timeout)
+	assert.Equal(t, duration(time.Minute), a.Server.Idle)
+	assert.Equal(t, "release", a.Server.Mode)
+	assert.Equal(t, []string{"a", "b"}, a.Server.Hosts)
+	assert.Equal(t, 0.5, *a.Server.Ratio)
+	assert.True(t, a.Debug)
+	assert.Equal(t, uint(10), a.Limit)
+
+	bad := &struct {
+		Port int `default:"x"`
+	}{}
+	assert.NotNil(t, SetDefaults(bad))
+	assert.NotNil(t, SetDefaults(app{}))
+}
+
+func TestStruct(t *testing.T) {
+	a := &app{}
+	assert.Nil(t, SetDefaults(a))
+	a.Server.Addr = "127.0.0.1"
+	a.Server.Name = "abc,def"
+	assert.Nil(t, Struct(a))
+
+	ratio := 2.0
+	a.Server.Addr = ""
+	a.Server.Port = 70000
+	a.Server.Timeout = time.Hour
+	a.Server.Mode = "test"
+	a.Server.Name = "abcd"
+	a.Server.Hosts = nil
+	a.Server.Ratio = &ratio
+	err := Struct(a)
+	errs, ok := err.(Errors)
+	assert.True(t, ok)
+	var fields []string
+	for _, e := range errs {
+		fields = append(fields, e.(*FieldError).Field)
+	}
+	assert.Equal(t, []string{"server.addr", "server.port", "server.timeout", "server.mode", "server.name", "server.hosts", "server.ratio"}, fields)
+}
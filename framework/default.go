@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"os"
 
 	"github.com/opentracing/opentracing-go"
 	"github.com/yunfeiyang1916/toolkit/framework/breaker"
@@ -62,6 +63,10 @@ func Shutdown() error {
 	return Default.Shutdown()
 }
 
+func ShutdownOnSignal(sigs ...os.Signal) {
+	Default.ShutdownOnSignal(sigs...)
+}
+
 func Config() *config.Namespace {
 	return Default.Config()
 }
@@ -0,0 +1,109 @@
+// Package feature evaluates the feature flags locally, the flags are read
+// from a config section or watched from a registry KV prefix.
+//
+//	f := feature.NewFromConfig(framework.ConfigInstance(), "feature",
+//		feature.WithDefault("new_checkout", false))
+//	if f.EnabledCtx(ctx, "new_checkout", feature.Attributes{feature.AttrUserID: uid}) {
+//		// ...
+//	}
+package feature
+
+import (
+	"sync/atomic"
+
+	ns "github.com/yunfeiyang1916/toolkit/framework/internal/kit/namespace"
+	"github.com/yunfeiyang1916/toolkit/metrics"
+	"golang.org/x/net/context"
+)
+
+// Flags evaluates the feature flags.
+type Flags struct {
+	flags    atomic.Value // map[string]*Flag, nil until the source is read
+	defaults map[string]bool
+	attrs    Attributes
+}
+
+// Option sets the options of the Flags.
+type Option func(f *Flags)
+
+// WithDefault sets the value of a flag when it's unknown or the source is
+// down, the default value is false.
+func WithDefault(name string, on bool) Option {
+	return func(f *Flags) {
+		f.defaults[name] = on
+	}
+}
+
+// WithAttributes sets the attributes of all the evaluations, e.g. the app,
+// the attributes of an evaluation take precedence.
+func WithAttributes(attrs Attributes) Option {
+	return func(f *Flags) {
+		for k, v := range attrs {
+			f.attrs[k] = v
+		}
+	}
+}
+
+func newFlags(opts ...Option) *Flags {
+	f := &Flags{defaults: map[string]bool{}, attrs: Attributes{}}
+	for _, o := range opts {
+		o(f)
+	}
+	return f
+}
+
+// Update replaces all the flags.
+func (f *Flags) Update(flags map[string]*Flag) {
+	f.flags.Store(flags)
+}
+
+// Ready reports whether the flags have been read from the source.
+func (f *Flags) Ready() bool {
+	return f.flags.Load() != nil
+}
+
+// Enabled evaluates the flag for the attributes.
+func (f *Flags) Enabled(name string, attrs Attributes) bool {
+	on, reason := f.evaluate(name, attrs)
+	result := "off"
+	if on {
+		result = "on"
+	}
+	metrics.Meter("feature.evaluate", 1, "flag", name, "result", result, "reason", reason)
+	return on
+}
+
+// EnabledCtx evaluates the flag for the attributes, the env attribute is the
+// namespace of the ctx when it's not set.
+func (f *Flags) EnabledCtx(ctx context.Context, name string, attrs Attributes) bool {
+	if _, ok := attrs[AttrEnv]; !ok {
+		if env := ns.GetNamespace(ctx); len(env) > 0 {
+			a := make(Attributes, len(attrs)+1)
+			for k, v := range attrs {
+				a[k] = v
+			}
+			a[AttrEnv] = env
+			attrs = a
+		}
+	}
+	return f.Enabled(name, attrs)
+}
+
+func (f *Flags) evaluate(name string, attrs Attributes) (bool, string) {
+	flags, _ := f.flags.Load().(map[string]*Flag)
+	flag, ok := flags[name]
+	if !ok {
+		return f.defaults[name], ReasonDefault
+	}
+	if len(f.attrs) > 0 {
+		a := make(Attributes, len(f.attrs)+len(attrs))
+		for k, v := range f.attrs {
+			a[k] = v
+		}
+		for k, v := range attrs {
+			a[k] = v
+		}
+		attrs = a
+	}
+	return flag.evaluate(name, attrs)
+}
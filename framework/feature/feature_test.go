@@ -0,0 +1,109 @@
+package feature
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yunfeiyang1916/toolkit/framework/config"
+	"github.com/yunfeiyang1916/toolkit/framework/config/source"
+	"github.com/yunfeiyang1916/toolkit/framework/config/source/memory"
+)
+
+func rollout(v float64) *Percent {
+	p := Percent(v)
+	return &p
+}
+
+func TestEvaluate(t *testing.T) {
+	f := newFlags(WithDefault("unknown", true), WithAttributes(Attributes{AttrApp: "a.b.c"}))
+	assert.False(t, f.Ready())
+	assert.True(t, f.Enabled("unknown", nil))
+	assert.False(t, f.Enabled("other", nil))
+
+	f.Update(map[string]*Flag{
+		"on":       {Enabled: true},
+		"off":      {Enabled: false, Rules: []Rule{{Attribute: AttrApp, Values: []string{"a.b.c"}, On: true}}},
+		"app":      {Enabled: true, Rollout: rollout(0), Rules: []Rule{{Attribute: AttrApp, Values: []string{"a.b.c"}, On: true}}},
+		"env":      {Enabled: true, Rules: []Rule{{Attribute: AttrEnv, Values: []string{"loadtest"}, On: false}}},
+		"rollout":  {Enabled: true, Rollout: rollout(30)},
+		"by_group": {Enabled: true, Rollout: rollout(100), StickyBy: "group"},
+	})
+	assert.True(t, f.Ready())
+	assert.True(t, f.Enabled("on", nil))
+	assert.False(t, f.Enabled("off", nil))
+	assert.True(t, f.Enabled("app", nil))
+	assert.False(t, f.Enabled("app", Attributes{AttrApp: "x"}))
+	assert.False(t, f.Enabled("env", Attributes{AttrEnv: "loadtest"}))
+	assert.True(t, f.Enabled("env", Attributes{AttrEnv: "online"}))
+	assert.True(t, f.Enabled("unknown", nil))
+	assert.False(t, f.Enabled("by_group", Attributes{AttrUserID: "1"}))
+	assert.True(t, f.Enabled("by_group", Attributes{"group": "g"}))
+
+	// about 30% of the users, and a user always gets the same value
+	var n int
+	for i := 0; i < 10000; i++ {
+		uid := fmt.Sprint(i)
+		on := f.Enabled("rollout", Attributes{AttrUserID: uid})
+		assert.Equal(t, on, f.Enabled("rollout", Attributes{AttrUserID: uid}))
+		if on {
+			n++
+		}
+	}
+	assert.InDelta(t, 3000, n, 300)
+	assert.False(t, f.Enabled("rollout", nil))
+}
+
+func TestFromConfig(t *testing.T) {
+	mem := memory.NewSource(memory.WithDataToml([]byte(`
+[feature.a]
+enabled = true
+[feature.b]
+enabled = true
+rollout = 100
+`)))
+	c := config.New(config.WithSource(mem))
+	f := NewFromConfig(c, "feature")
+	assert.True(t, f.Ready())
+	assert.True(t, f.Enabled("a", nil))
+	assert.True(t, f.Enabled("b", Attributes{AttrUserID: "1"}))
+
+	// the bad flag b keeps its previous definition
+	time.Sleep(100 * time.Millisecond) // wait the loader watching the source
+	mem.(interface{ Update(*source.ChangeSet) }).Update(&source.ChangeSet{Data: []byte(`
+[feature.a]
+enabled = false
+[feature.b]
+enabled = true
+rollout = 101
+`), Format: "toml"})
+	for i := 0; i < 100 && f.Enabled("a", nil); i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.False(t, f.Enabled("a", nil))
+	assert.True(t, f.Enabled("b", Attributes{AttrUserID: "1"}))
+}
+
+func TestFromKV(t *testing.T) {
+	f := newFlags(WithDefault("a", true))
+	ch := make(chan map[string]string)
+	go f.watch(ch)
+	assert.True(t, f.Enabled("a", nil))
+
+	ch <- map[string]string{
+		"/feature/a": "false",
+		"/feature/b": `{"enabled": true, "rules": [{"attribute": "app", "values": ["x"], "on": false}]}`,
+		"/feature/c": "enabled = true\nrollout = 0\n",
+		"/feature/d": "{bad",
+	}
+	close(ch)
+	for i := 0; i < 100 && !f.Ready(); i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.False(t, f.Enabled("a", nil))
+	assert.True(t, f.Enabled("b", nil))
+	assert.False(t, f.Enabled("b", Attributes{AttrApp: "x"}))
+	assert.False(t, f.Enabled("c", Attributes{AttrUserID: "1"}))
+	assert.False(t, f.Enabled("d", nil))
+}
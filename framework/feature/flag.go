@@ -0,0 +1,119 @@
+package feature
+
+import (
+	"fmt"
+	"hash/fnv"
+)
+
+// The well-known attributes, a rule or the sticky hashing can use any other
+// attribute of the evaluation.
+const (
+	AttrUserID = "user_id"
+	AttrApp    = "app"
+	AttrEnv    = "env" // the namespace of the request by default
+)
+
+// Attributes are the subject of an evaluation, e.g. the user id and app.
+type Attributes map[string]string
+
+// Flag is a feature flag, it's on when enabled and no rollout or rule is
+// set, e.g. in a config section or a KV value:
+//
+//	enabled = true
+//	rollout = 20.5          # percent of the subjects hashed by sticky_by
+//	sticky_by = "user_id"
+//	[[rules]]               # the first matched rule decides
+//	attribute = "app"
+//	values = ["a.b.c"]
+//	on = true
+type Flag struct {
+	Enabled  bool     `toml:"enabled" json:"enabled"`
+	Rollout  *Percent `toml:"rollout" json:"rollout"`
+	StickyBy string   `toml:"sticky_by" json:"sticky_by"`
+	Rules    []Rule   `toml:"rules" json:"rules"`
+}
+
+// Percent is a percentage in [0, 100], an integer or a float in toml.
+type Percent float64
+
+// UnmarshalTOML decodes an integer or a float.
+func (p *Percent) UnmarshalTOML(v interface{}) error {
+	switch n := v.(type) {
+	case int64:
+		*p = Percent(n)
+	case float64:
+		*p = Percent(n)
+	default:
+		return fmt.Errorf("rollout %v is not a number", v)
+	}
+	return nil
+}
+
+// Rule targets the subjects with the attribute of one of the values.
+type Rule struct {
+	Attribute string   `toml:"attribute" json:"attribute"`
+	Values    []string `toml:"values" json:"values"`
+	On        bool     `toml:"on" json:"on"`
+}
+
+// The reasons of the evaluation results, they're the metric tags.
+const (
+	ReasonDefault  = "default"  // the flag is unknown or the source is down
+	ReasonDisabled = "disabled" // the flag is not enabled
+	ReasonRule     = "rule"     // a rule matches
+	ReasonRollout  = "rollout"  // the subject is in or out of the rollout
+	ReasonOn       = "on"       // the flag is on for all
+)
+
+func (f *Flag) validate() error {
+	if f.Rollout != nil && (*f.Rollout < 0 || *f.Rollout > 100) {
+		return fmt.Errorf("rollout %v out of [0, 100]", *f.Rollout)
+	}
+	for i, r := range f.Rules {
+		if len(r.Attribute) == 0 {
+			return fmt.Errorf("rule %d has no attribute", i)
+		}
+	}
+	return nil
+}
+
+// evaluate returns the flag value for the attributes and the reason.
+func (f *Flag) evaluate(name string, attrs Attributes) (bool, string) {
+	if !f.Enabled {
+		return false, ReasonDisabled
+	}
+	for _, r := range f.Rules {
+		v, ok := attrs[r.Attribute]
+		if !ok {
+			continue
+		}
+		for _, rv := range r.Values {
+			if rv == v {
+				return r.On, ReasonRule
+			}
+		}
+	}
+	if f.Rollout == nil {
+		return true, ReasonOn
+	}
+	by := f.StickyBy
+	if len(by) == 0 {
+		by = AttrUserID
+	}
+	key, ok := attrs[by]
+	if !ok {
+		// a subject can't stick without the attribute
+		return false, ReasonRollout
+	}
+	return bucket(name, key) < uint32(float64(*f.Rollout)*100), ReasonRollout
+}
+
+// bucket hashes the subject into one of the 10000 buckets, the flag name is
+// hashed too so the flags don't roll out to the same subjects.
+func bucket(name, key string) uint32 {
+	h := fnv.New32a()
+	h.Write([]byte(name))
+	h.Write([]byte{0})
+	h.Write([]byte(key))
+	return h.Sum32() % 10000
+}
@@ -0,0 +1,120 @@
+package feature
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/yunfeiyang1916/toolkit/framework/config"
+	"github.com/yunfeiyang1916/toolkit/framework/config/encoder/toml"
+	"github.com/yunfeiyang1916/toolkit/framework/config/loader"
+	"github.com/yunfeiyang1916/toolkit/go-upstream/registry"
+	"github.com/yunfeiyang1916/toolkit/logging"
+)
+
+// NewFromConfig reads the flags from the config section of the prefix, each
+// table of the section is a flag named by its key, e.g. [feature.new_checkout]
+// with the prefix feature. The flags are updated when the section changes.
+func NewFromConfig(c config.Config, prefix string, opts ...Option) *Flags {
+	f := newFlags(opts...)
+	load := func() {
+		var section map[string]interface{}
+		if prefix == "" {
+			section = c.Map()
+		} else {
+			section, _ = lookup(c.Map(), prefix).(map[string]interface{})
+		}
+		f.update(section, func(v interface{}) (*Flag, error) {
+			m, ok := v.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("not a table")
+			}
+			b, err := toml.NewEncoder().Encode(m)
+			if err != nil {
+				return nil, err
+			}
+			flag := &Flag{}
+			return flag, toml.NewEncoder().Decode(b, flag)
+		})
+	}
+	load()
+	c.Subscribe(prefix, func([]loader.Change) { load() })
+	return f
+}
+
+// NewFromKV watches the flags under the registry KV prefix, each key is a flag
+// named by the last element of the key path. The value is a flag in toml or
+// json, or true/false for a plain toggle. The default values are used until
+// the prefix is read, the last flags are kept when the registry is down.
+func NewFromKV(prefix string, opts ...Option) *Flags {
+	f := newFlags(opts...)
+	if registry.Default == nil {
+		logging.GenLogf("on feature, no registry to watch %s, use the default values", prefix)
+		return f
+	}
+	go f.watch(registry.Default.WatchPrefixManual(prefix))
+	return f
+}
+
+func (f *Flags) watch(ch <-chan map[string]string) {
+	for kvs := range ch {
+		values := make(map[string]interface{}, len(kvs))
+		for k, v := range kvs {
+			if len(strings.TrimSpace(v)) > 0 {
+				values[path.Base(k)] = v
+			}
+		}
+		f.update(values, func(v interface{}) (*Flag, error) {
+			return parseKV(v.(string))
+		})
+	}
+}
+
+func parseKV(v string) (*Flag, error) {
+	v = strings.TrimSpace(v)
+	if on, err := strconv.ParseBool(v); err == nil {
+		return &Flag{Enabled: on}, nil
+	}
+	flag := &Flag{}
+	if strings.HasPrefix(v, "{") {
+		return flag, json.Unmarshal([]byte(v), flag)
+	}
+	return flag, toml.NewEncoder().Decode([]byte(v), flag)
+}
+
+// update parses the flags of the values, a bad flag keeps its previous
+// definition.
+func (f *Flags) update(values map[string]interface{}, parse func(v interface{}) (*Flag, error)) {
+	old, _ := f.flags.Load().(map[string]*Flag)
+	flags := make(map[string]*Flag, len(values))
+	for name, v := range values {
+		flag, err := parse(v)
+		if err == nil {
+			err = flag.validate()
+		}
+		if err != nil {
+			logging.GenLogf("on feature, flag %s rejected, err %v", name, err)
+			if prev, ok := old[name]; ok {
+				flags[name] = prev
+			}
+			continue
+		}
+		flags[name] = flag
+	}
+	f.Update(flags)
+}
+
+// lookup returns the value of a dotted key path.
+func lookup(m map[string]interface{}, key string) interface{} {
+	var v interface{} = m
+	for _, k := range strings.Split(key, ".") {
+		mm, ok := v.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		v = mm[k]
+	}
+	return v
+}
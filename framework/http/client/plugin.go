@@ -95,10 +95,12 @@ func (c *client) breaker() core.Plugin {
 			return
 		}
 
-		if err := brk.Call(func() error {
-			flow.Next(ctx)
-			return flow.Err()
-		}, 0); err != nil {
+		if err := brk.Shed(func() error {
+			return brk.Call(func() error {
+				flow.Next(ctx)
+				return flow.Err()
+			}, 0)
+		}); err != nil {
 			flow.AbortErr(err)
 		}
 	})
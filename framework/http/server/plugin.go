@@ -71,15 +71,17 @@ func (s *server) logging() core.Plugin {
 				"trace_id", cc.traceId,
 				"peer_name", cc.Peer,
 				"req_method", cc.Request.Method,
-				"req_uri", cc.Request.URL.String(),
+				"req_uri", logging.GetRedactor().String(cc.Request.URL.String()),
 				"real_ip", getRemoteIP(cc.Request),
 				"http_code", code,
 				"busi_code", cc.BusiCode(),
 				"namespace", cc.Namespace,
 			}
 
+			// 敏感字段按 logging.SetRedactor 的规则脱敏
+			redactor := logging.GetRedactor()
 			_ = cc.ForeachBaggage(func(key, val string) error {
-				logItems = append(logItems, key[len(utils.DaeBaggageHeaderPrefix):], val)
+				logItems = append(logItems, key[len(utils.DaeBaggageHeaderPrefix):], redactor.Header(key, val))
 				return nil
 			})
 
@@ -90,13 +92,13 @@ func (s *server) logging() core.Plugin {
 			// request body 全局打印开关与单个uri接口开关
 			if !s.options.reqBodyLogOff && cc.printReqBody {
 				if _, ok := cc.loggingExtra[internalReqBodyLogTag]; !ok {
-					logItems = append(logItems, "req_body", fmt.Sprintf("%q", cc.bodyBuff.Bytes()))
+					logItems = append(logItems, "req_body", fmt.Sprintf("%q", redactor.Body(cc.bodyBuff.Bytes())))
 				}
 			}
 			// response body
 			if cc.printRespBody {
 				if _, ok := cc.loggingExtra[internalRespBodyLogTag]; !ok {
-					logItems = append(logItems, "resp_body", fmt.Sprintf("%q", cc.Response.ByteBody()))
+					logItems = append(logItems, "resp_body", fmt.Sprintf("%q", redactor.Body(cc.Response.ByteBody())))
 				}
 			}
 
@@ -188,6 +190,29 @@ func (s *server) breaker(path string) core.Plugin {
 	})
 }
 
+// shed rejects the requests beyond the adaptive concurrency limit of the
+// path's breaker, before they reach the handler.
+func (s *server) shed(path string) core.Plugin {
+	return core.Function(func(ctx context.Context, flow core.Core) {
+		if s.options.breaker == nil {
+			return
+		}
+
+		cc := ctx.Value(iCtxKey).(*Context)
+		brk := s.options.breaker.GetBreaker(breaker.ServerBreakerType, cc.Namespace, "", path)
+		if brk == nil || brk.Adaptive() == nil {
+			return
+		}
+
+		if err := brk.Shed(func() error {
+			flow.Next(ctx)
+			return flow.Err()
+		}); err != nil {
+			flow.AbortErr(err)
+		}
+	})
+}
+
 func (s *server) methodNotAllowed(ctx *Context) bool {
 	// 405
 	t := s.trees
@@ -213,6 +238,7 @@ func PrintRespBody(b bool) HandlerFunc {
 	}
 }
 
+// PrintBodyLog 打开或关闭请求与响应 body 的日志，body 按 logging.SetRedactor 的规则脱敏
 func PrintBodyLog(printReq, printResp bool) HandlerFunc {
 	return func(c *Context) {
 		c.printReqBody = printReq
@@ -337,8 +337,8 @@ func (s *server) makeChain(path string, handlers []core.Plugin) []core.Plugin {
 	gPlugins := serverInternalThirdPlugin.OnGlobalStage().Stream()
 	ps = append(ps, gPlugins...)
 
-	// plugins on each path: namespaceKey -> rateLimit-> breaker -> metric -> (outside frame plugin) -> handlers
-	ps = append(ps, s.metric(), s.namespaceKey(), s.rateLimit(), s.breaker(path))
+	// plugins on each path: namespaceKey -> rateLimit-> shed -> breaker -> metric -> (outside frame plugin) -> handlers
+	ps = append(ps, s.metric(), s.namespaceKey(), s.rateLimit(), s.shed(path), s.breaker(path))
 
 	// third plugins effect on server, global scope
 	rPlugins := serverInternalThirdPlugin.OnRequestStage().Stream()
@@ -19,6 +19,7 @@ var (
 const (
 	_app             = "app"
 	_pprofURI        = "/debug/pprof/port"
+	_logLevelURI     = "/debug/log/level"
 	LOG_ROTATE_HOUR  = "hour"
 	LOG_ROTATE_DAY   = "day"
 	LOG_ROTATE_MONTH = "month"
@@ -87,6 +87,7 @@ func getClientBreakerConfig(namespace string, sc ServerClient) []breaker.Breaker
 			ErrorPercentThreshold:     v.ErrorPercentThreshold,
 			ConsecutiveErrorThreshold: v.ConsecutiveErrorThreshold,
 			MinSamples:                v.MinSamples,
+			AdaptiveConfig:            v.AdaptiveConfig,
 		})
 	}
 	return vals
@@ -104,6 +105,7 @@ func getServerBreakerConfig(namespace string, c frameworkConfig) []breaker.Break
 			ErrorPercentThreshold:     v.ErrorPercentThreshold,
 			ConsecutiveErrorThreshold: v.ConsecutiveErrorThreshold,
 			MinSamples:                v.MinSamples,
+			AdaptiveConfig:            v.AdaptiveConfig,
 		})
 	}
 	return vals
@@ -170,6 +172,7 @@ func getDefaultBreakerConfig(namespace string, dc DefaultCircuit) []breaker.Brea
 		ConsecutiveErrorThreshold: dc.Server.ConsecutiveErrorThreshold,
 		MinSamples:                dc.Server.MinSamples,
 		Break:                     dc.Server.Break,
+		AdaptiveConfig:            dc.Server.AdaptiveConfig,
 	})
 	vals = append(vals, breaker.BreakerConfig{
 		Name:                      breaker.GetDefaultClientBreakerName(namespace),
@@ -177,6 +180,7 @@ func getDefaultBreakerConfig(namespace string, dc DefaultCircuit) []breaker.Brea
 		ConsecutiveErrorThreshold: dc.Client.ConsecutiveErrorThreshold,
 		MinSamples:                dc.Client.MinSamples,
 		Break:                     dc.Client.Break,
+		AdaptiveConfig:            dc.Client.AdaptiveConfig,
 	})
 	return vals
 }
@@ -225,7 +229,8 @@ func (d *Framework) loadLocalConfig() []byte {
 	c := config.New()
 	_ = c.LoadFile(d.ConfigPath)
 	_ = c.Scan(&d.config)
-	return c.Bytes()
+	// only the service name is read from it, keep the secrets out
+	return []byte(c.String())
 }
 
 func makeUploadPath(dir string, filename string) string {
@@ -350,6 +355,18 @@ func (d *Framework) initLogger() {
 		d.config.Log.LogPath = "logs"
 	}
 	d.LogDir = d.config.Log.LogPath
+	policy := (&logging.Options{
+		MaxSize:    d.config.Log.MaxSize,
+		MaxBackups: d.config.Log.MaxBackups,
+		MaxAge:     d.config.Log.MaxAge,
+		Compress:   d.config.Log.Compress,
+		Symlink:    d.config.Log.Symlink,
+	}).RotatePolicy()
+
+	logging.SetService(d.localAppServiceName, d.Namespace)
+	if err := setRedactor(d.config.Log.Redact); err != nil {
+		logging.Errorf("log redact: %v", err)
+	}
 
 	// Init common logger
 	logging.InitCommonLog(logging.CommonLogConfig{
@@ -357,6 +374,8 @@ func (d *Framework) initLogger() {
 		Rotate:          d.config.Log.Rotate,
 		GenLogLevel:     d.config.Log.GenLogLevel,
 		BalanceLogLevel: d.config.Log.BalanceLogLevel,
+		Policy:          policy,
+		Encoding:        d.config.Log.Encoding,
 	})
 
 	// upstream logger
@@ -367,6 +386,17 @@ func (d *Framework) initLogger() {
 	} else {
 		logging.SetRotateByHour()
 	}
+	logging.SetRotatePolicy(policy)
+	if len(d.config.Log.AsyncPolicy) > 0 {
+		logging.SetAsync(logging.AsyncOptions{
+			Policy:     logging.AsyncPolicy(d.config.Log.AsyncPolicy),
+			QueueSize:  d.config.Log.AsyncQueueSize,
+			SampleRate: d.config.Log.AsyncSampleRate,
+		})
+	}
+	if len(d.config.Log.Encoding) > 0 {
+		logging.SetEncoding(d.config.Log.Encoding)
+	}
 	if len(d.config.Log.Level) > 0 {
 		logging.SetLevelByString(d.config.Log.Level)
 	} else {
@@ -395,29 +425,90 @@ func (d *Framework) initLogger() {
 	}
 	// internal logger
 	rotateType := d.config.Log.Rotate
+	kitEncoding := d.config.Log.KitEncoding
+	if kitEncoding == "" {
+		kitEncoding = logging.JSONEncoding
+	}
 	var blog, alog *logging.Logger
 	if !d.config.Log.AccessLogOff {
-		alog = log.New(filepath.Join(d.LogDir, "access.log"))
+		alog = log.NewEncoded(filepath.Join(d.LogDir, "access.log"), kitEncoding)
 		if rotateType == "day" {
 			alog.SetRotateByDay()
 		}
+		alog.SetRotatePolicy(policy)
 	}
 	if !d.config.Log.BusinessLogOff {
-		blog = log.New(filepath.Join(d.LogDir, "business.log"))
+		blog = log.NewEncoded(filepath.Join(d.LogDir, "business.log"), kitEncoding)
 		if rotateType == "day" {
 			blog.SetRotateByDay()
 		}
+		blog.SetRotatePolicy(policy)
 	}
 	// FIXME: should remove
-	elog := log.New(filepath.Join(d.LogDir, "error.log"))
+	elog := log.NewEncoded(filepath.Join(d.LogDir, "error.log"), kitEncoding)
 	elog.SetLevelByString("error")
 	if rotateType == "day" {
 		elog.SetRotateByDay()
 	}
+	elog.SetRotatePolicy(policy)
 
 	if DefaultKit == nil {
 		DefaultKit = log.NewKit(blog, alog, elog)
 	}
+
+	// the kit loggers are named for the sampling and the levels
+	for name, l := range map[string]*logging.Logger{"access": alog, "business": blog, "error": elog} {
+		if l != nil {
+			logging.Register(name, l)
+		}
+	}
+	for _, sc := range d.config.Log.Sampling {
+		name := sc.Logger
+		if name == "" {
+			name = logging.DefaultLoggerName
+		}
+		l := logging.Log(name)
+		if l == nil {
+			logging.GenLogf("log sampling: unknown logger %q", name)
+			continue
+		}
+		l.SetSampling(logging.SamplingOptions{
+			First:           sc.First,
+			Thereafter:      sc.Thereafter,
+			RateLimit:       sc.RateLimit,
+			Interval:        sc.Interval.Duration,
+			SummaryInterval: sc.SummaryInterval.Duration,
+		})
+	}
+	for name, level := range d.config.Log.Levels {
+		if err := logging.SetLoggerLevel(name, level, 0, "config"); err != nil {
+			logging.GenLogf("log levels: %v", err)
+		}
+	}
+}
+
+// setRedactor sets the redaction rules of the logs, none stops the redaction.
+func setRedactor(configs []LogRedactConfig) error {
+	if len(configs) == 0 {
+		logging.SetRedactor(nil)
+		return nil
+	}
+	rules := make([]logging.RedactRule, 0, len(configs))
+	for _, c := range configs {
+		rules = append(rules, logging.RedactRule{
+			Fields:   c.Fields,
+			Headers:  c.Headers,
+			Patterns: c.Patterns,
+			Mode:     logging.RedactMode(c.Mode),
+			Salt:     c.Salt,
+		})
+	}
+	r, err := logging.NewRedactor(rules...)
+	if err != nil {
+		return err
+	}
+	logging.SetRedactor(r)
+	return nil
 }
 
 // 如果设置了app_name则用app_name+service_name,如果没有则保持原有逻辑用service_name
@@ -472,6 +563,20 @@ func (d *Framework) makeCluster(sName string, sc ServerClient) clusterconfig.Clu
 	cluster.Proto = sc.ProtoType
 	cluster.LBType = sc.Balancetype
 	cluster.EndpointsFrom = sc.EndpointsFrom
+	if sc.EndpointsFrom == "etcd" {
+		etcdConfig := d.config.Registry.Etcd
+		cluster.Etcd = clusterconfig.Etcd{
+			Endpoints:   etcdConfig.Endpoints,
+			DialTimeout: etcdConfig.DialTimeout.Duration,
+			Username:    etcdConfig.Username,
+			Password:    etcdConfig.Password,
+			Prefix:      etcdConfig.Prefix,
+			Logger:      logging.Log(logging.GenLoggerName),
+		}
+		if len(sc.EtcdEndpoints) > 0 {
+			cluster.Etcd.Endpoints = sc.EtcdEndpoints
+		}
+	}
 	cluster.CheckInterval = sc.CheckInterval
 	cluster.UnHealthyThreshold = sc.UnHealthyThreshold
 	cluster.HealthyThreshold = sc.HealthyThreshold
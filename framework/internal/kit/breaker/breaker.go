@@ -30,10 +30,12 @@ func (i ikBreaker) Do(ctx context.Context, flow core.Core) {
 		return
 	}
 
-	if err := brk.Call(func() error {
-		flow.Next(ctx)
-		return flow.Err()
-	}, 0); err != nil {
+	if err := brk.Shed(func() error {
+		return brk.Call(func() error {
+			flow.Next(ctx)
+			return flow.Err()
+		}, 0)
+	}); err != nil {
 		flow.AbortErr(err)
 	}
 }
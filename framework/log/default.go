@@ -8,7 +8,13 @@ import (
 )
 
 func New(path string) *logging.Logger {
-	l, _ := logging.NewJSON(path, rolling.HourlyRolling)
+	return NewEncoded(path, logging.JSONEncoding)
+}
+
+// NewEncoded returns a logger like New with the lines in an encoding of
+// logging.NewEncoder.
+func NewEncoded(path, encoding string) *logging.Logger {
+	l, _ := logging.NewEncoded(path, rolling.HourlyRolling, encoding)
 	l.SetFlags(0)
 	l.SetPrintLevel(false)
 	l.SetHighlighting(false)
@@ -9,6 +9,8 @@ import (
 	"time"
 
 	goctx "golang.org/x/net/context"
+
+	"github.com/yunfeiyang1916/toolkit/logging"
 )
 
 // A Valuer generates a log value. When passed to With or WithPrefix in a
@@ -98,18 +100,10 @@ func Caller(depth int) Valuer {
 	}
 }
 
-// TraceID todo 使用jaeger，暂时注释
+// TraceID returns a Valuer of the trace id of ctx.
 func TraceID(ctx goctx.Context) Valuer {
-	// span := opentracing.SpanFromContext(ctx)
 	return func() interface{} {
-		var traceID string
-		//if span, ok := span.(*jaeger.Span); ok {
-		//	spanCtx := span.Context()
-		//	if sc, ok := spanCtx.(jaeger.SpanContext); ok {
-		//		traceID = sc.TraceID().String()
-		//	}
-		//}
-		return traceID
+		return logging.TraceID(ctx)
 	}
 }
 
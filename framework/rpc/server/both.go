@@ -42,6 +42,7 @@ func BothServer(serviceName string, port int, options ...Option) Server {
 	)
 	b.http = HTTPServer(ops2...)
 	b.Use(RatelimitPlugin)
+	b.Use(ShedPlugin)
 	b.Use(BreakerPlugin)
 	return b
 }
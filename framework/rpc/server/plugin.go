@@ -30,6 +30,28 @@ func BreakerPlugin(c *Context) {
 	}
 }
 
+// ShedPlugin rejects the calls beyond the adaptive concurrency limit of the
+// method's breaker, before they reach the handler.
+func ShedPlugin(c *Context) {
+	if c.opts.Breaker == nil {
+		return
+	}
+
+	endpoint := fmt.Sprintf("%s.%s", c.Service, c.Method)
+	brk := c.opts.Breaker.GetBreaker(breaker.ServerBreakerType, c.Namespace, "", endpoint)
+	if brk == nil || brk.Adaptive() == nil {
+		return
+	}
+
+	err := brk.Shed(func() error {
+		c.Next()
+		return c.Err()
+	})
+	if err != nil {
+		c.AbortErr(err)
+	}
+}
+
 func RatelimitPlugin(c *Context) {
 	if c.opts.Limiter == nil {
 		return
@@ -0,0 +1,47 @@
+package server
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yunfeiyang1916/toolkit/framework/breaker"
+	"github.com/yunfeiyang1916/toolkit/framework/internal/core"
+)
+
+func TestShedPlugin(t *testing.T) {
+	config := breaker.NewConfig([]breaker.BreakerConfig{
+		{
+			Name: "shed@server@*",
+			AdaptiveConfig: breaker.AdaptiveConfig{
+				AdaptiveConcurrency:  true,
+				AdaptiveInitialLimit: 1,
+				AdaptiveMaxLimit:     1,
+			},
+		},
+	})
+	call := func(handler func()) (*Context, bool) {
+		c := &Context{opts: Options{Breaker: config}, Ctx: context.Background(), Service: "Echo", Method: "Say", Namespace: "shed"}
+		called := false
+		c.core = core.New([]core.Plugin{
+			core.Function(func(ctx context.Context, _ core.Core) { ShedPlugin(c) }),
+			core.Function(func(ctx context.Context, _ core.Core) { called = true; handler() }),
+		})
+		c.Next()
+		return c, called
+	}
+
+	var inner *Context
+	var innerCalled bool
+	outer, called := call(func() {
+		// the call in flight holds the only slot
+		inner, innerCalled = call(func() {})
+	})
+	assert.True(t, called)
+	assert.Nil(t, outer.Err())
+	assert.False(t, innerCalled)
+	assert.Equal(t, breaker.ErrOverload, inner.Err())
+
+	_, called = call(func() {})
+	assert.True(t, called)
+}
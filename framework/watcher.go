@@ -2,15 +2,28 @@ package framework
 
 import (
 	"strings"
+	"sync"
 
 	"github.com/yunfeiyang1916/toolkit/framework/breaker"
 	"github.com/yunfeiyang1916/toolkit/framework/config"
 	"github.com/yunfeiyang1916/toolkit/framework/config/encoder/toml"
+	"github.com/yunfeiyang1916/toolkit/framework/config/loader"
 	ns "github.com/yunfeiyang1916/toolkit/framework/internal/kit/namespace"
 	"github.com/yunfeiyang1916/toolkit/framework/ratelimit"
 	"github.com/yunfeiyang1916/toolkit/logging"
 )
 
+var (
+	// the sections of the breaker and limiter configs
+	breakerSections = []string{"server.breaker", "server.default_circuit", "server_client"}
+	limiterSections = []string{"server.limiter", "server.default_circuit", "server_client"}
+	logSections     = []string{"log"}
+
+	// the last decoded remote config.toml of the namespaces
+	lastConfigMu sync.Mutex
+	lastConfig   = map[string]map[string]interface{}{}
+)
+
 func initConfigWatcher(remotePath string) {
 	logging.GenLogf("on config init watcher path:%s", remotePath)
 	go func() {
@@ -61,10 +74,72 @@ func decodeConfig(data map[string]string) {
 			return
 		}
 
-		logging.GenLogf("on config watcher, reload namespace:%s", namespace)
-		brkConfigs := getBreakerConfig(namespace, d)
-		breaker.ReloadConfig(brkConfigs)
-		limConfigs := getLimiterConfig(namespace, d)
-		ratelimit.ReloadConfig(limConfigs)
+		var m map[string]interface{}
+		if err := toml.NewEncoder().Decode([]byte(v), &m); err != nil {
+			logging.GenLogf("on config watcher, decode error: %v", err)
+			return
+		}
+		lastConfigMu.Lock()
+		old, reloaded := lastConfig[namespace]
+		lastConfig[namespace] = m
+		lastConfigMu.Unlock()
+
+		// the first remote config is always reloaded
+		changes := loader.Diff(old, m)
+		if !reloaded || sectionChanged(changes, breakerSections) {
+			logging.GenLogf("on config watcher, reload breaker namespace:%s", namespace)
+			breaker.ReloadConfig(getBreakerConfig(namespace, d))
+		}
+		if !reloaded || sectionChanged(changes, limiterSections) {
+			logging.GenLogf("on config watcher, reload limiter namespace:%s", namespace)
+			ratelimit.ReloadConfig(getLimiterConfig(namespace, d))
+		}
+		if namespace == Default.Namespace && (!reloaded || sectionChanged(changes, logSections)) {
+			logging.GenLogf("on config watcher, reload log levels and redaction namespace:%s", namespace)
+			reloadLogLevels(d, changes)
+			if err := setRedactor(d.Log.Redact); err != nil {
+				// the last rules are kept
+				logging.GenLogf("on config watcher, log redact: %v", err)
+			}
+		}
+	}
+}
+
+// reloadLogLevels sets the levels of the [log] section whose keys are changed,
+// the levels set at runtime are kept for the unchanged keys.
+func reloadLogLevels(d frameworkConfig, changes []loader.Change) {
+	changed := make(map[string]bool, len(changes))
+	for _, c := range loader.Filter(changes, "log") {
+		changed[c.Path] = true
+	}
+	levels := map[string]string{}
+	set := func(path, name, level string) {
+		if changed[path] {
+			levels[name] = level
+		}
+	}
+	set("log.level", logging.DefaultLoggerName, d.Log.Level)
+	set("log.gen_log_level", logging.GenLoggerName, d.Log.GenLogLevel)
+	set("log.balance_log_level", logging.BalanceLoggerName, d.Log.BalanceLogLevel)
+	for name, level := range d.Log.Levels {
+		set("log.levels."+name, name, level)
+	}
+	for name, level := range levels {
+		l := logging.Log(name)
+		if level == "" || l == nil || l.Level() == level {
+			continue
+		}
+		if err := logging.SetLoggerLevel(name, level, 0, "config"); err != nil {
+			logging.GenLogf("on config watcher, %v", err)
+		}
+	}
+}
+
+func sectionChanged(changes []loader.Change, sections []string) bool {
+	for _, s := range sections {
+		if len(loader.Filter(changes, s)) > 0 {
+			return true
+		}
 	}
+	return false
 }
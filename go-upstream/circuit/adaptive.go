@@ -0,0 +1,229 @@
+// This file implement the adaptive concurrency limiter.
+package circuit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	metrics "github.com/yunfeiyang1916/toolkit/metrics"
+)
+
+const (
+	AdaptiveGradient = "gradient"
+	AdaptiveVegas    = "vegas"
+
+	defaultAdaptiveInitialLimit = 20
+	defaultAdaptiveMinLimit     = 1
+	defaultAdaptiveMaxLimit     = 1000
+
+	// gradient2 parameters, see netflix concurrency-limits
+	gradientLongWindow = 600
+	gradientTolerance  = 1.5
+	gradientSmoothing  = 0.2
+	gradientMinimum    = 0.5
+
+	// vegas resets the no load rtt after this many samples
+	vegasProbeSamples = 1000
+
+	// dropBackoff shrinks the limit whenever a request timed out
+	dropBackoff = 0.9
+)
+
+// AdaptiveOptions holds the adaptive concurrency limiter configure.
+type AdaptiveOptions struct {
+	// Algorithm is AdaptiveGradient or AdaptiveVegas, gradient by default.
+	Algorithm    string
+	InitialLimit int64
+	MinLimit     int64
+	MaxLimit     int64
+}
+
+func (o AdaptiveOptions) withDefaults() AdaptiveOptions {
+	if o.Algorithm != AdaptiveVegas {
+		o.Algorithm = AdaptiveGradient
+	}
+	if o.MinLimit <= 0 {
+		o.MinLimit = defaultAdaptiveMinLimit
+	}
+	if o.MaxLimit <= 0 {
+		o.MaxLimit = defaultAdaptiveMaxLimit
+	}
+	if o.MaxLimit < o.MinLimit {
+		o.MaxLimit = o.MinLimit
+	}
+	if o.InitialLimit <= 0 {
+		o.InitialLimit = defaultAdaptiveInitialLimit
+	}
+	if o.InitialLimit < o.MinLimit {
+		o.InitialLimit = o.MinLimit
+	}
+	if o.InitialLimit > o.MaxLimit {
+		o.InitialLimit = o.MaxLimit
+	}
+	return o
+}
+
+// AdaptiveLimiter limits the in-flight requests of a resource. It learns the
+// no-load latency of the resource and lowers the limit as soon as latency
+// grows because requests start to queue, raising it again when latency falls
+// back. It can shed load in front of a server handler as well as guard the
+// calls to one upstream on the client side.
+type AdaptiveLimiter struct {
+	name     string
+	opts     AdaptiveOptions
+	inflight int64
+	current  int64 // the rounded limit, read without lock
+
+	mu      sync.Mutex
+	limit   float64
+	longRTT float64 // gradient: ewma of rtt in nanoseconds
+	noLoad  float64 // vegas: min rtt in nanoseconds
+	samples int64
+}
+
+// NewAdaptiveLimiter creates an adaptive limiter, name is the resource name
+// used to report the limit metric.
+func NewAdaptiveLimiter(name string, opts AdaptiveOptions) *AdaptiveLimiter {
+	opts = opts.withDefaults()
+	l := &AdaptiveLimiter{
+		name:    name,
+		opts:    opts,
+		limit:   float64(opts.InitialLimit),
+		current: opts.InitialLimit,
+	}
+	l.report()
+	return l
+}
+
+// Limit returns the current allowed in-flight request number.
+func (l *AdaptiveLimiter) Limit() int64 {
+	return atomic.LoadInt64(&l.current)
+}
+
+// Inflight returns the in-flight request number acquired by Acquire.
+func (l *AdaptiveLimiter) Inflight() int64 {
+	return atomic.LoadInt64(&l.inflight)
+}
+
+// Acquire reserves an in-flight slot, ok is false when the limit is reached.
+// Otherwise release must be called when the request completes, dropped tells
+// whether the request timed out or was rejected by an overloaded peer.
+func (l *AdaptiveLimiter) Acquire() (release func(dropped bool), ok bool) {
+	inflight := atomic.AddInt64(&l.inflight, 1)
+	if inflight > l.Limit() {
+		atomic.AddInt64(&l.inflight, -1)
+		return nil, false
+	}
+	start := time.Now()
+	var once int32
+	return func(dropped bool) {
+		if !atomic.CompareAndSwapInt32(&once, 0, 1) {
+			return
+		}
+		atomic.AddInt64(&l.inflight, -1)
+		l.Update(time.Since(start), inflight, dropped)
+	}, true
+}
+
+// Call runs fn when a slot is available, otherwise returns ErrAdaptiveConcurrency.
+// A fn which returns a timeout error counts as dropped.
+func (l *AdaptiveLimiter) Call(fn func() error) error {
+	release, ok := l.Acquire()
+	if !ok {
+		return ErrAdaptiveConcurrency
+	}
+	err := fn()
+	release(isDropped(err))
+	return err
+}
+
+// Update adjusts the limit with the rtt of one request, inflight is the
+// in-flight request number when the request started.
+func (l *AdaptiveLimiter) Update(rtt time.Duration, inflight int64, dropped bool) {
+	if rtt <= 0 {
+		rtt = time.Nanosecond
+	}
+	l.mu.Lock()
+	var limit float64
+	if l.opts.Algorithm == AdaptiveVegas {
+		limit = l.vegas(float64(rtt), float64(inflight), dropped)
+	} else {
+		limit = l.gradient(float64(rtt), float64(inflight), dropped)
+	}
+	limit = math.Max(float64(l.opts.MinLimit), math.Min(float64(l.opts.MaxLimit), limit))
+	l.limit = limit
+	l.mu.Unlock()
+
+	if atomic.SwapInt64(&l.current, int64(limit)) != int64(limit) {
+		l.report()
+	}
+}
+
+// gradient implements the gradient2 algorithm: the limit follows the ratio of
+// the long term rtt to the current one, plus a queue to allow for bursts.
+func (l *AdaptiveLimiter) gradient(rtt, inflight float64, dropped bool) float64 {
+	l.samples++
+	if l.longRTT == 0 {
+		l.longRTT = rtt
+	} else {
+		n := math.Min(float64(l.samples), gradientLongWindow)
+		l.longRTT += (rtt - l.longRTT) / n
+	}
+	// the long term rtt drifted above the current one, recover faster
+	if l.longRTT/rtt > 2 {
+		l.longRTT *= 0.95
+	}
+
+	if dropped {
+		return l.limit * dropBackoff
+	}
+	// the resource is not the bottleneck, keep the limit
+	if inflight < l.limit/2 {
+		return l.limit
+	}
+
+	gradient := math.Max(gradientMinimum, math.Min(1, gradientTolerance*l.longRTT/rtt))
+	limit := l.limit*gradient + math.Sqrt(l.limit)
+	return l.limit*(1-gradientSmoothing) + limit*gradientSmoothing
+}
+
+// vegas implements the tcp vegas algorithm: the queue size is estimated from
+// the minimum rtt, the limit grows while the queue is short and shrinks when
+// it gets long.
+func (l *AdaptiveLimiter) vegas(rtt, inflight float64, dropped bool) float64 {
+	l.samples++
+	if l.noLoad == 0 || rtt < l.noLoad || l.samples%vegasProbeSamples == 0 {
+		l.noLoad = rtt
+	}
+
+	step := math.Max(1, math.Log10(l.limit))
+	if dropped {
+		return l.limit - step
+	}
+	if inflight*2 < l.limit {
+		return l.limit
+	}
+
+	queue := math.Ceil(l.limit * (1 - l.noLoad/rtt))
+	alpha, beta := 3*step, 6*step
+	switch {
+	case queue <= step:
+		return l.limit + beta
+	case queue < alpha:
+		return l.limit + step
+	case queue > beta:
+		return l.limit - step
+	}
+	return l.limit
+}
+
+func (l *AdaptiveLimiter) report() {
+	metrics.Gauge("circuit.adaptive_limit", int(l.Limit()), "resource", l.name)
+}
+
+func isDropped(err error) bool {
+	return err == context.DeadlineExceeded || err == ErrBreakerTimeout
+}
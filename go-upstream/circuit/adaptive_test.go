@@ -0,0 +1,68 @@
+package circuit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAdaptiveLimiterGradient(t *testing.T) {
+	l := NewAdaptiveLimiter("gradient", AdaptiveOptions{InitialLimit: 100, MinLimit: 5, MaxLimit: 200})
+	assert.Equal(t, int64(100), l.Limit())
+
+	// stable latency under load grows the limit
+	for i := 0; i < 200; i++ {
+		l.Update(10*time.Millisecond, l.Limit(), false)
+	}
+	grown := l.Limit()
+	assert.True(t, grown > 100, "limit %d", grown)
+
+	// latency grows because requests queue, the limit shrinks
+	for i := 0; i < 50; i++ {
+		l.Update(40*time.Millisecond, l.Limit(), false)
+	}
+	assert.True(t, l.Limit() < grown, "limit %d", l.Limit())
+
+	// an app limited resource keeps the limit
+	limit := l.Limit()
+	l.Update(time.Second, 1, false)
+	assert.Equal(t, limit, l.Limit())
+
+	for i := 0; i < 100; i++ {
+		l.Update(time.Second, l.Limit(), true)
+	}
+	assert.Equal(t, int64(5), l.Limit())
+}
+
+func TestAdaptiveLimiterVegas(t *testing.T) {
+	l := NewAdaptiveLimiter("vegas", AdaptiveOptions{Algorithm: AdaptiveVegas, InitialLimit: 10, MaxLimit: 100})
+	for i := 0; i < 50; i++ {
+		l.Update(10*time.Millisecond, l.Limit(), false)
+	}
+	assert.Equal(t, int64(100), l.Limit())
+
+	for i := 0; i < 50; i++ {
+		l.Update(50*time.Millisecond, l.Limit(), false)
+	}
+	assert.True(t, l.Limit() < 100, "limit %d", l.Limit())
+}
+
+func TestAdaptiveLimiterAcquire(t *testing.T) {
+	l := NewAdaptiveLimiter("acquire", AdaptiveOptions{InitialLimit: 2, MinLimit: 2, MaxLimit: 2})
+	r1, ok := l.Acquire()
+	assert.True(t, ok)
+	r2, ok := l.Acquire()
+	assert.True(t, ok)
+	_, ok = l.Acquire()
+	assert.False(t, ok)
+	assert.Equal(t, ErrAdaptiveConcurrency, l.Call(func() error { return nil }))
+
+	r1(false)
+	r1(false)
+	assert.Equal(t, int64(1), l.Inflight())
+	r2(true)
+	assert.Equal(t, context.DeadlineExceeded, l.Call(func() error { return context.DeadlineExceeded }))
+	assert.Equal(t, int64(0), l.Inflight())
+}
@@ -88,6 +88,8 @@ var (
 	ErrPercent       = NewBreakerError("breaker: exceeds error percent")
 	ErrOpen          = NewBreakerError("breaker: initiative open")
 
+	ErrAdaptiveConcurrency = NewBreakerError("breaker: exceeds adaptive concurrency limit")
+
 	errDefault = NewBreakerError("breaker: error is nil")
 )
 
@@ -15,6 +15,8 @@ type Registry struct {
 	Static  Static
 	File    File
 	Consul  Consul
+	Etcd    Etcd
+	DNS     DNS
 	Timeout time.Duration
 	Retry   time.Duration
 }
@@ -25,6 +27,8 @@ type Static struct {
 
 type File struct {
 	Path string
+
+	Logger *logging.Logger
 }
 
 type Consul struct {
@@ -35,6 +39,38 @@ type Consul struct {
 	Logger *logging.Logger
 }
 
+type Etcd struct {
+	Endpoints   []string
+	DialTimeout time.Duration
+	Username    string
+	Password    string
+
+	// Prefix is the key prefix under which service instances are registered,
+	// instances live at <Prefix>/<ServiceName>/<ServiceID>
+	Prefix string
+	// TTL is the lease ttl in seconds of a registered service instance
+	TTL int64
+
+	Logger *logging.Logger
+}
+
+type DNS struct {
+	// Target is the name to resolve, a SRV name such as _http._tcp.example.com
+	// or a host name with port such as example.com:8080, the watched service
+	// name is resolved when Target is empty
+	Target string
+	// Servers are the name servers (host:port) to query, those of
+	// /etc/resolv.conf are used when empty
+	Servers []string
+	Timeout time.Duration
+	// MinInterval and MaxInterval bound the refresh interval derived
+	// from the record ttl
+	MinInterval time.Duration
+	MaxInterval time.Duration
+
+	Logger *logging.Logger
+}
+
 type Register struct {
 	ServiceName string
 	ServiceAddr string
@@ -73,9 +109,10 @@ type Cluster struct {
 	Proto           string `toml:"proto"`
 
 	// checker config
-	CheckInterval      Duration `toml:"check_interval"`
-	UnHealthyThreshold uint32   `toml:"check_unhealth_threshold"`
-	HealthyThreshold   uint32   `toml:"check_healthy_threshold"`
+	CheckInterval      Duration    `toml:"check_interval"`
+	UnHealthyThreshold uint32      `toml:"check_unhealth_threshold"`
+	HealthyThreshold   uint32      `toml:"check_healthy_threshold"`
+	HealthCheck        HealthCheck `toml:"health_check"`
 
 	// lb advance config
 	LBPanicThreshold int        `toml:"lb_panic_threshold"`
@@ -86,6 +123,28 @@ type Cluster struct {
 	Detector Detector `toml:"detector"`
 
 	Datacenter string `toml:"datacenter"`
+
+	// Etcd is the etcd cluster of the endpoints when EndpointsFrom is etcd
+	Etcd Etcd `toml:"-"`
+}
+
+// HealthCheck describes the active health check probe of a cluster.
+type HealthCheck struct {
+	// Type is tcp (default), http, grpc, ikio or the name of a registered probe
+	Type    string   `toml:"type"`
+	Timeout Duration `toml:"timeout"`
+	// Jitter is the max random delay added to every check interval
+	Jitter Duration `toml:"jitter"`
+
+	// http probe
+	Path             string            `toml:"path"`
+	Method           string            `toml:"method"`
+	ExpectedStatuses []string          `toml:"expected_statuses"` // "200" or ranges like "200-299"
+	BodyContains     string            `toml:"body_contains"`
+	Headers          map[string]string `toml:"headers"`
+
+	// grpc probe, the overall server health is checked when empty
+	GRPCService string `toml:"grpc_service"`
 }
 
 type Detector struct {
@@ -0,0 +1,126 @@
+// Package dns implements a registry backend which resolves service
+// endpoints from SRV or A/AAAA records and refreshes them as the
+// record ttl expires.
+package dns
+
+import (
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/yunfeiyang1916/toolkit/go-upstream/config"
+	"github.com/yunfeiyang1916/toolkit/go-upstream/registry"
+	"github.com/yunfeiyang1916/toolkit/logging"
+)
+
+const (
+	defaultTimeout     = 2 * time.Second
+	defaultMinInterval = 5 * time.Second
+	defaultMaxInterval = 5 * time.Minute
+	resolvConf         = "/etc/resolv.conf"
+)
+
+type be struct {
+	cfg      *config.DNS
+	resolver *resolver
+	logger   *logging.Logger
+}
+
+func NewBackend(cfg *config.DNS) (registry.Backend, error) {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultTimeout
+	}
+	if cfg.MinInterval <= 0 {
+		cfg.MinInterval = defaultMinInterval
+	}
+	if cfg.MaxInterval < cfg.MinInterval {
+		cfg.MaxInterval = defaultMaxInterval
+		if cfg.MaxInterval < cfg.MinInterval {
+			cfg.MaxInterval = cfg.MinInterval
+		}
+	}
+	logger := cfg.Logger
+	if logger == nil {
+		logger = logging.New()
+	}
+	r, err := newResolver(cfg.Servers, cfg.Timeout)
+	if err != nil {
+		return nil, err
+	}
+	logger.Infof("dns: Resolving with servers %q", strings.Join(r.servers, ","))
+	return &be{cfg: cfg, resolver: r, logger: logger}, nil
+}
+
+func (b *be) Register(*config.Register) error {
+	return nil
+}
+
+func (b *be) Deregister(*config.Register) error {
+	return nil
+}
+
+func (b *be) ReadManual(KVPath string) (value string, version uint64, err error) {
+	return "", 0, nil
+}
+
+func (b *be) WriteManual(KVPath, value string, version uint64) (ok bool, err error) {
+	return false, nil
+}
+
+// WatchServices resolves the configured target, or name when no target is
+// configured, and pushes the endpoints every time they change. When a
+// resolution fails the last good endpoints are kept.
+func (b *be) WatchServices(name string, status []string, dc string) chan []*registry.Cluster {
+	target := b.cfg.Target
+	if len(target) == 0 {
+		target = name
+	}
+	b.logger.Infof("dns: Watching Services %q, target %q", name, target)
+
+	svc := make(chan []*registry.Cluster)
+	go b.watchServices(name, target, svc)
+	return svc
+}
+
+func (b *be) WatchManual(KVPath string) chan string {
+	return make(chan string)
+}
+
+func (b *be) WatchPrefixManual(KVPath string) chan map[string]string {
+	return make(chan map[string]string)
+}
+
+func (b *be) watchServices(name, target string, config chan<- []*registry.Cluster) {
+	var last []registry.Endpoint
+	for {
+		endpoints, ttl, err := b.resolver.resolve(target)
+		if err == nil && len(endpoints) == 0 {
+			err = errNoRecords
+		}
+		if err != nil {
+			b.logger.Warnf("dns: Error resolving %s for service %s, keep last %d endpoints. %v", target, name, len(last), err)
+			time.Sleep(b.cfg.MinInterval)
+			continue
+		}
+
+		cluster := &registry.Cluster{Name: name, Endpoints: endpoints}
+		cluster.AddEnvTag()
+		if !reflect.DeepEqual(last, cluster.Endpoints) {
+			b.logger.Infof("dns: Service %s resolved %s to %v, ttl %s", name, target, cluster.Endpoints, ttl)
+			config <- []*registry.Cluster{cluster}
+			last = cluster.Endpoints
+		}
+		time.Sleep(b.refreshInterval(ttl))
+	}
+}
+
+// refreshInterval bounds ttl by the configured min and max interval.
+func (b *be) refreshInterval(ttl time.Duration) time.Duration {
+	if ttl < b.cfg.MinInterval {
+		return b.cfg.MinInterval
+	}
+	if ttl > b.cfg.MaxInterval {
+		return b.cfg.MaxInterval
+	}
+	return ttl
+}
@@ -0,0 +1,179 @@
+package dns
+
+import (
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	miekg "github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+	"github.com/yunfeiyang1916/toolkit/go-upstream/config"
+	"github.com/yunfeiyang1916/toolkit/go-upstream/registry"
+)
+
+// zone is an in-process name server answering from a mutable record set.
+type zone struct {
+	mu      sync.Mutex
+	records map[uint16][]miekg.RR
+	extra   []miekg.RR
+	fail    bool
+	failTp  uint16 // the query type answered with a failure
+}
+
+func (z *zone) set(fail bool, extra []miekg.RR, records ...string) {
+	z.mu.Lock()
+	defer z.mu.Unlock()
+	z.fail = fail
+	z.extra = extra
+	z.records = make(map[uint16][]miekg.RR)
+	for _, s := range records {
+		rr, err := miekg.NewRR(s)
+		if err != nil {
+			panic(err)
+		}
+		z.records[rr.Header().Rrtype] = append(z.records[rr.Header().Rrtype], rr)
+	}
+}
+
+func (z *zone) ServeDNS(w miekg.ResponseWriter, req *miekg.Msg) {
+	z.mu.Lock()
+	defer z.mu.Unlock()
+	resp := new(miekg.Msg)
+	resp.SetReply(req)
+	if z.fail || z.failTp == req.Question[0].Qtype {
+		resp.Rcode = miekg.RcodeServerFailure
+		w.WriteMsg(resp)
+		return
+	}
+	q := req.Question[0]
+	for _, rr := range z.records[q.Qtype] {
+		if rr.Header().Name == q.Name {
+			resp.Answer = append(resp.Answer, rr)
+		}
+	}
+	if q.Qtype == miekg.TypeSRV {
+		resp.Extra = z.extra
+	}
+	w.WriteMsg(resp)
+}
+
+func startZone(t *testing.T) (*zone, string) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen error %s", err)
+	}
+	z := &zone{}
+	started := make(chan struct{})
+	srv := &miekg.Server{PacketConn: pc, Handler: z, NotifyStartedFunc: func() { close(started) }}
+	go srv.ActivateAndServe()
+	<-started
+	t.Cleanup(func() { srv.Shutdown() })
+	return z, pc.LocalAddr().String()
+}
+
+func mustRR(s string) miekg.RR {
+	rr, err := miekg.NewRR(s)
+	if err != nil {
+		panic(err)
+	}
+	return rr
+}
+
+func TestResolveSRV(t *testing.T) {
+	z, addr := startZone(t)
+	z.set(false, []miekg.RR{mustRR("a.example.com. 30 IN A 10.0.0.1")},
+		"_http._tcp.example.com. 60 IN SRV 10 60 8080 a.example.com.",
+		"_http._tcp.example.com. 60 IN SRV 20 0 8081 b.example.com.",
+		"b.example.com. 20 IN A 10.0.0.2",
+	)
+	r, err := newResolver([]string{addr}, time.Second)
+	assert.Nil(t, err)
+
+	// the lowest priority is used
+	endpoints, ttl, err := r.resolve("_http._tcp.example.com")
+	assert.Nil(t, err)
+	assert.Equal(t, 30*time.Second, ttl)
+	assert.Equal(t, []registry.Endpoint{
+		{ID: "10.0.0.1:8080", Addr: "10.0.0.1", Port: 8080, Tags: []string{"__weight=60", "priority=10"}},
+	}, endpoints)
+
+	// the next priority is used when the targets don't resolve
+	z.set(false, nil,
+		"_http._tcp.example.com. 60 IN SRV 10 60 8080 a.example.com.",
+		"_http._tcp.example.com. 60 IN SRV 20 0 8081 b.example.com.",
+		"b.example.com. 20 IN A 10.0.0.2",
+	)
+	endpoints, ttl, err = r.resolve("_http._tcp.example.com")
+	assert.Nil(t, err)
+	assert.Equal(t, 20*time.Second, ttl)
+	assert.Equal(t, []registry.Endpoint{
+		{ID: "10.0.0.2:8081", Addr: "10.0.0.2", Port: 8081, Tags: []string{"__weight=1", "priority=20"}},
+	}, endpoints)
+}
+
+func TestResolveHost(t *testing.T) {
+	z, addr := startZone(t)
+	z.set(false, nil,
+		"example.com. 30 IN A 10.0.0.2",
+		"example.com. 30 IN A 10.0.0.1",
+		"example.com. 10 IN AAAA ::1",
+	)
+	r, err := newResolver([]string{addr}, time.Second)
+	assert.Nil(t, err)
+
+	endpoints, ttl, err := r.resolve("example.com:80")
+	assert.Nil(t, err)
+	assert.Equal(t, 10*time.Second, ttl)
+	assert.Equal(t, []registry.Endpoint{
+		{ID: "10.0.0.1:80", Addr: "10.0.0.1", Port: 80},
+		{ID: "10.0.0.2:80", Addr: "10.0.0.2", Port: 80},
+		{ID: "[::1]:80", Addr: "::1", Port: 80},
+	}, endpoints)
+
+	_, _, err = r.resolve("example.com")
+	assert.Equal(t, errBadTarget, err)
+
+	// a failed AAAA query is no AAAA records
+	z.failTp = miekg.TypeAAAA
+	endpoints, ttl, err = r.resolve("example.com:80")
+	assert.Nil(t, err)
+	assert.Equal(t, 30*time.Second, ttl)
+	assert.Equal(t, []registry.Endpoint{
+		{ID: "10.0.0.1:80", Addr: "10.0.0.1", Port: 80},
+		{ID: "10.0.0.2:80", Addr: "10.0.0.2", Port: 80},
+	}, endpoints)
+}
+
+func TestWatchServices(t *testing.T) {
+	z, addr := startZone(t)
+	z.set(false, nil, "example.com. 0 IN A 10.0.0.1")
+	b, err := NewBackend(&config.DNS{Target: "example.com:80", Servers: []string{addr}, MinInterval: 50 * time.Millisecond})
+	assert.Nil(t, err)
+
+	next := func(ch chan []*registry.Cluster, d time.Duration) []*registry.Cluster {
+		select {
+		case cs := <-ch:
+			return cs
+		case <-time.After(d):
+			return nil
+		}
+	}
+
+	ch := b.WatchServices("example", nil, "")
+	cs := next(ch, time.Second)
+	assert.Equal(t, 1, len(cs))
+	assert.Equal(t, "example", cs[0].Name)
+	assert.Equal(t, []registry.Endpoint{{ID: "10.0.0.1:80", Addr: "10.0.0.1", Port: 80, Tags: []string{"env=online"}}}, cs[0].Endpoints)
+
+	// failed and empty resolutions keep the last good endpoints
+	z.set(true, nil)
+	assert.Nil(t, next(ch, 300*time.Millisecond))
+	z.set(false, nil)
+	assert.Nil(t, next(ch, 300*time.Millisecond))
+
+	z.set(false, nil, "example.com. 0 IN A 10.0.0.2")
+	cs = next(ch, time.Second)
+	assert.Equal(t, 1, len(cs))
+	assert.Equal(t, "10.0.0.2", cs[0].Endpoints[0].Addr)
+}
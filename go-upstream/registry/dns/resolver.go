@@ -0,0 +1,249 @@
+package dns
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	miekg "github.com/miekg/dns"
+	"github.com/yunfeiyang1916/toolkit/go-upstream/registry"
+)
+
+const weightTagsKey = "__weight"
+
+var (
+	errNoRecords   = errors.New("dns: no records found")
+	errNoServers   = errors.New("dns: no name servers")
+	errBadTarget   = errors.New("dns: target must be a SRV name or host:port")
+	errBadResponse = errors.New("dns: bad response")
+)
+
+type endpointSlice []registry.Endpoint
+
+func (s endpointSlice) Less(i, j int) bool {
+	return s[i].ID < s[j].ID
+}
+func (s endpointSlice) Len() int {
+	return len(s)
+}
+func (s endpointSlice) Swap(i, j int) {
+	s[i], s[j] = s[j], s[i]
+}
+
+type resolver struct {
+	servers []string
+	udp     *miekg.Client
+	tcp     *miekg.Client
+}
+
+func newResolver(servers []string, timeout time.Duration) (*resolver, error) {
+	if len(servers) == 0 {
+		conf, err := miekg.ClientConfigFromFile(resolvConf)
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range conf.Servers {
+			servers = append(servers, net.JoinHostPort(s, conf.Port))
+		}
+	}
+	if len(servers) == 0 {
+		return nil, errNoServers
+	}
+	return &resolver{
+		servers: servers,
+		udp:     &miekg.Client{Net: "udp", Timeout: timeout},
+		tcp:     &miekg.Client{Net: "tcp", Timeout: timeout},
+	}, nil
+}
+
+// resolve returns the endpoints of target sorted by id, and the smallest ttl
+// of the records they were built from.
+//
+// A target starting with an underscore is looked up as a SRV name, only the
+// records of the lowest priority whose targets resolve are used, the next
+// priority is a fallback. The priority of each record is set as the
+// "priority" tag and its weight as the host weight. Any other target must
+// be host:port and is looked up as A and AAAA records.
+func (r *resolver) resolve(target string) ([]registry.Endpoint, time.Duration, error) {
+	if strings.HasPrefix(target, "_") {
+		return r.resolveSRV(target)
+	}
+	host, portStr, err := net.SplitHostPort(target)
+	if err != nil {
+		return nil, 0, errBadTarget
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		return nil, 0, errBadTarget
+	}
+	ips, ttl, err := r.resolveHost(host, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	endpoints := make([]registry.Endpoint, 0, len(ips))
+	for _, ip := range ips {
+		endpoints = append(endpoints, registry.Endpoint{
+			ID:   net.JoinHostPort(ip, portStr),
+			Addr: ip,
+			Port: port,
+		})
+	}
+	sort.Sort(endpointSlice(endpoints))
+	return endpoints, ttl, nil
+}
+
+func (r *resolver) resolveSRV(name string) ([]registry.Endpoint, time.Duration, error) {
+	msg, err := r.exchange(name, miekg.TypeSRV)
+	if err != nil {
+		return nil, 0, err
+	}
+	var srvs []*miekg.SRV
+	for _, rr := range msg.Answer {
+		if srv, ok := rr.(*miekg.SRV); ok {
+			srvs = append(srvs, srv)
+		}
+	}
+	if len(srvs) == 0 {
+		return []registry.Endpoint{}, math.MaxUint32 * time.Second, nil
+	}
+	sort.SliceStable(srvs, func(i, j int) bool {
+		return srvs[i].Priority < srvs[j].Priority
+	})
+	for i, j := 0, 0; i < len(srvs); i = j {
+		for j = i; j < len(srvs) && srvs[j].Priority == srvs[i].Priority; j++ {
+		}
+		endpoints, ttl, e := r.resolveTargets(srvs[i:j], msg.Extra)
+		if e == nil {
+			return endpoints, ttl, nil
+		}
+		err = e
+	}
+	return nil, 0, err
+}
+
+// resolveTargets returns the endpoints of the SRV records of one priority.
+func (r *resolver) resolveTargets(srvs []*miekg.SRV, extra []miekg.RR) ([]registry.Endpoint, time.Duration, error) {
+	ttl := uint32(math.MaxUint32)
+	endpoints := make([]registry.Endpoint, 0, len(srvs))
+	seen := make(map[string]bool)
+	for _, srv := range srvs {
+		ttl = minTTL(ttl, srv.Hdr.Ttl)
+		ips, hostTTL, err := r.resolveHost(srv.Target, extra)
+		if err != nil {
+			return nil, 0, fmt.Errorf("resolve srv target %s error %s", srv.Target, err)
+		}
+		ttl = minTTL(ttl, uint32(hostTTL/time.Second))
+		weight := srv.Weight
+		if weight == 0 {
+			weight = 1
+		}
+		for _, ip := range ips {
+			id := net.JoinHostPort(ip, strconv.Itoa(int(srv.Port)))
+			if seen[id] {
+				continue
+			}
+			seen[id] = true
+			endpoints = append(endpoints, registry.Endpoint{
+				ID:   id,
+				Addr: ip,
+				Port: int(srv.Port),
+				Tags: []string{
+					weightTagsKey + "=" + strconv.Itoa(int(weight)),
+					"priority=" + strconv.Itoa(int(srv.Priority)),
+				},
+			})
+		}
+	}
+	sort.Sort(endpointSlice(endpoints))
+	return endpoints, time.Duration(ttl) * time.Second, nil
+}
+
+// resolveHost returns the addresses of host, using the A and AAAA records of
+// extra when the host is found there. A failed AAAA query counts as no
+// records, the name servers without ipv6 support may reject it.
+func (r *resolver) resolveHost(host string, extra []miekg.RR) ([]string, time.Duration, error) {
+	if ip := net.ParseIP(host); ip != nil {
+		return []string{ip.String()}, math.MaxUint32 * time.Second, nil
+	}
+	fqdn := miekg.Fqdn(host)
+	ttl := uint32(math.MaxUint32)
+	ips := addrRecords(fqdn, extra, &ttl)
+	if len(ips) != 0 {
+		return ips, time.Duration(ttl) * time.Second, nil
+	}
+	for _, qtype := range []uint16{miekg.TypeA, miekg.TypeAAAA} {
+		msg, err := r.exchange(fqdn, qtype)
+		if err != nil && qtype == miekg.TypeAAAA {
+			continue
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+		// the answer may hold a CNAME chain, so all address records count
+		ips = append(ips, addrRecords("", msg.Answer, &ttl)...)
+	}
+	if len(ips) == 0 {
+		return nil, 0, errNoRecords
+	}
+	return ips, time.Duration(ttl) * time.Second, nil
+}
+
+// exchange queries the name servers in turn until one of them answers,
+// falling back to tcp on truncated responses.
+func (r *resolver) exchange(name string, qtype uint16) (*miekg.Msg, error) {
+	req := new(miekg.Msg)
+	req.SetQuestion(miekg.Fqdn(name), qtype)
+	var lastErr error
+	for _, server := range r.servers {
+		msg, _, err := r.udp.Exchange(req, server)
+		if err == nil && msg.Truncated {
+			msg, _, err = r.tcp.Exchange(req, server)
+		}
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if msg.Rcode == miekg.RcodeNameError {
+			return msg, nil
+		}
+		if msg.Rcode != miekg.RcodeSuccess {
+			lastErr = fmt.Errorf("%w %s from %s", errBadResponse, miekg.RcodeToString[msg.Rcode], server)
+			continue
+		}
+		return msg, nil
+	}
+	return nil, lastErr
+}
+
+// addrRecords returns the addresses of the A and AAAA records of name in rrs,
+// or of all of them when name is empty, lowering ttl to the smallest record ttl.
+func addrRecords(name string, rrs []miekg.RR, ttl *uint32) []string {
+	var ips []string
+	for _, rr := range rrs {
+		if len(name) != 0 && !strings.EqualFold(rr.Header().Name, name) {
+			continue
+		}
+		switch v := rr.(type) {
+		case *miekg.A:
+			ips = append(ips, v.A.String())
+		case *miekg.AAAA:
+			ips = append(ips, v.AAAA.String())
+		default:
+			continue
+		}
+		*ttl = minTTL(*ttl, rr.Header().Ttl)
+	}
+	return ips
+}
+
+func minTTL(a, b uint32) uint32 {
+	if a < b {
+		return a
+	}
+	return b
+}
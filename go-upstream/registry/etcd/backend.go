@@ -0,0 +1,113 @@
+// Package etcd implements a registry backend for etcd v3.
+package etcd
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/yunfeiyang1916/toolkit/go-upstream/config"
+	"github.com/yunfeiyang1916/toolkit/go-upstream/registry"
+	"github.com/yunfeiyang1916/toolkit/logging"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+const (
+	defaultPrefix      = "/services"
+	defaultTTL         = 10
+	defaultDialTimeout = 5 * time.Second
+	requestTimeout     = 5 * time.Second
+)
+
+// be is an implementation of a registry backend for etcd.
+type be struct {
+	c      *clientv3.Client
+	cfg    *config.Etcd
+	logger *logging.Logger
+}
+
+func NewBackend(cfg *config.Etcd) (registry.Backend, error) {
+	if len(cfg.Prefix) == 0 {
+		cfg.Prefix = defaultPrefix
+	}
+	cfg.Prefix = strings.TrimSuffix(cfg.Prefix, "/")
+	if cfg.TTL <= 0 {
+		cfg.TTL = defaultTTL
+	}
+	if cfg.DialTimeout <= 0 {
+		cfg.DialTimeout = defaultDialTimeout
+	}
+	logger := cfg.Logger
+	if logger == nil {
+		logger = logging.New()
+	}
+
+	// create a reusable client
+	c, err := clientv3.New(clientv3.Config{
+		Endpoints:   cfg.Endpoints,
+		DialTimeout: cfg.DialTimeout,
+		Username:    cfg.Username,
+		Password:    cfg.Password,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// ping the cluster
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+	if _, err = c.Get(ctx, cfg.Prefix, clientv3.WithCountOnly()); err != nil {
+		logger.Warnf("etcd backend init error %s", err)
+	}
+
+	logger.Infof("etcd: Connecting to %q with prefix %q", strings.Join(cfg.Endpoints, ","), cfg.Prefix)
+	return &be{c: c, cfg: cfg, logger: logger}, nil
+}
+
+func (b *be) Register(cfg *config.Register) error {
+	tagsValue := <-cfg.TagsOverrideCh
+	key, endpoint := serviceRegistration(b.cfg.Prefix, cfg, tagsValue)
+	cfg.DerigesterCh = register(b.logger, b.c, key, b.cfg.TTL, endpoint, cfg.TagsOverrideCh)
+	return nil
+}
+
+func (b *be) Deregister(cfg *config.Register) error {
+	cfg.DerigesterCh <- true // trigger deregistration
+	<-cfg.DerigesterCh
+	return nil
+}
+
+func (b *be) ReadManual(KVPath string) (value string, version uint64, err error) {
+	return getKV(b.c, KVPath)
+}
+
+// WriteManual stores value only if the mod revision of the key still matches
+// version, version 0 creates a key which does not exist yet.
+func (b *be) WriteManual(KVPath, value string, version uint64) (ok bool, err error) {
+	return putKV(b.c, KVPath, value, version)
+}
+
+// WatchServices watches the instances registered under the service prefix.
+// Registrations are bound to a lease, so an instance is removed as soon as its
+// lease expires; status and dc are ignored by this backend.
+func (b *be) WatchServices(name string, status []string, dc string) chan []*registry.Cluster {
+	b.logger.Infof("etcd: Watching Services %q", name)
+
+	svc := make(chan []*registry.Cluster)
+	go watchServices(b.logger, b.c, servicePrefix(b.cfg.Prefix, name), name, svc)
+	return svc
+}
+
+func (b *be) WatchManual(KVPath string) chan string {
+	b.logger.Infof("etcd: Watching KV path %q", KVPath)
+	kv := make(chan string)
+	go watchKV(b.logger, b.c, KVPath, kv)
+	return kv
+}
+
+func (b *be) WatchPrefixManual(prefix string) chan map[string]string {
+	b.logger.Infof("etcd: Watching prefix path %q", prefix)
+	kvs := make(chan map[string]string)
+	go watchPrefix(b.logger, b.c, prefix, kvs)
+	return kvs
+}
@@ -0,0 +1,138 @@
+package etcd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yunfeiyang1916/toolkit/go-upstream/config"
+	"github.com/yunfeiyang1916/toolkit/go-upstream/registry"
+	"github.com/yunfeiyang1916/toolkit/internal/etcdtest"
+)
+
+func newTestBackend(t *testing.T) registry.Backend {
+	b, err := NewBackend(&config.Etcd{Endpoints: []string{etcdtest.Start(t)}, TTL: 2})
+	if err != nil {
+		t.Fatalf("init etcd backend error %s", err)
+	}
+	return b
+}
+
+func nextClusters(t *testing.T, ch chan []*registry.Cluster) []*registry.Cluster {
+	select {
+	case cs := <-ch:
+		return cs
+	case <-time.After(5 * time.Second):
+		t.Fatal("wait services timeout")
+	}
+	return nil
+}
+
+func TestManual(t *testing.T) {
+	b := newTestBackend(t)
+
+	value, version, err := b.ReadManual("/test/manual")
+	assert.Nil(t, err)
+	assert.Equal(t, "", value)
+	assert.Equal(t, uint64(0), version)
+
+	ok, err := b.WriteManual("/test/manual", "v1", 0)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+
+	// the key exists, creating it again must fail
+	ok, err = b.WriteManual("/test/manual", "v2", 0)
+	assert.Nil(t, err)
+	assert.False(t, ok)
+
+	value, version, err = b.ReadManual("/test/manual")
+	assert.Nil(t, err)
+	assert.Equal(t, "v1", value)
+
+	ok, err = b.WriteManual("/test/manual", "v2", version)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+
+	// stale version
+	ok, err = b.WriteManual("/test/manual", "v3", version)
+	assert.Nil(t, err)
+	assert.False(t, ok)
+
+	value, _, err = b.ReadManual("/test/manual")
+	assert.Nil(t, err)
+	assert.Equal(t, "v2", value)
+}
+
+func TestWatchManual(t *testing.T) {
+	b := newTestBackend(t)
+
+	ch := b.WatchManual("/test/watch")
+	assert.Equal(t, "", <-ch)
+
+	ok, err := b.WriteManual("/test/watch", "v1", 0)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	select {
+	case v := <-ch:
+		assert.Equal(t, "v1", v)
+	case <-time.After(5 * time.Second):
+		t.Fatal("wait manual timeout")
+	}
+}
+
+func TestWatchPrefixManual(t *testing.T) {
+	b := newTestBackend(t)
+
+	ok, err := b.WriteManual("/test/prefix/a", "1", 0)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+
+	ch := b.WatchPrefixManual("/test/prefix/")
+	assert.Equal(t, map[string]string{"/test/prefix/a": "1"}, <-ch)
+
+	ok, err = b.WriteManual("/test/prefix/b", "2", 0)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	select {
+	case v := <-ch:
+		assert.Equal(t, map[string]string{"/test/prefix/a": "1", "/test/prefix/b": "2"}, v)
+	case <-time.After(5 * time.Second):
+		t.Fatal("wait prefix manual timeout")
+	}
+}
+
+func TestRegister(t *testing.T) {
+	b := newTestBackend(t)
+
+	ch := b.WatchServices("test.service", nil, "")
+	cs := nextClusters(t, ch)
+	assert.Equal(t, 1, len(cs))
+	assert.Equal(t, 0, len(cs[0].Endpoints))
+
+	reg := config.NewRegister("test.service", "127.0.0.1", 8080)
+	reg.TagsOverrideCh <- []string{"a=1"}
+	assert.Nil(t, b.Register(reg))
+
+	cs = nextClusters(t, ch)
+	assert.Equal(t, []registry.Endpoint{{
+		ID:   "test.service-127.0.0.1:8080",
+		Addr: "127.0.0.1",
+		Port: 8080,
+		Tags: []string{"a=1", "env=online"},
+	}}, cs[0].Endpoints)
+
+	reg.TagsOverrideCh <- []string{"a=2", "env=test"}
+	cs = nextClusters(t, ch)
+	assert.Equal(t, []string{"a=2", "env=test"}, cs[0].Endpoints[0].Tags)
+
+	// the lease must be kept alive longer than its ttl
+	select {
+	case cs = <-ch:
+		t.Fatalf("unexpected services change %v", cs[0].Endpoints)
+	case <-time.After(3 * time.Second):
+	}
+
+	assert.Nil(t, b.Deregister(reg))
+	cs = nextClusters(t, ch)
+	assert.Equal(t, 0, len(cs[0].Endpoints))
+}
@@ -0,0 +1,167 @@
+package etcd
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/yunfeiyang1916/toolkit/logging"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// watchKV monitors a key in the KV store for changes.
+// The intended use case is to add additional route commands to the routing table.
+func watchKV(logger *logging.Logger, client *clientv3.Client, path string, config chan string) {
+	var (
+		lastValue string
+		pushed    bool
+	)
+	for {
+		value, rev, err := getKVRevision(client, path)
+		if err != nil {
+			logger.Warnf("etcd: Error fetching config from %s. %v", path, err)
+			time.Sleep(time.Second)
+			continue
+		}
+		if !pushed || value != lastValue {
+			logger.Infof("etcd: Manual config changed to #%d (path %s, last value len %d, new value len %d)", rev, path, len(lastValue), len(value))
+			config <- value
+			lastValue, pushed = value, true
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		for resp := range client.Watch(clientv3.WithRequireLeader(ctx), path, clientv3.WithRev(rev+1)) {
+			if err := resp.Err(); err != nil {
+				logger.Warnf("etcd: Error watching config from %s. %v", path, err)
+				break
+			}
+			for _, ev := range resp.Events {
+				value = ""
+				if ev.Type == clientv3.EventTypePut {
+					value = strings.TrimSpace(string(ev.Kv.Value))
+				}
+			}
+			if len(resp.Events) != 0 && value != lastValue {
+				logger.Infof("etcd: Manual config changed to #%d (path %s, last value len %d, new value len %d)", resp.Header.Revision, path, len(lastValue), len(value))
+				config <- value
+				lastValue = value
+			}
+		}
+		cancel()
+		time.Sleep(time.Second)
+	}
+}
+
+// watchPrefix monitors a prefix in the KV store for changes.
+// The intended use case is to add additional route commands to the routing table.
+func watchPrefix(logger *logging.Logger, client *clientv3.Client, prefix string, config chan map[string]string) {
+	var (
+		lastValue map[string]string
+		pushed    bool
+	)
+	for {
+		values, rev, err := getPrefix(client, prefix)
+		if err != nil {
+			logger.Warnf("etcd: Error fetching prefix config from %s. %v", prefix, err)
+			time.Sleep(time.Second)
+			continue
+		}
+		if !pushed || !reflect.DeepEqual(lastValue, values) {
+			logger.Infof("etcd: Manual prefix config changed to #%d", rev)
+			config <- values
+			lastValue, pushed = values, true
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		for resp := range client.Watch(clientv3.WithRequireLeader(ctx), prefix, clientv3.WithPrefix(), clientv3.WithRev(rev+1)) {
+			if err := resp.Err(); err != nil {
+				logger.Warnf("etcd: Error watching prefix config from %s. %v", prefix, err)
+				break
+			}
+			values = make(map[string]string, len(lastValue))
+			for k, v := range lastValue {
+				values[k] = v
+			}
+			for _, ev := range resp.Events {
+				key := string(ev.Kv.Key)
+				if ev.Type == clientv3.EventTypeDelete {
+					delete(values, key)
+					continue
+				}
+				values[key] = strings.TrimSpace(string(ev.Kv.Value))
+			}
+			if len(values) == 0 {
+				values = nil
+			}
+			if !reflect.DeepEqual(lastValue, values) {
+				logger.Infof("etcd: Manual prefix config changed to #%d", resp.Header.Revision)
+				config <- values
+				lastValue = values
+			}
+		}
+		cancel()
+		time.Sleep(time.Second)
+	}
+}
+
+// getKV returns the value of key and its mod revision, which is
+// the version to use for a following putKV.
+func getKV(client *clientv3.Client, key string) (string, uint64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+	resp, err := client.Get(ctx, key)
+	if err != nil {
+		return "", 0, err
+	}
+	if len(resp.Kvs) == 0 {
+		return "", 0, nil
+	}
+	return strings.TrimSpace(string(resp.Kvs[0].Value)), uint64(resp.Kvs[0].ModRevision), nil
+}
+
+// getKVRevision returns the value of key and the store revision to watch from.
+func getKVRevision(client *clientv3.Client, key string) (string, int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+	resp, err := client.Get(ctx, key)
+	if err != nil {
+		return "", 0, err
+	}
+	if len(resp.Kvs) == 0 {
+		return "", resp.Header.Revision, nil
+	}
+	return strings.TrimSpace(string(resp.Kvs[0].Value)), resp.Header.Revision, nil
+}
+
+func getPrefix(client *clientv3.Client, prefix string) (map[string]string, int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+	resp, err := client.Get(ctx, prefix, clientv3.WithPrefix())
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(resp.Kvs) == 0 {
+		return nil, resp.Header.Revision, nil
+	}
+	kvs := make(map[string]string, len(resp.Kvs))
+	for _, kv := range resp.Kvs {
+		kvs[string(kv.Key)] = strings.TrimSpace(string(kv.Value))
+	}
+	return kvs, resp.Header.Revision, nil
+}
+
+// putKV writes value if the mod revision of key equals index. A key which
+// does not exist has mod revision 0.
+func putKV(client *clientv3.Client, key, value string, index uint64) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+	resp, err := client.Txn(ctx).
+		If(clientv3.Compare(clientv3.ModRevision(key), "=", int64(index))).
+		Then(clientv3.OpPut(key, value)).
+		Commit()
+	if err != nil {
+		return false, err
+	}
+	return resp.Succeeded, nil
+}
@@ -0,0 +1,138 @@
+package etcd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/yunfeiyang1916/toolkit/go-upstream/config"
+	"github.com/yunfeiyang1916/toolkit/go-upstream/registry"
+	"github.com/yunfeiyang1916/toolkit/logging"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+const retryInterval = 10 * time.Second
+
+// register keeps a service registered in etcd.
+//
+// The instance key is attached to a lease which is kept alive for as long
+// as the service is registered. When the keepalive stream is lost the
+// instance is registered again with a new lease.
+//
+// When a value is sent in the dereg channel the service is deregistered from
+// etcd. To wait for completion the caller should read the next value from
+// the dereg channel.
+//
+//    dereg <- true // trigger deregistration
+//    <-dereg       // wait for completion
+//
+func register(logger *logging.Logger, c *clientv3.Client, key string, ttl int64, endpoint registry.Endpoint, tagsOverride <-chan []string) (dereg chan bool) {
+	var (
+		leaseID   clientv3.LeaseID
+		keepalive <-chan *clientv3.LeaseKeepAliveResponse
+		stop      context.CancelFunc
+	)
+
+	put := func() error {
+		value, err := json.Marshal(endpoint)
+		if err != nil {
+			return err
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+		defer cancel()
+		_, err = c.Put(ctx, key, string(value), clientv3.WithLease(leaseID))
+		return err
+	}
+
+	register := func() {
+		if stop != nil {
+			stop()
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+		lease, err := c.Grant(ctx, ttl)
+		cancel()
+		if err != nil {
+			logger.Errorf("etcd: Cannot grant lease for service %s. %s", key, err)
+			return
+		}
+		leaseID = lease.ID
+		if err = put(); err != nil {
+			logger.Errorf("etcd: Cannot register service %s in etcd. %s", key, err)
+			return
+		}
+
+		var kaCtx context.Context
+		kaCtx, stop = context.WithCancel(context.Background())
+		keepalive, err = c.KeepAlive(kaCtx, leaseID)
+		if err != nil {
+			logger.Errorf("etcd: Cannot keep alive lease %x of service %s. %s", leaseID, key, err)
+			stop()
+			keepalive = nil
+			return
+		}
+		logger.Infof("etcd: Registered service %s with lease %x, address %s:%d, tags %q", key, leaseID, endpoint.Addr, endpoint.Port, strings.Join(endpoint.Tags, ","))
+	}
+
+	deregister := func() {
+		if stop != nil {
+			stop()
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+		defer cancel()
+		_, err := c.Revoke(ctx, leaseID)
+		logger.Infof("etcd: Deregistering service %s with lease %x, err %v", key, leaseID, err)
+	}
+
+	dereg = make(chan bool)
+	go func() {
+		register()
+		ticker := time.NewTicker(retryInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-dereg:
+				deregister()
+				dereg <- true
+				return
+			case _, ok := <-keepalive:
+				if !ok {
+					logger.Warnf("etcd: Lease %x of service %s lost", leaseID, key)
+					keepalive = nil
+				}
+			case <-ticker.C:
+				if keepalive == nil {
+					register()
+				}
+			case tags := <-tagsOverride:
+				endpoint.Tags = tags
+				if keepalive == nil {
+					register()
+					continue
+				}
+				if err := put(); err != nil {
+					logger.Errorf("etcd: Cannot update tags of service %s. %s", key, err)
+					continue
+				}
+				logger.Infof("etcd: Updated service %s tags %q", key, strings.Join(tags, ","))
+			}
+		}
+	}()
+	return dereg
+}
+
+func servicePrefix(prefix, name string) string {
+	return prefix + "/" + name + "/"
+}
+
+func serviceRegistration(prefix string, cfg *config.Register, tags []string) (string, registry.Endpoint) {
+	serviceID := fmt.Sprintf("%s-%s:%d", cfg.ServiceName, cfg.ServiceAddr, cfg.ServicePort)
+	endpoint := registry.Endpoint{
+		ID:   serviceID,
+		Addr: cfg.ServiceAddr,
+		Port: cfg.ServicePort,
+		Tags: tags,
+	}
+	return servicePrefix(prefix, cfg.ServiceName) + serviceID, endpoint
+}
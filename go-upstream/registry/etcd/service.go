@@ -0,0 +1,98 @@
+package etcd
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/yunfeiyang1916/toolkit/go-upstream/registry"
+	"github.com/yunfeiyang1916/toolkit/logging"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+type endpointSlice []registry.Endpoint
+
+func (s endpointSlice) Less(i, j int) bool {
+	return s[i].ID < s[j].ID
+}
+func (s endpointSlice) Len() int {
+	return len(s)
+}
+func (s endpointSlice) Swap(i, j int) {
+	s[i], s[j] = s[j], s[i]
+}
+
+// watchServices monitors the instances registered under prefix and creates
+// a new configuration on every change.
+func watchServices(logger *logging.Logger, client *clientv3.Client, prefix, service string, config chan<- []*registry.Cluster) {
+	var lastConfig []*registry.Cluster
+
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+		resp, err := client.Get(ctx, prefix, clientv3.WithPrefix())
+		cancel()
+		if err != nil {
+			logger.Warnf("etcd: Error fetching service %s instances. %v", service, err)
+			time.Sleep(time.Second)
+			continue
+		}
+		instances := make(map[string]registry.Endpoint, len(resp.Kvs))
+		for _, kv := range resp.Kvs {
+			applyInstance(logger, instances, kv, false)
+		}
+		lastConfig = pushServices(logger, service, instances, lastConfig, resp.Header.Revision, config)
+
+		wctx, wcancel := context.WithCancel(context.Background())
+		for wresp := range client.Watch(clientv3.WithRequireLeader(wctx), prefix, clientv3.WithPrefix(), clientv3.WithRev(resp.Header.Revision+1)) {
+			if err := wresp.Err(); err != nil {
+				logger.Warnf("etcd: Error watching service %s instances. %v", service, err)
+				break
+			}
+			for _, ev := range wresp.Events {
+				applyInstance(logger, instances, ev.Kv, ev.Type == clientv3.EventTypeDelete)
+			}
+			lastConfig = pushServices(logger, service, instances, lastConfig, wresp.Header.Revision, config)
+		}
+		wcancel()
+		time.Sleep(time.Second)
+	}
+}
+
+func applyInstance(logger *logging.Logger, instances map[string]registry.Endpoint, kv *mvccpb.KeyValue, deleted bool) {
+	key := string(kv.Key)
+	if deleted {
+		delete(instances, key)
+		return
+	}
+	var e registry.Endpoint
+	if err := json.Unmarshal(kv.Value, &e); err != nil {
+		logger.Warnf("etcd: Error parsing service instance %s. %v", key, err)
+		return
+	}
+	instances[key] = e
+}
+
+func pushServices(logger *logging.Logger, service string, instances map[string]registry.Endpoint, lastConfig []*registry.Cluster, revision int64, config chan<- []*registry.Cluster) []*registry.Cluster {
+	cluster := &registry.Cluster{
+		Name:      service,
+		Endpoints: make([]registry.Endpoint, 0, len(instances)),
+	}
+	for _, e := range instances {
+		e.Tags = append([]string(nil), e.Tags...)
+		cluster.Endpoints = append(cluster.Endpoints, e)
+	}
+	sort.Sort(endpointSlice(cluster.Endpoints))
+	cluster.AddEnvTag()
+
+	newConfig := []*registry.Cluster{cluster}
+	if lastConfig != nil && reflect.DeepEqual(newConfig[0].Endpoints, lastConfig[0].Endpoints) {
+		logger.Infof("etcd: Service %s changed to #%d, but server list not changed", service, revision)
+		return lastConfig
+	}
+	logger.Infof("etcd: Service %s changed to #%d", service, revision)
+	config <- newConfig
+	return newConfig
+}
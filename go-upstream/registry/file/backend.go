@@ -1,40 +1,65 @@
-// Package file implements a simple file based registry
-// backend which reads the routes from a file once.
+// Package file implements a file based registry backend which
+// reloads the clusters and manual values whenever the file changes.
 package file
 
 import (
-	"bytes"
-	"encoding/json"
-	"fmt"
-	"io/ioutil"
 	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/fsnotify/fsnotify"
 	"github.com/yunfeiyang1916/toolkit/go-upstream/config"
 	"github.com/yunfeiyang1916/toolkit/go-upstream/registry"
+	"github.com/yunfeiyang1916/toolkit/logging"
+)
+
+const (
+	// reloadDelay merges the burst of events of a single file write
+	reloadDelay = 100 * time.Millisecond
+	// pollInterval is used when the file system can not be watched
+	pollInterval = 10 * time.Second
 )
 
 type be struct {
-	path       string
-	lastData   *registry.Cluster
-	lastModify time.Time
+	path     string
+	initData *registry.Cluster
+	logger   *logging.Logger
+
+	mu      sync.RWMutex
+	doc     *document
+	version uint64
+	changed chan struct{}
 }
 
+// NewBackend returns a backend of the file filename, initData is pushed to
+// the watchers before the file is read.
 func NewBackend(filename string, initData *registry.Cluster) (registry.Backend, error) {
-	return &be{path: filename, lastModify: time.Time{}, lastData: initData}, nil
+	return NewBackendWithConfig(&config.File{Path: filename}, initData)
 }
 
-func readFileData(path string) (*registry.Cluster, error) {
-	data, err := ioutil.ReadFile(path)
-	if err != nil {
-		return nil, err
+// NewBackendWithConfig is NewBackend with the logger of cfg.
+func NewBackendWithConfig(cfg *config.File, initData *registry.Cluster) (registry.Backend, error) {
+	logger := cfg.Logger
+	if logger == nil {
+		logger = logging.New()
 	}
-	var clusters registry.Cluster
-	err = json.Unmarshal(bytes.TrimSpace(data), &clusters)
+	path, err := filepath.Abs(cfg.Path)
 	if err != nil {
 		return nil, err
 	}
-	return &clusters, nil
+	b := &be{
+		path:     path,
+		initData: initData,
+		logger:   logger,
+		doc:      &document{},
+		changed:  make(chan struct{}),
+	}
+	b.reload()
+	go b.watch()
+	return b, nil
 }
 
 func (b *be) Register(*config.Register) error {
@@ -45,61 +70,175 @@ func (b *be) Deregister(*config.Register) error {
 	return nil
 }
 
-func (b *be) OverrideTags([]string) {
-}
-
 func (b *be) ReadManual(KVPath string) (value string, version uint64, err error) {
-	return "", 0, nil
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return strings.TrimSpace(b.doc.Manual[KVPath]), b.version, nil
 }
 
+// WriteManual never writes, the manual values are only changed in the file.
 func (b *be) WriteManual(KVPath, value string, version uint64) (ok bool, err error) {
 	return false, nil
 }
 
-// func (b *be) WatchServices() chan string {
 func (b *be) WatchServices(name string, status []string, dc string) chan []*registry.Cluster {
+	b.logger.Infof("file: Watching Services %q in %s", name, b.path)
 	ch := make(chan []*registry.Cluster, 1)
-	if b.lastData != nil && len(b.lastData.Endpoints) != 0 {
-		b.lastData.AddEnvTag()
-		ch <- []*registry.Cluster{
-			b.lastData,
-		}
+	var last []registry.Endpoint
+	if b.initData != nil && len(b.initData.Endpoints) != 0 {
+		doc := &document{Clusters: []*registry.Cluster{b.initData}, single: true}
+		cluster := doc.cluster(name)
+		ch <- []*registry.Cluster{cluster}
+		last = cluster.Endpoints
 	}
 	go func() {
 		for {
-			time.Sleep(10 * time.Second)
-			st, err := os.Stat(b.path)
-			if err != nil {
-				fmt.Fprintf(os.Stderr, "[balance relaod] open file (path %s) error %s\n", b.path, err)
-				continue
-			}
-			if st.ModTime().After(b.lastModify) {
-				clusters, err := readFileData(b.path)
-				if err != nil {
-					fmt.Fprintf(os.Stderr, "[balance reload] reaload file (path %s) error %s\n", b.path, err)
-					continue
-				}
-				fmt.Fprintf(os.Stdout, "[balance reload] reload file (path %s) success with data %+v\n", b.path, clusters)
-				if len(clusters.Endpoints) == 0 {
-					fmt.Fprintf(os.Stderr, "[balance reload] reload file (path %s) success with data %+v, size 0\n", b.path, clusters)
-					continue
-				}
-				b.lastModify = st.ModTime()
-				b.lastData = clusters
-				b.lastData.AddEnvTag()
-				ch <- []*registry.Cluster{
-					b.lastData,
+			doc, version, changed := b.current()
+			if cluster := doc.cluster(name); cluster != nil {
+				if len(cluster.Endpoints) == 0 {
+					b.logger.Warnf("file: Service %s in %s has no endpoints, ignore it", name, b.path)
+				} else if !reflect.DeepEqual(last, cluster.Endpoints) {
+					b.logger.Infof("file: Service %s changed to #%d with endpoints %v", name, version, cluster.Endpoints)
+					ch <- []*registry.Cluster{cluster}
+					last = cluster.Endpoints
 				}
 			}
+			<-changed
 		}
 	}()
 	return ch
 }
 
 func (b *be) WatchManual(KVPath string) chan string {
-	return make(chan string)
+	b.logger.Infof("file: Watching KV path %q in %s", KVPath, b.path)
+	kv := make(chan string)
+	go func() {
+		var (
+			lastValue string
+			pushed    bool
+		)
+		for {
+			doc, version, changed := b.current()
+			value := strings.TrimSpace(doc.Manual[KVPath])
+			if !pushed || value != lastValue {
+				b.logger.Infof("file: Manual config changed to #%d (path %s, last value len %d, new value len %d)", version, KVPath, len(lastValue), len(value))
+				kv <- value
+				lastValue, pushed = value, true
+			}
+			<-changed
+		}
+	}()
+	return kv
+}
+
+func (b *be) WatchPrefixManual(prefix string) chan map[string]string {
+	b.logger.Infof("file: Watching prefix path %q in %s", prefix, b.path)
+	kvs := make(chan map[string]string)
+	go func() {
+		var (
+			lastValue map[string]string
+			pushed    bool
+		)
+		for {
+			doc, version, changed := b.current()
+			values := doc.prefix(prefix)
+			if !pushed || !reflect.DeepEqual(lastValue, values) {
+				b.logger.Infof("file: Manual prefix config changed to #%d", version)
+				kvs <- values
+				lastValue, pushed = values, true
+			}
+			<-changed
+		}
+	}()
+	return kvs
 }
 
-func (b *be) WatchPrefixManual(KVPath string) chan map[string]string {
-	return make(chan map[string]string)
+// current returns the last good document, its version and a channel
+// which is closed when a newer document is loaded.
+func (b *be) current() (*document, uint64, chan struct{}) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.doc, b.version, b.changed
+}
+
+// reload reads the file and, when it is valid, replaces the current document.
+// The last good document is kept on any error.
+func (b *be) reload() {
+	doc, err := readDocument(b.path)
+	if err != nil {
+		_, version, _ := b.current()
+		b.logger.Errorf("file: Reload %s error %s, keep version #%d", b.path, err, version)
+		return
+	}
+	b.mu.Lock()
+	b.doc = doc
+	b.version++
+	close(b.changed)
+	b.changed = make(chan struct{})
+	b.logger.Infof("file: Reload %s success, version #%d with %d clusters and %d manual values", b.path, b.version, len(doc.Clusters), len(doc.Manual))
+	b.mu.Unlock()
+}
+
+// watch reloads the file on every change. The directory is watched rather
+// than the file so that files replaced by rename are followed.
+func (b *be) watch() {
+	w, err := fsnotify.NewWatcher()
+	if err == nil {
+		err = w.Add(filepath.Dir(b.path))
+	}
+	if err != nil {
+		b.logger.Warnf("file: Watch %s error %s, fallback to polling every %s", b.path, err, pollInterval)
+		if w != nil {
+			w.Close()
+		}
+		b.poll()
+		return
+	}
+	defer w.Close()
+
+	var delay <-chan time.Time
+	for {
+		select {
+		case ev, ok := <-w.Events:
+			if !ok {
+				return
+			}
+			if filepath.Clean(ev.Name) != b.path || ev.Op == fsnotify.Chmod {
+				continue
+			}
+			if ev.Op&(fsnotify.Remove|fsnotify.Rename) != 0 {
+				_, version, _ := b.current()
+				b.logger.Warnf("file: %s removed, keep version #%d", b.path, version)
+			}
+			delay = time.After(reloadDelay)
+		case err, ok := <-w.Errors:
+			if !ok {
+				return
+			}
+			b.logger.Errorf("file: Watch %s error %s", b.path, err)
+		case <-delay:
+			delay = nil
+			if _, err := os.Stat(b.path); err == nil {
+				b.reload()
+			}
+		}
+	}
+}
+
+func (b *be) poll() {
+	var lastModify time.Time
+	if st, err := os.Stat(b.path); err == nil {
+		lastModify = st.ModTime()
+	}
+	for range time.Tick(pollInterval) {
+		st, err := os.Stat(b.path)
+		if err != nil {
+			b.logger.Errorf("file: Stat %s error %s", b.path, err)
+			continue
+		}
+		if st.ModTime().After(lastModify) {
+			lastModify = st.ModTime()
+			b.reload()
+		}
+	}
 }
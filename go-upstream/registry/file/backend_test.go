@@ -0,0 +1,150 @@
+package file
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yunfeiyang1916/toolkit/go-upstream/config"
+	"github.com/yunfeiyang1916/toolkit/go-upstream/registry"
+)
+
+const tomlDoc = `
+[[clusters]]
+name = "svc.a"
+  [[clusters.endpoints]]
+  addr = "10.0.0.1"
+  port = 8080
+  tags = ["a=1"]
+
+[[clusters]]
+name = "svc.b"
+  [[clusters.endpoints]]
+  id = "b1"
+  addr = "10.0.0.2"
+  port = 8081
+
+[manual]
+"/tags/a" = "1"
+"/tags/b" = "2"
+"/other" = "3"
+`
+
+const yamlDoc = `
+clusters:
+- name: svc.a
+  endpoints:
+  - addr: 10.0.0.3
+    port: 8080
+manual:
+  /tags/a: "4"
+`
+
+func writeFile(t *testing.T, path, content string) {
+	if err := ioutil.WriteFile(path+".tmp", []byte(content), 0644); err != nil {
+		t.Fatalf("write file error %s", err)
+	}
+	if err := os.Rename(path+".tmp", path); err != nil {
+		t.Fatalf("rename file error %s", err)
+	}
+}
+
+func TestReadDocument(t *testing.T) {
+	dir := t.TempDir()
+
+	path := filepath.Join(dir, "registry.toml")
+	writeFile(t, path, tomlDoc)
+	doc, err := readDocument(path)
+	assert.Nil(t, err)
+	assert.Equal(t, &registry.Cluster{Name: "svc.a", Endpoints: []registry.Endpoint{
+		{ID: "10.0.0.1:8080", Addr: "10.0.0.1", Port: 8080, Tags: []string{"a=1", "env=online"}},
+	}}, doc.cluster("svc.a"))
+	assert.Equal(t, "b1", doc.cluster("svc.b").Endpoints[0].ID)
+	assert.Nil(t, doc.cluster("svc.c"))
+	assert.Equal(t, map[string]string{"/tags/a": "1", "/tags/b": "2"}, doc.prefix("/tags/"))
+
+	path = filepath.Join(dir, "registry.yaml")
+	writeFile(t, path, yamlDoc)
+	doc, err = readDocument(path)
+	assert.Nil(t, err)
+	assert.Equal(t, "10.0.0.3", doc.cluster("svc.a").Endpoints[0].Addr)
+	assert.Equal(t, "4", doc.Manual["/tags/a"])
+
+	// single cluster json is returned for any service name
+	path = filepath.Join(dir, "registry.json")
+	writeFile(t, path, `{"Name": "svc.a", "Endpoints": [{"Addr": "10.0.0.4", "Port": 8080}]}`)
+	doc, err = readDocument(path)
+	assert.Nil(t, err)
+	assert.Equal(t, "10.0.0.4", doc.cluster("svc.x").Endpoints[0].Addr)
+
+	for _, content := range []string{
+		`{"clusters": [{"endpoints": [{"addr": "10.0.0.1", "port": 80}]}]}`,
+		`{"clusters": [{"name": "a"}, {"name": "a"}]}`,
+		`{"clusters": [{"name": "a", "endpoints": [{"addr": "10.0.0.1"}]}]}`,
+		`{"clusters": [{"name": "a", "endpoints": [{"port": 80}]}]}`,
+		`{"clusters": [{"name": "a", "endpoints": [{"addr": "10.0.0.1", "port": 80}, {"addr": "10.0.0.1", "port": 80}]}]}`,
+	} {
+		writeFile(t, path, content)
+		_, err = readDocument(path)
+		assert.NotNil(t, err, content)
+	}
+}
+
+func TestWatch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "registry.toml")
+	writeFile(t, path, tomlDoc)
+	b, err := NewBackendWithConfig(&config.File{Path: path}, nil)
+	assert.Nil(t, err)
+
+	services := b.WatchServices("svc.a", nil, "")
+	tags := b.WatchManual("/tags/a")
+	prefix := b.WatchPrefixManual("/tags/")
+	assert.Equal(t, "10.0.0.1", (<-services)[0].Endpoints[0].Addr)
+	assert.Equal(t, "1", <-tags)
+	assert.Equal(t, map[string]string{"/tags/a": "1", "/tags/b": "2"}, <-prefix)
+
+	value, version, err := b.ReadManual("/tags/b")
+	assert.Nil(t, err)
+	assert.Equal(t, "2", value)
+	ok, err := b.WriteManual("/tags/b", "3", version)
+	assert.False(t, ok)
+	assert.Nil(t, err)
+
+	// a broken file keeps the last good document
+	writeFile(t, path, `[[clusters]`)
+	time.Sleep(3 * reloadDelay)
+	value, _, _ = b.ReadManual("/tags/b")
+	assert.Equal(t, "2", value)
+
+	writeFile(t, path, `
+[[clusters]]
+name = "svc.a"
+  [[clusters.endpoints]]
+  addr = "10.0.0.9"
+  port = 8080
+
+[manual]
+"/tags/a" = "5"
+`)
+	select {
+	case cs := <-services:
+		assert.Equal(t, "10.0.0.9", cs[0].Endpoints[0].Addr)
+	case <-time.After(2 * time.Second):
+		t.Fatal("wait services timeout")
+	}
+	select {
+	case v := <-tags:
+		assert.Equal(t, "5", v)
+	case <-time.After(2 * time.Second):
+		t.Fatal("wait manual timeout")
+	}
+	select {
+	case v := <-prefix:
+		assert.Equal(t, map[string]string{"/tags/a": "5"}, v)
+	case <-time.After(2 * time.Second):
+		t.Fatal("wait prefix manual timeout")
+	}
+}
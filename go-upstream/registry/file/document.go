@@ -0,0 +1,140 @@
+package file
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	"github.com/yunfeiyang1916/toolkit/go-upstream/registry"
+	"github.com/yunfeiyang1916/toolkit/toml"
+)
+
+// document is the content of a registry file.
+//
+// A file holds any number of named clusters and the manual key values,
+// in json:
+//
+//    {
+//        "clusters": [
+//            {"name": "app.service", "endpoints": [{"addr": "10.0.0.1", "port": 8080, "tags": ["env=online"]}]}
+//        ],
+//        "manual": {"/app/service/tags": "{\"a\": \"1\"}"}
+//    }
+//
+// or the same layout in yaml or toml. A json file holding a single
+// registry.Cluster is still accepted, its cluster is returned whatever
+// the watched service name is.
+type document struct {
+	Clusters []*registry.Cluster `json:"clusters" toml:"clusters"`
+	Manual   map[string]string   `json:"manual" toml:"manual"`
+
+	// single cluster layout
+	Name      string              `json:"name" toml:"name"`
+	Endpoints []registry.Endpoint `json:"endpoints" toml:"endpoints"`
+
+	single bool
+}
+
+func unmarshalFunc(path string) func([]byte, interface{}) error {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return yaml.Unmarshal
+	case ".toml":
+		return toml.Unmarshal
+	default:
+		return json.Unmarshal
+	}
+}
+
+func readDocument(path string) (*document, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var doc document
+	if err = unmarshalFunc(path)(bytes.TrimSpace(data), &doc); err != nil {
+		return nil, err
+	}
+	if len(doc.Clusters) == 0 && (len(doc.Name) != 0 || len(doc.Endpoints) != 0) {
+		doc.single = true
+		doc.Clusters = []*registry.Cluster{{Name: doc.Name, Endpoints: doc.Endpoints}}
+	}
+	doc.Name, doc.Endpoints = "", nil
+	if err = doc.validate(); err != nil {
+		return nil, err
+	}
+	return &doc, nil
+}
+
+// validate checks the clusters of the document and fills in missing endpoint ids.
+func (doc *document) validate() error {
+	names := make(map[string]bool, len(doc.Clusters))
+	for i, cluster := range doc.Clusters {
+		if cluster == nil {
+			return fmt.Errorf("cluster #%d is empty", i)
+		}
+		if !doc.single {
+			if len(cluster.Name) == 0 {
+				return fmt.Errorf("cluster #%d has no name", i)
+			}
+			if names[cluster.Name] {
+				return fmt.Errorf("cluster %q is duplicated", cluster.Name)
+			}
+			names[cluster.Name] = true
+		}
+		ids := make(map[string]bool, len(cluster.Endpoints))
+		for j := range cluster.Endpoints {
+			end := &cluster.Endpoints[j]
+			if len(end.Addr) == 0 {
+				return fmt.Errorf("cluster %q endpoint #%d has no addr", cluster.Name, j)
+			}
+			if end.Port <= 0 || end.Port > 65535 {
+				return fmt.Errorf("cluster %q endpoint %s has invalid port %d", cluster.Name, end.Addr, end.Port)
+			}
+			if len(end.ID) == 0 {
+				end.ID = end.Addr + ":" + strconv.Itoa(end.Port)
+			}
+			if ids[end.ID] {
+				return fmt.Errorf("cluster %q endpoint %q is duplicated", cluster.Name, end.ID)
+			}
+			ids[end.ID] = true
+		}
+	}
+	return nil
+}
+
+// cluster returns a copy of the cluster called name.
+func (doc *document) cluster(name string) *registry.Cluster {
+	for _, c := range doc.Clusters {
+		if doc.single || c.Name == name {
+			cluster := &registry.Cluster{Name: c.Name, Endpoints: make([]registry.Endpoint, len(c.Endpoints))}
+			for i, end := range c.Endpoints {
+				end.Tags = append([]string(nil), end.Tags...)
+				cluster.Endpoints[i] = end
+			}
+			cluster.AddEnvTag()
+			return cluster
+		}
+	}
+	return nil
+}
+
+// prefix returns the manual values whose key starts with prefix, nil when there is none.
+func (doc *document) prefix(prefix string) map[string]string {
+	var kvs map[string]string
+	for k, v := range doc.Manual {
+		if !strings.HasPrefix(k, prefix) {
+			continue
+		}
+		if kvs == nil {
+			kvs = make(map[string]string)
+		}
+		kvs[k] = strings.TrimSpace(v)
+	}
+	return kvs
+}
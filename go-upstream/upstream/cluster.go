@@ -39,7 +39,7 @@ func NewCluster(conf config.Cluster, backend registry.Backend) *Cluster {
 	c := &Cluster{
 		name:            conf.Name,
 		registerBackend: backend,
-		checker:         NewHealthChecker(TCP, time.Duration(conf.CheckInterval), conf.UnHealthyThreshold, conf.HealthyThreshold, conf.Name),
+		checker:         newClusterHealthChecker(conf),
 		hostSet:         NewHostSet(nil, nil),
 		conf:            conf,
 		maxWeight:       0,
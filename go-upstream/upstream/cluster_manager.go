@@ -10,6 +10,8 @@ import (
 
 	"github.com/yunfeiyang1916/toolkit/go-upstream/config"
 	"github.com/yunfeiyang1916/toolkit/go-upstream/registry"
+	"github.com/yunfeiyang1916/toolkit/go-upstream/registry/dns"
+	"github.com/yunfeiyang1916/toolkit/go-upstream/registry/etcd"
 	"github.com/yunfeiyang1916/toolkit/go-upstream/registry/file"
 	"github.com/yunfeiyang1916/toolkit/go-upstream/registry/static"
 	"golang.org/x/net/context"
@@ -17,17 +19,21 @@ import (
 
 const (
 	filePrefix = "file://"
+	dnsPrefix  = "dns://"
 )
 
 type ClusterManager struct {
 	mutex    *sync.RWMutex
 	clusters map[string]*Cluster
+	// etcd backends shared by the clusters of the same etcd cluster
+	etcdBackends map[string]registry.Backend
 }
 
 func NewClusterManager() *ClusterManager {
 	return &ClusterManager{
-		mutex:    new(sync.RWMutex),
-		clusters: make(map[string]*Cluster),
+		mutex:        new(sync.RWMutex),
+		clusters:     make(map[string]*Cluster),
+		etcdBackends: make(map[string]registry.Backend),
 	}
 }
 
@@ -47,7 +53,7 @@ func (cm *ClusterManager) InitService(conf config.Cluster) error {
 	}
 	// if backend
 	var backend registry.Backend
-	if conf.EndpointsFrom != "consul" {
+	if conf.EndpointsFrom != "consul" && conf.EndpointsFrom != "etcd" {
 		clusterEndpoints := make([]registry.Endpoint, 0)
 		if len(conf.StaticEndpoints) != 0 {
 			for _, end := range strings.Split(conf.StaticEndpoints, ",") {
@@ -73,11 +79,23 @@ func (cm *ClusterManager) InitService(conf config.Cluster) error {
 			Endpoints: clusterEndpoints,
 		}
 		if strings.HasPrefix(conf.EndpointsFrom, filePrefix) {
-			backend, _ = file.NewBackend(strings.TrimPrefix(conf.EndpointsFrom, filePrefix), clusterHosts[0])
+			backend, _ = file.NewBackendWithConfig(&config.File{Path: strings.TrimPrefix(conf.EndpointsFrom, filePrefix), Logger: logging}, clusterHosts[0])
+		} else if strings.HasPrefix(conf.EndpointsFrom, dnsPrefix) {
+			var err error
+			backend, err = dns.NewBackend(&config.DNS{Target: strings.TrimPrefix(conf.EndpointsFrom, dnsPrefix), Logger: logging})
+			if err != nil {
+				return fmt.Errorf("init service %q dns backend error %s", conf.Name, err)
+			}
 		} else {
 			routers, _ := json.Marshal(clusterHosts)
 			backend, _ = static.NewBackend(string(routers))
 		}
+	} else if conf.EndpointsFrom == "etcd" {
+		var err error
+		backend, err = cm.etcdBackend(conf.Etcd)
+		if err != nil {
+			return fmt.Errorf("init service %q etcd backend error %s", conf.Name, err)
+		}
 	} else {
 		backend = registry.Default
 	}
@@ -86,6 +104,27 @@ func (cm *ClusterManager) InitService(conf config.Cluster) error {
 	return nil
 }
 
+// etcdBackend returns the backend of an etcd cluster, created once per
+// endpoints and prefix, the caller holds the lock.
+func (cm *ClusterManager) etcdBackend(cfg config.Etcd) (registry.Backend, error) {
+	if len(cfg.Endpoints) == 0 {
+		return nil, fmt.Errorf("no etcd endpoints")
+	}
+	key := strings.Join(cfg.Endpoints, ",") + cfg.Prefix
+	if backend, ok := cm.etcdBackends[key]; ok {
+		return backend, nil
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = logging
+	}
+	backend, err := etcd.NewBackend(&cfg)
+	if err != nil {
+		return nil, err
+	}
+	cm.etcdBackends[key] = backend
+	return backend, nil
+}
+
 func (cm *ClusterManager) ChooseHost(ctx context.Context, service string) *Host {
 	cm.mutex.RLock()
 	cluster, ok := cm.clusters[service]
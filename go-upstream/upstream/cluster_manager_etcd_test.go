@@ -0,0 +1,47 @@
+package upstream
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yunfeiyang1916/toolkit/go-upstream/config"
+	"github.com/yunfeiyang1916/toolkit/go-upstream/registry/etcd"
+	"github.com/yunfeiyang1916/toolkit/internal/etcdtest"
+	"golang.org/x/net/context"
+)
+
+func TestClusterManagerEtcd(t *testing.T) {
+	endpoint := etcdtest.Start(t)
+	b, err := etcd.NewBackend(&config.Etcd{Endpoints: []string{endpoint}, TTL: 2})
+	assert.Nil(t, err)
+	reg := config.NewRegister("etcd.service-http", "127.0.0.1", 8080)
+	reg.TagsOverrideCh <- nil
+	assert.Nil(t, b.Register(reg))
+	defer b.Deregister(reg)
+
+	manager := NewClusterManager()
+	cc := config.NewCluster()
+	cc.Name = "etcd.service-http"
+	cc.EndpointsFrom = "etcd"
+	// no etcd, no consul fallback
+	assert.NotNil(t, manager.InitService(cc))
+
+	cc.Etcd = config.Etcd{Endpoints: []string{endpoint}}
+	assert.Nil(t, manager.InitService(cc))
+	cc2 := cc
+	cc2.Name = "etcd.other-http"
+	assert.Nil(t, manager.InitService(cc2))
+	assert.Equal(t, 1, len(manager.etcdBackends))
+
+	for i := 0; ; i++ {
+		if host := manager.ChooseHost(context.Background(), cc.Name); host != nil {
+			assert.Equal(t, "127.0.0.1:8080", host.Address())
+			break
+		}
+		if i > 50 {
+			t.Fatal("no host from etcd")
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
@@ -1,10 +1,15 @@
 package upstream
 
 import (
-	"net"
+	"context"
+	"errors"
+	"math/rand"
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/yunfeiyang1916/toolkit/go-upstream/config"
+	"github.com/yunfeiyang1916/toolkit/metrics"
 )
 
 type HealthTransition int
@@ -28,6 +33,15 @@ const (
 const (
 	HTTP HealthCheckerType = iota
 	TCP
+	GRPC
+	IKIO
+)
+
+const (
+	defaultTCPCheckTimeout = 100 * time.Millisecond
+	defaultCheckTimeout    = time.Second
+
+	healthCheckMetricName = "upstream.health_check"
 )
 
 const (
@@ -44,6 +58,9 @@ const (
 
 type HealthChecker struct {
 	name               string
+	probe              Probe
+	checkTimeout       time.Duration
+	checkJitter        time.Duration
 	checkInterval      time.Duration
 	unHealthyThreshold uint32
 	healthyThreshold   uint32
@@ -62,6 +79,18 @@ func (t ActiveHalthFailureType) String() string {
 	return "Unknown"
 }
 
+func (t HealthCheckerType) String() string {
+	switch t {
+	case HTTP:
+		return ProbeHTTP
+	case GRPC:
+		return ProbeGRPC
+	case IKIO:
+		return ProbeIKIO
+	}
+	return ProbeTCP
+}
+
 func (t HealthTransition) String() string {
 	switch t {
 	case Changed:
@@ -76,8 +105,26 @@ func (t HealthTransition) String() string {
 type HealthCheckCompleteCallback func(*Host, HealthTransition)
 
 func NewHealthChecker(tp HealthCheckerType, interval time.Duration, unHealthyThreshold, healthyThreshold uint32, name string) *HealthChecker {
+	conf := config.HealthCheck{Type: tp.String()}
+	probe, _ := NewProbe(conf)
+	return NewProbeHealthChecker(probe, conf, interval, unHealthyThreshold, healthyThreshold, name)
+}
+
+// NewProbeHealthChecker creates a checker which checks hosts with probe,
+// the timeout and the interval jitter are taken from conf.
+func NewProbeHealthChecker(probe Probe, conf config.HealthCheck, interval time.Duration, unHealthyThreshold, healthyThreshold uint32, name string) *HealthChecker {
+	timeout := time.Duration(conf.Timeout)
+	if timeout <= 0 {
+		timeout = defaultCheckTimeout
+		if len(conf.Type) == 0 || strings.EqualFold(conf.Type, ProbeTCP) {
+			timeout = defaultTCPCheckTimeout
+		}
+	}
 	return &HealthChecker{
 		name:               name,
+		probe:              probe,
+		checkTimeout:       timeout,
+		checkJitter:        time.Duration(conf.Jitter),
 		checkInterval:      interval,
 		unHealthyThreshold: unHealthyThreshold,
 		healthyThreshold:   healthyThreshold,
@@ -127,12 +174,45 @@ func (hc *HealthChecker) onStateChange(h *Host, state HealthTransition) {
 	}
 }
 
+// newClusterHealthChecker creates the checker described by the health_check
+// section of the cluster config, falling back to a tcp checker on error.
+func newClusterHealthChecker(conf config.Cluster) *HealthChecker {
+	probe, err := NewProbe(conf.HealthCheck)
+	if err != nil {
+		logging.Errorf("cluster %q create health check probe error %s, use tcp probe", conf.Name, err)
+		conf.HealthCheck = config.HealthCheck{Type: ProbeTCP, Jitter: conf.HealthCheck.Jitter}
+		probe, _ = NewProbe(conf.HealthCheck)
+	}
+	return NewProbeHealthChecker(probe, conf.HealthCheck, time.Duration(conf.CheckInterval), conf.UnHealthyThreshold, conf.HealthyThreshold, conf.Name)
+}
+
+// nextInterval returns the check interval plus a random jitter.
+func (hc *HealthChecker) nextInterval() time.Duration {
+	if hc.checkJitter <= 0 {
+		return hc.checkInterval
+	}
+	return hc.checkInterval + time.Duration(rand.Int63n(int64(hc.checkJitter)))
+}
+
+// check probes h and reports the check latency. Every probe error is counted
+// against the unhealthy threshold, so a single failed check doesn't eject
+// the host.
 func (hc *HealthChecker) check(h *Host, session *ActiveHealthCheckSession) {
 	address := h.Address()
-	conn, err := net.DialTimeout("tcp", address, 100*time.Millisecond)
+	ctx, cancel := context.WithTimeout(context.Background(), hc.checkTimeout)
+	start := time.Now()
+	err := hc.probe.Check(ctx, address)
+	latency := time.Since(start)
+	cancel()
+	h.SetCheckLatency(latency)
+	code := 0
 	if err != nil {
-		errUpper := strings.ToUpper(err.Error())
-		if strings.Contains(errUpper, "TIMEOUT") {
+		code = 1
+	}
+	metrics.TimerDuration(healthCheckMetricName, latency, "cluster", hc.name, metrics.TagCode, code)
+	if err != nil {
+		logging.Debugf("%s checker host %s probe error %s", hc.name, address, err)
+		if errors.Is(err, context.DeadlineExceeded) || strings.Contains(strings.ToUpper(err.Error()), "TIMEOUT") {
 			h.SetActiveHealthFailureType(Timeout)
 		} else {
 			h.SetActiveHealthFailureType(UnHealthy)
@@ -140,7 +220,6 @@ func (hc *HealthChecker) check(h *Host, session *ActiveHealthCheckSession) {
 		session.handleFailure(NETWORK)
 		return
 	}
-	conn.Close()
 	session.handleSuccess()
 	h.SetActiveHealthFailureType(Unknown)
 }
@@ -170,17 +249,17 @@ func newActiveHealthCheckSession(host *Host, checker *HealthChecker) *ActiveHeal
 
 func (ahcs *ActiveHealthCheckSession) Start() {
 	go func() {
+		// fire check imediatilly
 		ahcs.checker.check(ahcs.host, ahcs)
-		ticker := time.NewTicker(ahcs.checker.checkInterval)
-		defer ticker.Stop()
-		// fire ticker imediatilly
-		ahcs.checker.check(ahcs.host, ahcs)
+		timer := time.NewTimer(ahcs.checker.nextInterval())
+		defer timer.Stop()
 		for {
 			select {
 			case <-ahcs.exit:
 				return
-			case <-ticker.C:
+			case <-timer.C:
 				ahcs.checker.check(ahcs.host, ahcs)
+				timer.Reset(ahcs.checker.nextInterval())
 			}
 		}
 	}()
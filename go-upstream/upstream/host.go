@@ -3,6 +3,7 @@ package upstream
 import (
 	"math/bits"
 	"sync/atomic"
+	"time"
 )
 
 type Host struct {
@@ -11,6 +12,7 @@ type Host struct {
 	weight                  uint32
 	used                    uint32
 	activeHealthFailureType uint32
+	checkLatency            int64
 	meta                    atomic.Value
 	detector                atomic.Value
 }
@@ -71,6 +73,16 @@ func (h *Host) SetActiveHealthFailureType(tp ActiveHalthFailureType) {
 	atomic.StoreUint32(&h.activeHealthFailureType, uint32(tp))
 
 }
+
+// CheckLatency returns the latency of the last active health check.
+func (h *Host) CheckLatency() time.Duration {
+	return time.Duration(atomic.LoadInt64(&h.checkLatency))
+}
+
+func (h *Host) SetCheckLatency(latency time.Duration) {
+	atomic.StoreInt64(&h.checkLatency, int64(latency))
+}
+
 func (h *Host) Weight() (weight uint32) {
 	weight = atomic.LoadUint32(&h.weight)
 	return
@@ -0,0 +1,182 @@
+package upstream
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/yunfeiyang1916/toolkit/go-upstream/config"
+)
+
+const (
+	ProbeTCP  = "tcp"
+	ProbeHTTP = "http"
+	ProbeGRPC = "grpc"
+	ProbeIKIO = "ikio"
+
+	// maxProbeBodySize limits the response body read by the http probe
+	maxProbeBodySize = 64 * 1024
+)
+
+// Probe checks the health of a single host, a nil error means the host is
+// healthy. The context carries the check timeout.
+type Probe interface {
+	Check(ctx context.Context, address string) error
+}
+
+// ProbeFunc is an adapter to use ordinary functions as probes.
+type ProbeFunc func(ctx context.Context, address string) error
+
+func (f ProbeFunc) Check(ctx context.Context, address string) error {
+	return f(ctx, address)
+}
+
+// ProbeFactory creates the probe of a cluster from its health check config.
+type ProbeFactory func(conf config.HealthCheck) (Probe, error)
+
+var (
+	probesMu sync.RWMutex
+	probes   = map[string]ProbeFactory{
+		ProbeTCP:  func(config.HealthCheck) (Probe, error) { return ProbeFunc(tcpProbe), nil },
+		ProbeHTTP: newHTTPProbe,
+		ProbeGRPC: newGRPCProbe,
+		ProbeIKIO: func(config.HealthCheck) (Probe, error) { return ProbeFunc(ikioProbe), nil },
+	}
+)
+
+// RegisterProbe makes a probe available by name to the health_check type of
+// cluster configs, an already registered probe of the same name is replaced.
+func RegisterProbe(name string, factory ProbeFactory) {
+	probesMu.Lock()
+	probes[strings.ToLower(name)] = factory
+	probesMu.Unlock()
+}
+
+// NewProbe creates the probe described by conf, a tcp probe when no type is set.
+func NewProbe(conf config.HealthCheck) (Probe, error) {
+	tp := strings.ToLower(conf.Type)
+	if len(tp) == 0 {
+		tp = ProbeTCP
+	}
+	probesMu.RLock()
+	factory, ok := probes[tp]
+	probesMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown health check type %q", conf.Type)
+	}
+	return factory(conf)
+}
+
+func tcpProbe(ctx context.Context, address string) error {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+type statusRange struct {
+	min, max int
+}
+
+type httpProbe struct {
+	client   *http.Client
+	path     string
+	method   string
+	statuses []statusRange
+	body     []byte
+	headers  map[string]string
+}
+
+func newHTTPProbe(conf config.HealthCheck) (Probe, error) {
+	p := &httpProbe{
+		client:  &http.Client{Transport: &http.Transport{DisableKeepAlives: true}},
+		path:    conf.Path,
+		method:  strings.ToUpper(conf.Method),
+		body:    []byte(conf.BodyContains),
+		headers: conf.Headers,
+	}
+	if !strings.HasPrefix(p.path, "/") {
+		p.path = "/" + p.path
+	}
+	if len(p.method) == 0 {
+		p.method = http.MethodGet
+	}
+	for _, s := range conf.ExpectedStatuses {
+		r, err := parseStatusRange(s)
+		if err != nil {
+			return nil, err
+		}
+		p.statuses = append(p.statuses, r)
+	}
+	if len(p.statuses) == 0 {
+		p.statuses = []statusRange{{200, 299}}
+	}
+	return p, nil
+}
+
+func parseStatusRange(s string) (statusRange, error) {
+	parts := strings.SplitN(strings.TrimSpace(s), "-", 2)
+	min, err := strconv.Atoi(strings.TrimSpace(parts[0]))
+	if err != nil {
+		return statusRange{}, fmt.Errorf("invalid expected status %q", s)
+	}
+	max := min
+	if len(parts) == 2 {
+		if max, err = strconv.Atoi(strings.TrimSpace(parts[1])); err != nil || max < min {
+			return statusRange{}, fmt.Errorf("invalid expected status %q", s)
+		}
+	}
+	return statusRange{min, max}, nil
+}
+
+func (p *httpProbe) Check(ctx context.Context, address string) error {
+	req, err := http.NewRequest(p.method, "http://"+address+p.path, nil)
+	if err != nil {
+		return err
+	}
+	for k, v := range p.headers {
+		if strings.EqualFold(k, "Host") {
+			req.Host = v
+			continue
+		}
+		req.Header.Set(k, v)
+	}
+	resp, err := p.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if !p.expectedStatus(resp.StatusCode) {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	if len(p.body) == 0 {
+		io.Copy(ioutil.Discard, io.LimitReader(resp.Body, maxProbeBodySize))
+		return nil
+	}
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxProbeBodySize))
+	if err != nil {
+		return err
+	}
+	if !bytes.Contains(body, p.body) {
+		return fmt.Errorf("response body does not contain %q", p.body)
+	}
+	return nil
+}
+
+func (p *httpProbe) expectedStatus(code int) bool {
+	for _, r := range p.statuses {
+		if code >= r.min && code <= r.max {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,35 @@
+package upstream
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/yunfeiyang1916/toolkit/go-upstream/config"
+	"google.golang.org/grpc"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// grpcProbe checks hosts with the grpc health checking protocol.
+type grpcProbe struct {
+	service string
+}
+
+func newGRPCProbe(conf config.HealthCheck) (Probe, error) {
+	return &grpcProbe{service: conf.GRPCService}, nil
+}
+
+func (p *grpcProbe) Check(ctx context.Context, address string) error {
+	conn, err := grpc.DialContext(ctx, address, grpc.WithInsecure(), grpc.WithBlock())
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	resp, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{Service: p.service})
+	if err != nil {
+		return err
+	}
+	if resp.Status != healthpb.HealthCheckResponse_SERVING {
+		return fmt.Errorf("grpc service %q status %s", p.service, resp.Status)
+	}
+	return nil
+}
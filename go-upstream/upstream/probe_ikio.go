@@ -0,0 +1,68 @@
+package upstream
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"math/bits"
+	"net"
+
+	"github.com/yunfeiyang1916/toolkit/ikio"
+)
+
+// ikioNegoMagic is the magic of the negotiation packet of the binary rpc.
+const ikioNegoMagic = 0x4D4F4341
+
+// ikioProbe pings a binary rpc server: it negotiates the connection and
+// sends a hint packet, which the server echoes back.
+func ikioProbe(ctx context.Context, address string) error {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	codec := &ikio.RPCCodec{}
+	r := bufio.NewReader(conn)
+	if _, err = codec.Encode(&ikio.RPCNegoPacket{Magic: ikioNegoMagic}, conn); err != nil {
+		return err
+	}
+	p, err := codec.Decode(r)
+	if err != nil {
+		return err
+	}
+	nego, ok := p.(*ikio.RPCNegoPacket)
+	if !ok {
+		return fmt.Errorf("ikio unexpected negotiation packet type %d", p.Type())
+	}
+	// the codec is big endian, a server writing in little endian has the
+	// magic swapped and its packet headers must be swapped back
+	swapped := nego.Magic == bits.ReverseBytes32(ikioNegoMagic)
+	if !swapped && nego.Magic != ikioNegoMagic {
+		return fmt.Errorf("ikio bad negotiation magic %x", nego.Magic)
+	}
+
+	if _, err = codec.Encode(&ikio.RPCPacket{Tp: ikio.PacketTypeHint}, conn); err != nil {
+		return err
+	}
+	if p, err = codec.Decode(r); err != nil {
+		return err
+	}
+	hint, ok := p.(*ikio.RPCPacket)
+	if !ok {
+		return fmt.Errorf("ikio unexpected ping reply type %d", p.Type())
+	}
+	defer ikio.PutRPCPacket(hint)
+	tp := hint.Tp
+	if swapped {
+		tp = int32(bits.ReverseBytes32(uint32(hint.Flags<<8|hint.Tp)) & 0xFF)
+	}
+	if tp != ikio.PacketTypeHint {
+		return fmt.Errorf("ikio unexpected ping reply type %d", tp)
+	}
+	return nil
+}
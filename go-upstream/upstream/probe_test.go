@@ -0,0 +1,182 @@
+package upstream
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yunfeiyang1916/toolkit/go-upstream/config"
+	"github.com/yunfeiyang1916/toolkit/ikio"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func checkProbe(p Probe, address string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	return p.Check(ctx, address)
+}
+
+func TestHTTPProbe(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodHead && r.Header.Get("X-Check") != "1" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		switch r.URL.Path {
+		case "/health":
+			w.Write([]byte(`{"status": "ok"}`))
+		case "/moved":
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer ts.Close()
+	address := strings.TrimPrefix(ts.URL, "http://")
+
+	tests := []struct {
+		conf config.HealthCheck
+		ok   bool
+	}{
+		{config.HealthCheck{Path: "/health", Headers: map[string]string{"X-Check": "1"}}, true},
+		{config.HealthCheck{Path: "health", Headers: map[string]string{"X-Check": "1"}, BodyContains: `"ok"`}, true},
+		{config.HealthCheck{Path: "/health", Headers: map[string]string{"X-Check": "1"}, BodyContains: "fail"}, false},
+		{config.HealthCheck{Path: "/health"}, false},
+		{config.HealthCheck{Path: "/health", ExpectedStatuses: []string{"403"}}, true},
+		{config.HealthCheck{Path: "/moved", Method: "head", ExpectedStatuses: []string{"200", "204-206"}}, true},
+		{config.HealthCheck{Path: "/down", Headers: map[string]string{"X-Check": "1"}}, false},
+		{config.HealthCheck{Path: "/down", Headers: map[string]string{"X-Check": "1"}, ExpectedStatuses: []string{"500-599"}}, true},
+	}
+	for _, tt := range tests {
+		tt.conf.Type = ProbeHTTP
+		p, err := NewProbe(tt.conf)
+		assert.Nil(t, err)
+		err = checkProbe(p, address)
+		assert.Equal(t, tt.ok, err == nil, "%+v: %v", tt.conf, err)
+	}
+
+	_, err := NewProbe(config.HealthCheck{Type: ProbeHTTP, ExpectedStatuses: []string{"299-200"}})
+	assert.NotNil(t, err)
+}
+
+func TestGRPCProbe(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	srv := grpc.NewServer()
+	hs := health.NewServer()
+	healthpb.RegisterHealthServer(srv, hs)
+	go srv.Serve(ln)
+	defer srv.Stop()
+
+	p, err := NewProbe(config.HealthCheck{Type: ProbeGRPC, GRPCService: "app.service"})
+	assert.Nil(t, err)
+
+	hs.SetServingStatus("app.service", healthpb.HealthCheckResponse_SERVING)
+	assert.Nil(t, checkProbe(p, ln.Addr().String()))
+	hs.SetServingStatus("app.service", healthpb.HealthCheckResponse_NOT_SERVING)
+	assert.NotNil(t, checkProbe(p, ln.Addr().String()))
+}
+
+// startIKIOServer starts a binary rpc server which negotiates the connections
+// and echoes the hint packets, the packets written are wrapped by wrap, it
+// returns its address.
+func startIKIOServer(t *testing.T, wrap func(ikio.Packet) ikio.Packet) string {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	s := ikio.NewServer(
+		ikio.CustomCodecOption(func() ikio.Codec { return &ikio.RPCCodec{} }),
+		ikio.OnConnectOption(func(wc ikio.WriteCloser) bool {
+			_, err := wc.Write(context.TODO(), wrap(&ikio.RPCNegoPacket{Magic: ikioNegoMagic}))
+			return err == nil
+		}),
+	)
+	s.Register(ikio.PacketTypeHint, func(ctx context.Context, wc ikio.WriteCloser) {
+		wc.Write(ctx, wrap(ikio.MessageFromContext(ctx)))
+	}, ikio.HandlePooledRandom)
+	go s.Start(ln)
+	t.Cleanup(s.Stop)
+	return ln.Addr().String()
+}
+
+// littleEndianPacket is serialized in little endian, like the packets of the
+// binary rpc server in the native order of amd64.
+type littleEndianPacket struct {
+	ikio.Packet
+}
+
+func (p littleEndianPacket) Serialize() ([]byte, error) {
+	b, err := p.Packet.Serialize()
+	if err != nil {
+		return nil, err
+	}
+	// the magic, flags and peer id length of a negotiation, the id, code,
+	// type, header and payload length of a packet
+	words := []int{4, 4, 4}
+	if _, ok := p.Packet.(*ikio.RPCPacket); ok {
+		words = []int{8, 4, 4, 4, 4}
+	}
+	for i, n := 0, 0; n < len(words); i, n = i+words[n], n+1 {
+		for l, r := i, i+words[n]-1; l < r; l, r = l+1, r-1 {
+			b[l], b[r] = b[r], b[l]
+		}
+	}
+	return b, nil
+}
+
+func TestIKIOProbe(t *testing.T) {
+	p, err := NewProbe(config.HealthCheck{Type: ProbeIKIO})
+	assert.Nil(t, err)
+
+	addr := startIKIOServer(t, func(p ikio.Packet) ikio.Packet { return p })
+	assert.Nil(t, checkProbe(p, addr))
+
+	addr = startIKIOServer(t, func(p ikio.Packet) ikio.Packet { return littleEndianPacket{p} })
+	assert.Nil(t, checkProbe(p, addr))
+}
+
+func TestRegisterProbe(t *testing.T) {
+	errDown := errors.New("down")
+	healthy := true
+	RegisterProbe("custom", func(conf config.HealthCheck) (Probe, error) {
+		return ProbeFunc(func(ctx context.Context, address string) error {
+			time.Sleep(10 * time.Millisecond)
+			if healthy {
+				return nil
+			}
+			return errDown
+		}), nil
+	})
+	_, err := NewProbe(config.HealthCheck{Type: "unknown"})
+	assert.NotNil(t, err)
+
+	conf := config.NewCluster()
+	conf.Name = "custom"
+	conf.HealthCheck = config.HealthCheck{Type: "Custom", Jitter: config.Duration(time.Millisecond)}
+	conf.UnHealthyThreshold = 2
+	hc := newClusterHealthChecker(conf)
+	assert.Equal(t, time.Second, hc.checkTimeout)
+	interval := hc.nextInterval()
+	assert.True(t, interval >= hc.checkInterval && interval < hc.checkInterval+time.Millisecond)
+
+	h := NewHost("127.0.0.1:1", 100, nil)
+	session := newActiveHealthCheckSession(h, hc)
+	hc.check(h, session)
+	assert.True(t, h.Healthy())
+	assert.True(t, h.CheckLatency() >= 10*time.Millisecond)
+
+	// the probe errors count against the unhealthy threshold
+	healthy = false
+	hc.check(h, session)
+	assert.True(t, h.Healthy())
+	hc.check(h, session)
+	assert.False(t, h.Healthy())
+	assert.Equal(t, UnHealthy, h.GetActiveHealthFailureType())
+}
@@ -59,6 +59,9 @@ type channelOption struct {
 	waitGroup     *sync.WaitGroup
 	timingWheel   *timer.TimingWheel
 	getHandleFunc func(int32) (HandlerFunc, HandlePoolType)
+	// the rate of the messages read, shared by the connections of a remote ip
+	messageBucket *bucket
+	onThrottled   func()
 }
 
 // MessageHandler is a combination of message and its handler function.
@@ -284,16 +287,26 @@ func (c *Channel) readLoop(wg *sync.WaitGroup) {
 		default:
 			msg, err := c.codec.Decode(c.reader)
 			now := time.Now()
-			if err != nil {
-				if _, ok := err.(ErrUndefined); ok {
-					// update last active
-					c.SetLastActive(now.UnixNano())
-					continue
-				}
+			_, undefined := err.(ErrUndefined)
+			if err != nil && !undefined {
 				c.logger.Warnf("read abort, closing conn #%d, local %v, remote %v, decode packet error %v", c.connID, c.conn.LocalAddr(), c.conn.RemoteAddr(), err)
 				return
 			}
-			c.SetLastActive(time.Now().UnixNano())
+			// update last active
+			c.SetLastActive(now.UnixNano())
+			// the undefined frames take a token too, or they would not be throttled
+			if c.opts.messageBucket != nil {
+				waited, err := c.opts.messageBucket.wait(c.ctx)
+				if waited && c.opts.onThrottled != nil {
+					c.opts.onThrottled()
+				}
+				if err != nil {
+					return
+				}
+			}
+			if undefined {
+				continue
+			}
 			var handler HandlerFunc
 			handlerType := HandlePooledRandom
 			if c.opts.getHandleFunc != nil {
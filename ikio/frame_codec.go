@@ -0,0 +1,234 @@
+package ikio
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"sync"
+)
+
+// Errors of the framing codecs.
+var (
+	ErrFrameTooLarge = errors.New("frame too large")
+	ErrFramePrefix   = errors.New("unknown frame length prefix")
+)
+
+// LengthPrefix is how the length of a frame is written before it.
+type LengthPrefix int
+
+// The length prefixes.
+const (
+	Uint32BigEndian LengthPrefix = iota
+	Uint16BigEndian
+	Uint32LittleEndian
+	Uint16LittleEndian
+	Uvarint
+)
+
+// the max frame size by default, also bounded by the prefix
+const defaultMaxFrameSize = 4 << 20
+
+// Framing is a length prefixed framing of the packets, the frames are
+// decoded by its codec as FramePackets:
+//
+//	f := ikio.Framing{Prefix: ikio.Uint16BigEndian}
+//	s := ikio.NewServer(ikio.CustomCodecOption(f.NewCodec), ...)
+//	wc.Write(ctx, f.Packet(payload))
+type Framing struct {
+	Prefix LengthPrefix
+	// MaxFrameSize is the max size of the frames without their prefix, 4MB by
+	// default. The frames beyond it fail to decode, which closes the
+	// connection.
+	MaxFrameSize int
+}
+
+func (f Framing) maxFrameSize() int {
+	max := f.MaxFrameSize
+	if max <= 0 {
+		max = defaultMaxFrameSize
+	}
+	if (f.Prefix == Uint16BigEndian || f.Prefix == Uint16LittleEndian) && max > 0xFFFF {
+		max = 0xFFFF
+	}
+	return max
+}
+
+// Frame returns payload prefixed by its length.
+func (f Framing) Frame(payload []byte) ([]byte, error) {
+	n := len(payload)
+	if n > f.maxFrameSize() {
+		return nil, ErrFrameTooLarge
+	}
+	var frame []byte
+	switch f.Prefix {
+	case Uint32BigEndian:
+		frame = make([]byte, 4, 4+n)
+		binary.BigEndian.PutUint32(frame, uint32(n))
+	case Uint16BigEndian:
+		frame = make([]byte, 2, 2+n)
+		binary.BigEndian.PutUint16(frame, uint16(n))
+	case Uint32LittleEndian:
+		frame = make([]byte, 4, 4+n)
+		binary.LittleEndian.PutUint32(frame, uint32(n))
+	case Uint16LittleEndian:
+		frame = make([]byte, 2, 2+n)
+		binary.LittleEndian.PutUint16(frame, uint16(n))
+	case Uvarint:
+		frame = make([]byte, binary.MaxVarintLen64, binary.MaxVarintLen64+n)
+		frame = frame[:binary.PutUvarint(frame, uint64(n))]
+	default:
+		return nil, ErrFramePrefix
+	}
+	return append(frame, payload...), nil
+}
+
+// ReadFrame reads a frame, it returns its payload.
+func (f Framing) ReadFrame(reader *bufio.Reader) ([]byte, error) {
+	var (
+		n   uint64
+		err error
+	)
+	switch f.Prefix {
+	case Uint32BigEndian, Uint32LittleEndian, Uint16BigEndian, Uint16LittleEndian:
+		size := 4
+		if f.Prefix == Uint16BigEndian || f.Prefix == Uint16LittleEndian {
+			size = 2
+		}
+		var prefix [4]byte
+		if _, err = io.ReadFull(reader, prefix[:size]); err != nil {
+			return nil, err
+		}
+		switch f.Prefix {
+		case Uint32BigEndian:
+			n = uint64(binary.BigEndian.Uint32(prefix[:]))
+		case Uint16BigEndian:
+			n = uint64(binary.BigEndian.Uint16(prefix[:]))
+		case Uint32LittleEndian:
+			n = uint64(binary.LittleEndian.Uint32(prefix[:]))
+		default:
+			n = uint64(binary.LittleEndian.Uint16(prefix[:]))
+		}
+	case Uvarint:
+		if n, err = binary.ReadUvarint(reader); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, ErrFramePrefix
+	}
+	if n > uint64(f.maxFrameSize()) {
+		return nil, ErrFrameTooLarge
+	}
+	payload := make([]byte, n)
+	if _, err = io.ReadFull(reader, payload); err != nil {
+		return nil, err
+	}
+	return payload, nil
+}
+
+// NewCodec returns a codec of the frames, for CustomCodecOption.
+func (f Framing) NewCodec() Codec {
+	return &FrameCodec{Framing: f}
+}
+
+// Packet returns a packet of payload, written as a frame.
+func (f Framing) Packet(payload []byte) *FramePacket {
+	return &FramePacket{Payload: payload, framing: f}
+}
+
+// FramePacket is a frame, of type 0.
+type FramePacket struct {
+	Payload []byte
+	framing Framing
+}
+
+func (fp *FramePacket) Type() int32 {
+	return 0
+}
+
+func (fp *FramePacket) Serialize() ([]byte, error) {
+	return fp.framing.Frame(fp.Payload)
+}
+
+// FrameCodec decodes the frames of a Framing as FramePackets.
+type FrameCodec struct {
+	Framing
+}
+
+func (fc *FrameCodec) Encode(p Packet, writer io.Writer) (int, error) {
+	data, err := p.Serialize()
+	if err != nil {
+		return 0, err
+	}
+	if _, ok := p.(*FramePacket); !ok {
+		if data, err = fc.Frame(data); err != nil {
+			return 0, err
+		}
+	}
+	return writer.Write(data)
+}
+
+func (fc *FrameCodec) Decode(reader *bufio.Reader) (Packet, error) {
+	payload, err := fc.ReadFrame(reader)
+	if err != nil {
+		return nil, err
+	}
+	return fc.Packet(payload), nil
+}
+
+// NegotiatedCodec returns a codec chosen by the first n bytes a connection
+// reads, which are left to the codec, so a port may serve several protocols.
+// choose returns nil for the bytes of no protocol, which closes the
+// connection. Nothing can be written before the codec is chosen, Encode
+// returns ErrCodecNotNegotiated until the first Decode.
+func NegotiatedCodec(n int, choose func(prefix []byte) Codec) func() Codec {
+	return func() Codec {
+		return &negotiatedCodec{n: n, choose: choose}
+	}
+}
+
+// Errors of the negotiated codecs.
+var (
+	// ErrCodecNegotiation is the error of the bytes of no protocol.
+	ErrCodecNegotiation = errors.New("codec negotiation fail")
+	// ErrCodecNotNegotiated is the error of a write before the codec is chosen.
+	ErrCodecNotNegotiated = errors.New("codec not negotiated")
+)
+
+type negotiatedCodec struct {
+	n      int
+	choose func([]byte) Codec
+	mu     sync.RWMutex
+	codec  Codec // chosen by the read goroutine, used by the write one
+}
+
+func (nc *negotiatedCodec) chosen() Codec {
+	nc.mu.RLock()
+	defer nc.mu.RUnlock()
+	return nc.codec
+}
+
+func (nc *negotiatedCodec) Encode(p Packet, writer io.Writer) (int, error) {
+	codec := nc.chosen()
+	if codec == nil {
+		return 0, ErrCodecNotNegotiated
+	}
+	return codec.Encode(p, writer)
+}
+
+func (nc *negotiatedCodec) Decode(reader *bufio.Reader) (Packet, error) {
+	codec := nc.chosen()
+	if codec == nil {
+		prefix, err := reader.Peek(nc.n)
+		if err != nil {
+			return nil, err
+		}
+		if codec = nc.choose(prefix); codec == nil {
+			return nil, ErrCodecNegotiation
+		}
+		nc.mu.Lock()
+		nc.codec = codec
+		nc.mu.Unlock()
+	}
+	return codec.Decode(reader)
+}
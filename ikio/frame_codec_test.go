@@ -0,0 +1,99 @@
+package ikio
+
+import (
+	"bufio"
+	"bytes"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes/wrappers"
+)
+
+var framings = []Framing{
+	{Prefix: Uint32BigEndian},
+	{Prefix: Uint16BigEndian},
+	{Prefix: Uint32LittleEndian},
+	{Prefix: Uint16LittleEndian},
+	{Prefix: Uvarint},
+	{Prefix: Uvarint, MaxFrameSize: 8},
+}
+
+func TestFrameCodec(t *testing.T) {
+	for _, f := range framings {
+		var buf bytes.Buffer
+		payloads := [][]byte{{}, []byte("hello"), bytes.Repeat([]byte{'x'}, 300)}
+		for _, p := range payloads {
+			if _, err := f.NewCodec().Encode(f.Packet(p), &buf); err != nil && (len(p) <= 8 || f.MaxFrameSize == 0) {
+				t.Fatalf("%+v: %v", f, err)
+			}
+		}
+		c := f.NewCodec()
+		r := bufio.NewReader(&buf)
+		for _, p := range payloads {
+			if f.MaxFrameSize != 0 && len(p) > f.MaxFrameSize {
+				continue
+			}
+			pkt, err := c.Decode(r)
+			if err != nil || !bytes.Equal(pkt.(*FramePacket).Payload, p) {
+				t.Fatalf("%+v: got %v, %v", f, pkt, err)
+			}
+		}
+	}
+
+	// the size is checked before the frame is read
+	f := Framing{MaxFrameSize: 4}
+	if _, err := f.NewCodec().Decode(bufio.NewReader(bytes.NewReader([]byte{0, 0, 0, 5}))); err != ErrFrameTooLarge {
+		t.Fatalf("got %v", err)
+	}
+}
+
+func TestProtoCodec(t *testing.T) {
+	pf := NewProtoFraming(Framing{Prefix: Uvarint})
+	pf.Register(1, func() proto.Message { return &wrappers.StringValue{} })
+
+	var buf bytes.Buffer
+	c := pf.NewCodec()
+	for _, p := range []*ProtoPacket{
+		pf.Packet(2, &wrappers.StringValue{Value: "skipped"}),
+		pf.Packet(1, &wrappers.StringValue{Value: "ping"}),
+	} {
+		if _, err := c.Encode(p, &buf); err != nil {
+			t.Fatal(err)
+		}
+	}
+	r := bufio.NewReader(&buf)
+	if _, err := c.Decode(r); err != ErrUndefined(2) {
+		t.Fatalf("got %v", err)
+	}
+	pkt, err := c.Decode(r)
+	if err != nil || pkt.Type() != 1 || pkt.(*ProtoPacket).Message.(*wrappers.StringValue).Value != "ping" {
+		t.Fatalf("got %v, %v", pkt, err)
+	}
+}
+
+func TestNegotiatedCodec(t *testing.T) {
+	f := Framing{Prefix: Uint16BigEndian}
+	newCodec := NegotiatedCodec(1, func(prefix []byte) Codec {
+		if prefix[0] == 0 {
+			return f.NewCodec()
+		}
+		return &LineCodec{}
+	})
+
+	c := newCodec()
+	var buf bytes.Buffer
+	if _, err := c.Encode(f.Packet([]byte("hi")), &buf); err != ErrCodecNotNegotiated {
+		t.Fatalf("write before negotiation, got %v", err)
+	}
+	pkt, err := c.Decode(bufio.NewReader(bytes.NewReader([]byte{0, 2, 'h', 'i'})))
+	if err != nil || string(pkt.(*FramePacket).Payload) != "hi" {
+		t.Fatalf("got %v, %v", pkt, err)
+	}
+	if _, err := c.Encode(pkt, &buf); err != nil || !bytes.Equal(buf.Bytes(), []byte{0, 2, 'h', 'i'}) {
+		t.Fatalf("got %x, %v", buf.Bytes(), err)
+	}
+	pkt, err = newCodec().Decode(bufio.NewReader(bytes.NewBufferString("hi\r\n")))
+	if err != nil || string(pkt.(*LinePacket).Payload) != "hi" {
+		t.Fatalf("got %v, %v", pkt, err)
+	}
+}
@@ -0,0 +1,70 @@
+//go:build go1.18
+// +build go1.18
+
+package ikio
+
+import (
+	"bufio"
+	"bytes"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes/wrappers"
+)
+
+// FuzzFrameCodec decodes any input: the frames decoded are encoded back as the
+// bytes read, and nothing beyond the max frame size is allocated.
+func FuzzFrameCodec(f *testing.F) {
+	f.Add(byte(Uint32BigEndian), []byte{0, 0, 0, 2, 'h', 'i'})
+	f.Add(byte(Uint16LittleEndian), []byte{2, 0, 'h', 'i', 1})
+	f.Add(byte(Uvarint), []byte{0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x01})
+	f.Fuzz(func(t *testing.T, prefix byte, data []byte) {
+		framing := Framing{Prefix: LengthPrefix(prefix % 6), MaxFrameSize: 1 << 10}
+		c := framing.NewCodec()
+		r := bufio.NewReader(bytes.NewReader(data))
+		read := 0
+		for {
+			pkt, err := c.Decode(r)
+			if err != nil {
+				return
+			}
+			frame, err := pkt.Serialize()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(pkt.(*FramePacket).Payload) > 1<<10 {
+				t.Fatalf("a frame of %d bytes", len(pkt.(*FramePacket).Payload))
+			}
+			// a uvarint prefix may be written longer than needed
+			if framing.Prefix != Uvarint && !bytes.Equal(frame, data[read:read+len(frame)]) {
+				t.Fatalf("got %x, read %x", frame, data[read:read+len(frame)])
+			}
+			read += len(frame)
+		}
+	})
+}
+
+// FuzzProtoCodec decodes any input without a panic.
+func FuzzProtoCodec(f *testing.F) {
+	pf := NewProtoFraming(Framing{Prefix: Uvarint, MaxFrameSize: 1 << 10})
+	pf.Register(1, func() proto.Message { return &wrappers.StringValue{} })
+	frame, _ := pf.Packet(1, &wrappers.StringValue{Value: "ping"}).Serialize()
+	f.Add(frame)
+	f.Add([]byte{3, 0x80, 0x80, 0x80})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		c := pf.NewCodec()
+		r := bufio.NewReader(bytes.NewReader(data))
+		for {
+			pkt, err := c.Decode(r)
+			if _, ok := err.(ErrUndefined); ok {
+				continue
+			}
+			if err != nil {
+				return
+			}
+			if _, err := pkt.Serialize(); err != nil {
+				t.Fatal(err)
+			}
+		}
+	})
+}
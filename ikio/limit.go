@@ -0,0 +1,160 @@
+package ikio
+
+import (
+	"errors"
+	"net"
+	"sync"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// The reasons of the connections rejected, passed to the OnRejectOption
+// callback.
+var (
+	ErrTooManyConns      = errors.New("too many connections")
+	ErrTooManyConnsPerIP = errors.New("too many connections of the remote ip")
+)
+
+// bucket is a token bucket of rate tokens per second, at most burst.
+type bucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newBucket(rate float64, burst int) *bucket {
+	if burst < 1 {
+		burst = 1
+	}
+	return &bucket{rate: rate, burst: float64(burst), tokens: float64(burst), last: time.Now()}
+}
+
+// reserve takes a token, it returns how long to wait for it.
+func (b *bucket) reserve() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := time.Now()
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+	b.last = now
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+// wait takes a token, it tells whether it had to wait for it; it returns
+// ctx.Err() if ctx is done first.
+func (b *bucket) wait(ctx context.Context) (bool, error) {
+	d := b.reserve()
+	if d <= 0 {
+		return false, nil
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return true, nil
+	case <-ctx.Done():
+		return true, ctx.Err()
+	}
+}
+
+// the time the message bucket of a remote ip is kept once its connections
+// are closed, so it is not refilled by reconnecting
+const ipIdleTTL = time.Minute
+
+// remoteIP is the state of the connections of a remote ip.
+type remoteIP struct {
+	conns  int
+	bucket *bucket   // the messages of all its connections
+	idle   time.Time // when its last connection closed
+}
+
+// limiter counts the connections, overall and by remote ip.
+type limiter struct {
+	opts *options
+
+	mu    sync.Mutex
+	conns int
+	ips   map[string]*remoteIP
+	swept time.Time
+}
+
+func newLimiter(opts *options) *limiter {
+	return &limiter{opts: opts, ips: make(map[string]*remoteIP)}
+}
+
+// acquire counts a connection of ip, unless a limit is reached. It returns
+// the bucket of the messages of ip, nil without a message rate.
+func (l *limiter) acquire(ip string) (*bucket, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.sweep(time.Now())
+	if l.opts.maxConns > 0 && l.conns >= l.opts.maxConns {
+		return nil, ErrTooManyConns
+	}
+	r := l.ips[ip]
+	if r == nil {
+		r = &remoteIP{}
+		if l.opts.ipMessageRate > 0 {
+			r.bucket = newBucket(l.opts.ipMessageRate, l.opts.ipMessageBurst)
+		}
+		l.ips[ip] = r
+	}
+	if l.opts.maxConnsPerIP > 0 && r.conns >= l.opts.maxConnsPerIP {
+		return nil, ErrTooManyConnsPerIP
+	}
+	r.conns++
+	l.conns++
+	return r.bucket, nil
+}
+
+// release uncounts a connection of ip. The state of ip is kept for
+// ipIdleTTL while it has a bucket.
+func (l *limiter) release(ip string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.conns--
+	if r := l.ips[ip]; r != nil {
+		if r.conns--; r.conns <= 0 {
+			if r.bucket == nil {
+				delete(l.ips, ip)
+			} else {
+				r.idle = time.Now()
+			}
+		}
+	}
+}
+
+// sweep deletes the ips idle for ipIdleTTL, at most once per ipIdleTTL.
+func (l *limiter) sweep(now time.Time) {
+	if now.Sub(l.swept) < ipIdleTTL {
+		return
+	}
+	l.swept = now
+	for ip, r := range l.ips {
+		if r.conns <= 0 && now.Sub(r.idle) >= ipIdleTTL {
+			delete(l.ips, ip)
+		}
+	}
+}
+
+// ipOf returns the ip of a remote address, the address itself if it has no
+// port.
+func ipOf(addr net.Addr) string {
+	if addr == nil {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return addr.String()
+	}
+	return host
+}
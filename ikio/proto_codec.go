@@ -0,0 +1,104 @@
+package ikio
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+
+	"github.com/golang/protobuf/proto"
+)
+
+// ErrProtoType is the error of a frame without a message type.
+var ErrProtoType = errors.New("proto frame without message type")
+
+// ProtoFraming frames protobuf messages, a frame is the uvarint type of its
+// message followed by the marshaled message. The frames are decoded as
+// ProtoPackets of the types registered, whose Type is the message type, so
+// Server.Register dispatches them. A frame of another type is decoded as an
+// ErrUndefined error, the server skips it as its read loop goes on after an
+// ErrUndefined, other callers of Decode must do the same.
+//
+//	pf := ikio.NewProtoFraming(ikio.Framing{Prefix: ikio.Uvarint})
+//	pf.Register(1, func() proto.Message { return &pb.Ping{} })
+//	s := ikio.NewServer(ikio.CustomCodecOption(pf.NewCodec), ...)
+//	wc.Write(ctx, pf.Packet(2, &pb.Pong{}))
+type ProtoFraming struct {
+	Framing
+	types map[int32]func() proto.Message
+}
+
+// NewProtoFraming returns a ProtoFraming with the frames of f.
+func NewProtoFraming(f Framing) *ProtoFraming {
+	return &ProtoFraming{Framing: f, types: make(map[int32]func() proto.Message)}
+}
+
+// Register sets the messages of type tp, it is not safe to call once the
+// codecs are in use.
+func (pf *ProtoFraming) Register(tp int32, newMessage func() proto.Message) {
+	pf.types[tp] = newMessage
+}
+
+// NewCodec returns a codec of the messages, for CustomCodecOption.
+func (pf *ProtoFraming) NewCodec() Codec {
+	return &ProtoCodec{pf: pf}
+}
+
+// Packet returns a packet of msg, written as a frame of type tp.
+func (pf *ProtoFraming) Packet(tp int32, msg proto.Message) *ProtoPacket {
+	return &ProtoPacket{Tp: tp, Message: msg, pf: pf}
+}
+
+// ProtoPacket is a protobuf message.
+type ProtoPacket struct {
+	Tp      int32
+	Message proto.Message
+	pf      *ProtoFraming
+}
+
+func (pp *ProtoPacket) Type() int32 {
+	return pp.Tp
+}
+
+func (pp *ProtoPacket) Serialize() ([]byte, error) {
+	b, err := proto.Marshal(pp.Message)
+	if err != nil {
+		return nil, err
+	}
+	payload := make([]byte, binary.MaxVarintLen32, binary.MaxVarintLen32+len(b))
+	payload = append(payload[:binary.PutUvarint(payload, uint64(uint32(pp.Tp)))], b...)
+	return pp.pf.Frame(payload)
+}
+
+// ProtoCodec decodes the frames of a ProtoFraming as ProtoPackets.
+type ProtoCodec struct {
+	pf *ProtoFraming
+}
+
+func (pc *ProtoCodec) Encode(p Packet, writer io.Writer) (int, error) {
+	data, err := p.Serialize()
+	if err != nil {
+		return 0, err
+	}
+	return writer.Write(data)
+}
+
+func (pc *ProtoCodec) Decode(reader *bufio.Reader) (Packet, error) {
+	payload, err := pc.pf.ReadFrame(reader)
+	if err != nil {
+		return nil, err
+	}
+	tp, n := binary.Uvarint(payload)
+	if n <= 0 || tp > 0xFFFFFFFF {
+		return nil, ErrProtoType
+	}
+	newMessage, ok := pc.pf.types[int32(tp)]
+	if !ok {
+		return nil, ErrUndefined(int32(tp))
+	}
+	msg := newMessage()
+	if err := proto.Unmarshal(payload[n:], msg); err != nil {
+		return nil, err
+	}
+	return pc.pf.Packet(int32(tp), msg), nil
+}
@@ -1,10 +1,13 @@
 package ikio
 
 import (
+	"crypto/tls"
+	"errors"
 	"fmt"
 	"net"
 	"sync"
 	"sync/atomic"
+	"syscall"
 	"time"
 
 	"github.com/yunfeiyang1916/toolkit/ikio/timer"
@@ -31,6 +34,14 @@ type options struct {
 	logger            *log.Logger
 
 	metricTags []interface{}
+
+	tlsConfig         *tls.Config
+	certFile, keyFile string
+	maxConns          int
+	maxConnsPerIP     int
+	ipMessageRate     float64
+	ipMessageBurst    int
+	onReject          func(net.Conn, error)
 }
 
 // OnTimeOut represents a timed task.
@@ -155,6 +166,60 @@ func OnCloseOption(cb func(WriteCloser)) Option {
 	}
 }
 
+// TLSOption returns a Option that will serve TLS with the certificate of a
+// pair of PEM files, reloaded once they are modified; cfg, which may be nil,
+// is the rest of the config. Start fails if the files can not be loaded.
+func TLSOption(certFile, keyFile string, cfg *tls.Config) Option {
+	return func(o *options) {
+		o.certFile, o.keyFile = certFile, keyFile
+		o.tlsConfig = cfg
+	}
+}
+
+// TLSConfigOption returns a Option that will serve TLS with cfg, which has
+// its own certificates.
+func TLSConfigOption(cfg *tls.Config) Option {
+	return func(o *options) {
+		o.tlsConfig = cfg
+	}
+}
+
+// MaxConnsOption returns a Option that will reject the connections beyond
+// max open ones, 0 is no limit.
+func MaxConnsOption(max int) Option {
+	return func(o *options) {
+		o.maxConns = max
+	}
+}
+
+// MaxConnsPerIPOption returns a Option that will reject the connections of a
+// remote ip beyond max open ones, 0 is no limit.
+func MaxConnsPerIPOption(max int) Option {
+	return func(o *options) {
+		o.maxConnsPerIP = max
+	}
+}
+
+// MessageRatePerIPOption returns a Option that will limit the messages read
+// from all the connections of a remote ip to rate per second, with bursts of
+// burst messages; the reading waits beyond it, so the peers are slowed down
+// rather than their messages dropped. 0 is no limit.
+func MessageRatePerIPOption(rate float64, burst int) Option {
+	return func(o *options) {
+		o.ipMessageRate = rate
+		o.ipMessageBurst = burst
+	}
+}
+
+// OnRejectOption returns a Option that will set callback to call with the
+// connections rejected by a limit and the reason, ErrTooManyConns or
+// ErrTooManyConnsPerIP, before they are closed.
+func OnRejectOption(cb func(net.Conn, error)) Option {
+	return func(o *options) {
+		o.onReject = cb
+	}
+}
+
 type Server struct {
 	opts            options
 	ctx             context.Context
@@ -168,6 +233,8 @@ type Server struct {
 	timing          *timer.TimingWheel
 	identifier      int64
 	logger          *log.Logger
+	limiter         *limiter
+	stats           Stats
 }
 
 type handlerEntry struct {
@@ -188,12 +255,22 @@ func NewServer(opt ...Option) *Server {
 		identifier:      0,
 		logger:          opts.logger,
 	}
+	s.limiter = newLimiter(&s.opts)
 	s.ctx, s.cancel = context.WithCancel(NewContextWithServer(context.Background(), s))
 	s.timing = timer.NewTimingWheel(s.ctx, timer.MetricsTags(opts.metricTags...))
 	return s
 }
 
 func (s *Server) Start(l net.Listener) error {
+	tlsConfig, err := s.tlsConfig()
+	if err != nil {
+		l.Close()
+		return err
+	}
+	if tlsConfig != nil {
+		l = tls.NewListener(l, tlsConfig)
+	}
+
 	s.mu.Lock()
 	if s.lis == nil {
 		s.mu.Unlock()
@@ -234,11 +311,20 @@ func (s *Server) Start(l net.Listener) error {
 		rawConn, err := l.Accept()
 		if err != nil {
 			if ne, ok := err.(net.Error); ok && ne.Temporary() {
+				atomic.AddInt64(&s.stats.AcceptErrors, 1)
 				if tempDelay == 0 {
 					tempDelay = 5 * time.Millisecond
 				} else {
 					tempDelay *= 2
 				}
+				if errors.Is(err, syscall.EMFILE) || errors.Is(err, syscall.ENFILE) {
+					// out of file descriptors, wait for connections to close
+					atomic.AddInt64(&s.stats.AcceptFDExceeded, 1)
+					metrics.Meter("ikio.server.accept-fd-exceeded", 1, s.opts.metricTags...)
+					if tempDelay < 100*time.Millisecond {
+						tempDelay = 100 * time.Millisecond
+					}
+				}
 				if max := 1 * time.Second; tempDelay >= max {
 					tempDelay = max
 				}
@@ -252,13 +338,49 @@ func (s *Server) Start(l net.Listener) error {
 			return err
 		}
 		tempDelay = 0
+		ip := ipOf(rawConn.RemoteAddr())
+		bucket, err := s.limiter.acquire(ip)
+		if err != nil {
+			s.reject(rawConn, err)
+			continue
+		}
+		atomic.AddInt64(&s.stats.Accepted, 1)
+		atomic.AddInt64(&s.stats.Conns, 1)
 		connID := atomic.AddInt64(&s.identifier, 1)
 		conn := NewServerChannel(connID, s, rawConn)
+		conn.opts.messageBucket = bucket
+		conn.opts.onThrottled = s.throttled
+		oldClose := conn.opts.onClose
+		conn.opts.onClose = func(wc WriteCloser) {
+			s.limiter.release(ip)
+			atomic.AddInt64(&s.stats.Conns, -1)
+			oldClose(wc)
+		}
 		s.conns.Store(connID, conn)
 		s.wg.Add(1)
 		go conn.Start()
 	}
 }
+
+// reject closes a connection rejected by a limit.
+func (s *Server) reject(c net.Conn, reason error) {
+	if reason == ErrTooManyConnsPerIP {
+		atomic.AddInt64(&s.stats.RejectedPerIP, 1)
+	} else {
+		atomic.AddInt64(&s.stats.Rejected, 1)
+	}
+	metrics.Meter("ikio.server.rejected", 1, append([]interface{}{"reason", reason.Error()}, s.opts.metricTags...)...)
+	s.logger.Warnf("reject conn, local %v, remote %v, %v", c.LocalAddr(), c.RemoteAddr(), reason)
+	if s.opts.onReject != nil {
+		s.opts.onReject(c, reason)
+	}
+	c.Close()
+}
+
+func (s *Server) throttled() {
+	atomic.AddInt64(&s.stats.Throttled, 1)
+}
+
 func (s *Server) Stop() {
 	// immediately stop accepting new clients
 	s.mu.Lock()
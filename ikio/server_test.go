@@ -0,0 +1,271 @@
+package ikio
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes/wrappers"
+)
+
+// startServer starts s on a local listener, it returns its address.
+func startServer(t *testing.T, s *Server) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go s.Start(l)
+	t.Cleanup(s.Stop)
+	return l.Addr().String()
+}
+
+// waitFor polls cond for a second.
+func waitFor(t *testing.T, cond func() bool) {
+	for deadline := time.Now().Add(time.Second); !cond(); time.Sleep(5 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out")
+		}
+	}
+}
+
+func dial(t *testing.T, addr string) net.Conn {
+	c, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { c.Close() })
+	return c
+}
+
+// closedByPeer tells whether c is closed by the server without writing.
+func closedByPeer(c net.Conn) bool {
+	c.SetReadDeadline(time.Now().Add(time.Second))
+	_, err := c.Read(make([]byte, 1))
+	return err != nil && !isTimeout(err)
+}
+
+func isTimeout(err error) bool {
+	ne, ok := err.(net.Error)
+	return ok && ne.Timeout()
+}
+
+func TestServerMaxConns(t *testing.T) {
+	for _, c := range []struct {
+		opt    Option
+		reason error
+	}{
+		{MaxConnsOption(1), ErrTooManyConns},
+		{MaxConnsPerIPOption(1), ErrTooManyConnsPerIP},
+	} {
+		rejected := make(chan error, 1)
+		s := NewServer(
+			CustomCodecOption(func() Codec { return &LineCodec{} }),
+			c.opt,
+			OnRejectOption(func(c net.Conn, err error) {
+				rejected <- err
+			}),
+		)
+		addr := startServer(t, s)
+
+		dial(t, addr)
+		waitFor(t, func() bool { return s.Stats().Conns == 1 })
+		if !closedByPeer(dial(t, addr)) {
+			t.Fatalf("%v: the second connection is not closed", c.reason)
+		}
+		if err := <-rejected; err != c.reason {
+			t.Fatalf("got %v, want %v", err, c.reason)
+		}
+
+		st := s.Stats()
+		want := Stats{Conns: 1, Accepted: 1, Rejected: 1}
+		if c.reason == ErrTooManyConnsPerIP {
+			want = Stats{Conns: 1, Accepted: 1, RejectedPerIP: 1}
+		}
+		if st != want {
+			t.Fatalf("got %+v, want %+v", st, want)
+		}
+	}
+}
+
+func TestServerMessageRatePerIP(t *testing.T) {
+	received := make(chan string, 10)
+	s := NewServer(
+		CustomCodecOption(func() Codec { return &LineCodec{} }),
+		MessageRatePerIPOption(20, 1),
+		OnMessageOption(func(p Packet, wc WriteCloser) {
+			received <- string(p.(*LinePacket).Payload)
+		}),
+	)
+	addr := startServer(t, s)
+
+	// the connections of an ip share its rate
+	c1, c2 := dial(t, addr), dial(t, addr)
+	start := time.Now()
+	for i := 0; i < 2; i++ {
+		c1.Write([]byte("ping\n"))
+		c2.Write([]byte("ping\n"))
+	}
+	for i := 0; i < 4; i++ {
+		select {
+		case <-received:
+		case <-time.After(time.Second):
+			t.Fatal("message not received")
+		}
+	}
+	// a token for the first message, 3 more at 20 per second
+	if d := time.Since(start); d < 100*time.Millisecond {
+		t.Fatalf("4 messages in %v", d)
+	}
+	if st := s.Stats(); st.Throttled < 3 {
+		t.Fatalf("got %+v", st)
+	}
+}
+
+func TestServerMessageRateUndefined(t *testing.T) {
+	pf := NewProtoFraming(Framing{Prefix: Uvarint})
+	pf.Register(1, func() proto.Message { return &wrappers.StringValue{} })
+	s := NewServer(
+		CustomCodecOption(pf.NewCodec),
+		MessageRatePerIPOption(20, 1),
+	)
+	addr := startServer(t, s)
+
+	// the frames of the unregistered types are throttled as well
+	frame, err := pf.Packet(2, &wrappers.StringValue{Value: "ping"}).Serialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := dial(t, addr)
+	for i := 0; i < 4; i++ {
+		c.Write(frame)
+	}
+	waitFor(t, func() bool { return s.Stats().Throttled >= 3 })
+}
+
+func TestLimiterIdleBucket(t *testing.T) {
+	l := newLimiter(&options{ipMessageRate: 1, ipMessageBurst: 1})
+	b, err := l.acquire("10.0.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.reserve()
+	l.release("10.0.0.1")
+
+	// reconnecting gets the drained bucket back
+	if b2, _ := l.acquire("10.0.0.1"); b2 != b {
+		t.Fatal("the bucket is not kept")
+	}
+	l.release("10.0.0.1")
+
+	// it is deleted once idle for ipIdleTTL
+	l.ips["10.0.0.1"].idle = time.Now().Add(-ipIdleTTL)
+	l.swept = time.Time{}
+	if b2, _ := l.acquire("10.0.0.2"); b2 == b {
+		t.Fatal("the buckets of two ips are shared")
+	}
+	if _, ok := l.ips["10.0.0.1"]; ok {
+		t.Fatal("the idle ip is not deleted")
+	}
+}
+
+// writeCert writes a self signed certificate of name to dir.
+func writeCert(t *testing.T, dir, name string) (certFile, keyFile string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certFile, keyFile = filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	if err := ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return certFile, keyFile
+}
+
+func TestServerTLS(t *testing.T) {
+	interval := certCheckInterval
+	certCheckInterval = 0
+	defer func() { certCheckInterval = interval }()
+
+	dir, err := ioutil.TempDir("", "ikio")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	certFile, keyFile := writeCert(t, dir, "first")
+
+	received := make(chan string, 1)
+	s := NewServer(
+		CustomCodecOption(func() Codec { return &LineCodec{} }),
+		TLSOption(certFile, keyFile, nil),
+		OnMessageOption(func(p Packet, wc WriteCloser) {
+			received <- string(p.(*LinePacket).Payload)
+		}),
+	)
+	addr := startServer(t, s)
+
+	// handshake returns the name of the certificate served, once a line is
+	// received through it
+	handshake := func() string {
+		c, err := tls.Dial("tcp", addr, &tls.Config{InsecureSkipVerify: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer c.Close()
+		c.Write([]byte("ping\n"))
+		select {
+		case line := <-received:
+			if line != "ping" {
+				t.Fatalf("got %q", line)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("message not received")
+		}
+		return c.ConnectionState().PeerCertificates[0].Subject.CommonName
+	}
+
+	if name := handshake(); name != "first" {
+		t.Fatalf("got %s", name)
+	}
+
+	writeCert(t, dir, "second")
+	later := time.Now().Add(time.Minute)
+	os.Chtimes(certFile, later, later)
+	if name := handshake(); name != "second" {
+		t.Fatalf("got %s", name)
+	}
+	if st := s.Stats(); st.CertReloads != 1 {
+		t.Fatalf("got %+v", st)
+	}
+}
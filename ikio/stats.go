@@ -1,8 +1,29 @@
 package ikio
 
-func init() {
+import "sync/atomic"
 
+// Stats are the counters of a Server.
+type Stats struct {
+	Conns            int64 // connections open
+	Accepted         int64 // connections accepted
+	Rejected         int64 // connections rejected by MaxConnsOption
+	RejectedPerIP    int64 // connections rejected by MaxConnsPerIPOption
+	Throttled        int64 // messages delayed by MessageRatePerIPOption
+	AcceptErrors     int64 // temporary accept errors
+	AcceptFDExceeded int64 // accept errors for lack of file descriptors, EMFILE or ENFILE
+	CertReloads      int64 // certificates reloaded by TLSOption
 }
 
-type Stats struct {
+// Stats returns the counters of the server.
+func (s *Server) Stats() Stats {
+	return Stats{
+		Conns:            atomic.LoadInt64(&s.stats.Conns),
+		Accepted:         atomic.LoadInt64(&s.stats.Accepted),
+		Rejected:         atomic.LoadInt64(&s.stats.Rejected),
+		RejectedPerIP:    atomic.LoadInt64(&s.stats.RejectedPerIP),
+		Throttled:        atomic.LoadInt64(&s.stats.Throttled),
+		AcceptErrors:     atomic.LoadInt64(&s.stats.AcceptErrors),
+		AcceptFDExceeded: atomic.LoadInt64(&s.stats.AcceptFDExceeded),
+		CertReloads:      atomic.LoadInt64(&s.stats.CertReloads),
+	}
 }
@@ -0,0 +1,91 @@
+package ikio
+
+import (
+	"crypto/tls"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/yunfeiyang1916/toolkit/logging"
+)
+
+// the least time between two checks of the certificate files
+var certCheckInterval = 10 * time.Second
+
+// certReloader serves the certificate of a pair of files, which is reloaded
+// once they are modified, so a renewed certificate needs no restart.
+type certReloader struct {
+	certFile, keyFile string
+	logger            *logging.Logger
+	reloads           *int64
+
+	mu      sync.Mutex
+	cert    *tls.Certificate
+	modTime time.Time
+	checked time.Time
+}
+
+func (r *certReloader) load() error {
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return err
+	}
+	r.cert = &cert
+	r.modTime = r.lastModified()
+	r.checked = time.Now()
+	return nil
+}
+
+// lastModified returns the last modification time of the files.
+func (r *certReloader) lastModified() time.Time {
+	var t time.Time
+	for _, name := range []string{r.certFile, r.keyFile} {
+		if fi, err := os.Stat(name); err == nil && fi.ModTime().After(t) {
+			t = fi.ModTime()
+		}
+	}
+	return t
+}
+
+// getCertificate is the tls.Config GetCertificate, the certificate loaded
+// last is served while the new files fail to load.
+func (r *certReloader) getCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if now := time.Now(); now.Sub(r.checked) >= certCheckInterval {
+		r.checked = now
+		if r.lastModified().After(r.modTime) {
+			if err := r.load(); err != nil {
+				r.logger.Warnf("reload certificate %s fail, %v", r.certFile, err)
+			} else {
+				atomic.AddInt64(r.reloads, 1)
+			}
+		}
+	}
+	return r.cert, nil
+}
+
+// tlsConfig returns the config of the TLS listeners, nil without TLS.
+func (s *Server) tlsConfig() (*tls.Config, error) {
+	if s.opts.tlsConfig == nil && s.opts.certFile == "" {
+		return nil, nil
+	}
+	cfg := &tls.Config{}
+	if s.opts.tlsConfig != nil {
+		cfg = s.opts.tlsConfig.Clone()
+	}
+	if s.opts.certFile != "" {
+		r := &certReloader{
+			certFile: s.opts.certFile,
+			keyFile:  s.opts.keyFile,
+			logger:   s.logger,
+			reloads:  &s.stats.CertReloads,
+		}
+		if err := r.load(); err != nil {
+			return nil, err
+		}
+		cfg.GetCertificate = r.getCertificate
+	}
+	return cfg, nil
+}
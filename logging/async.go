@@ -0,0 +1,253 @@
+package logging
+
+import (
+	"errors"
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+
+	"github.com/yunfeiyang1916/toolkit/metrics"
+)
+
+// AsyncPolicy is what an AsyncWriter does with a line when its queue is full.
+type AsyncPolicy string
+
+const (
+	// AsyncBlock waits for room in the queue, no line is lost.
+	AsyncBlock AsyncPolicy = "block"
+	// AsyncDropNewest drops the line being written.
+	AsyncDropNewest AsyncPolicy = "drop_newest"
+	// AsyncDropOldest drops the oldest line of the queue to make room.
+	AsyncDropOldest AsyncPolicy = "drop_oldest"
+	// AsyncSample keeps one of every SampleRate lines, waiting for room for
+	// it, and drops the others.
+	AsyncSample AsyncPolicy = "sample"
+)
+
+var asyncPolicies = map[AsyncPolicy]bool{
+	AsyncBlock:      true,
+	AsyncDropNewest: true,
+	AsyncDropOldest: true,
+	AsyncSample:     true,
+}
+
+const (
+	defaultAsyncQueueSize  = 8192
+	defaultAsyncSampleRate = 10
+
+	// the meter of the lines dropped by the async writers, tagged by policy
+	asyncDroppedMetric = "logging.async.dropped"
+	asyncReportPeriod  = time.Second
+)
+
+// ErrAsyncClosed is returned by the writes to a closed AsyncWriter.
+var ErrAsyncClosed = errors.New("async log writer is closed")
+
+// AsyncOptions configures an AsyncWriter, an empty Policy is no async writer.
+type AsyncOptions struct {
+	Policy AsyncPolicy
+
+	// The number of lines queued, 8192 by default.
+	QueueSize int
+
+	// SampleRate is the one of N lines AsyncSample keeps, 10 by default.
+	SampleRate int
+}
+
+func (o *AsyncOptions) init() {
+	if !asyncPolicies[o.Policy] {
+		o.Policy = AsyncBlock
+	}
+	if o.QueueSize <= 0 {
+		o.QueueSize = defaultAsyncQueueSize
+	}
+	if o.SampleRate <= 0 {
+		o.SampleRate = defaultAsyncSampleRate
+	}
+}
+
+// AsyncWriter writes the lines to the underlying writer from a goroutine, the
+// callers only wait on a full queue if the policy says so. Sync waits for the
+// lines written before it.
+type AsyncWriter struct {
+	out   zapcore.WriteSyncer
+	opt   AsyncOptions
+	queue chan []byte
+	done  chan struct{}
+
+	mu     sync.RWMutex
+	closed bool
+
+	// enqueued lines, and the processed ones which are written or dropped
+	// from the queue, Sync waits for processed to reach enqueued
+	enqueued  int64
+	processed int64
+	pmu       sync.Mutex
+	pcond     *sync.Cond
+
+	dropped  int64
+	reported int64
+	sampled  uint64
+}
+
+// NewAsyncWriter returns an AsyncWriter writing to out.
+func NewAsyncWriter(out zapcore.WriteSyncer, opt AsyncOptions) *AsyncWriter {
+	opt.init()
+	w := &AsyncWriter{
+		out:   out,
+		opt:   opt,
+		queue: make(chan []byte, opt.QueueSize),
+		done:  make(chan struct{}),
+	}
+	w.pcond = sync.NewCond(&w.pmu)
+	go w.run()
+	return w
+}
+
+// Write queues a copy of p.
+func (w *AsyncWriter) Write(p []byte) (int, error) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if w.closed {
+		return 0, ErrAsyncClosed
+	}
+	b := make([]byte, len(p))
+	copy(b, p)
+
+	select {
+	case w.queue <- b:
+		atomic.AddInt64(&w.enqueued, 1)
+		return len(p), nil
+	default:
+	}
+	switch w.opt.Policy {
+	case AsyncDropNewest:
+		atomic.AddInt64(&w.dropped, 1)
+		return len(p), nil
+	case AsyncDropOldest:
+		for {
+			select {
+			case w.queue <- b:
+				atomic.AddInt64(&w.enqueued, 1)
+				return len(p), nil
+			default:
+			}
+			select {
+			case <-w.queue:
+				atomic.AddInt64(&w.dropped, 1)
+				w.markProcessed()
+			default:
+			}
+		}
+	case AsyncSample:
+		if atomic.AddUint64(&w.sampled, 1)%uint64(w.opt.SampleRate) != 0 {
+			atomic.AddInt64(&w.dropped, 1)
+			return len(p), nil
+		}
+	}
+	w.queue <- b
+	atomic.AddInt64(&w.enqueued, 1)
+	return len(p), nil
+}
+
+// Sync waits for the lines written before it to be written, and syncs the
+// underlying writer.
+func (w *AsyncWriter) Sync() error {
+	target := atomic.LoadInt64(&w.enqueued)
+	w.pmu.Lock()
+	for w.processed < target {
+		w.pcond.Wait()
+	}
+	w.pmu.Unlock()
+	return w.out.Sync()
+}
+
+// Close writes the queued lines and stops the writer, the underlying writer
+// is synced but not closed.
+func (w *AsyncWriter) Close() error {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return nil
+	}
+	w.closed = true
+	close(w.queue)
+	w.mu.Unlock()
+	<-w.done
+	return w.out.Sync()
+}
+
+// Dropped returns the number of lines dropped so far.
+func (w *AsyncWriter) Dropped() int64 {
+	return atomic.LoadInt64(&w.dropped)
+}
+
+func (w *AsyncWriter) markProcessed() {
+	w.pmu.Lock()
+	w.processed++
+	w.pmu.Unlock()
+	w.pcond.Broadcast()
+}
+
+func (w *AsyncWriter) run() {
+	ticker := time.NewTicker(asyncReportPeriod)
+	defer func() {
+		ticker.Stop()
+		w.report()
+		close(w.done)
+	}()
+	for {
+		select {
+		case b, ok := <-w.queue:
+			if !ok {
+				return
+			}
+			w.out.Write(b)
+			w.markProcessed()
+		case <-ticker.C:
+			w.report()
+		}
+	}
+}
+
+// report marks the lines dropped since the last report.
+func (w *AsyncWriter) report() {
+	dropped := atomic.LoadInt64(&w.dropped)
+	if n := dropped - w.reported; n > 0 {
+		metrics.Meter(asyncDroppedMetric, int(n), "policy", string(w.opt.Policy))
+		w.reported = dropped
+	}
+}
+
+// SyncOnSignal syncs all the loggers when the process gets one of the signals,
+// SIGINT and SIGTERM by default, then exits with 128 plus the signal number as
+// a process killed by it. It is for the programs which don't handle these
+// signals themselves, the ones which do should call Sync as they shut down,
+// framework.Shutdown does.
+func SyncOnSignal(sigs ...os.Signal) {
+	if len(sigs) == 0 {
+		sigs = []os.Signal{os.Interrupt, syscall.SIGTERM}
+	}
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, sigs...)
+	go func() {
+		sig := <-ch
+		signal.Stop(ch)
+		Sync()
+		exit(sig)
+	}()
+}
+
+// exit is os.Exit with the status of a process killed by sig, replaced in
+// the tests.
+var exit = func(sig os.Signal) {
+	if n, ok := sig.(syscall.Signal); ok {
+		os.Exit(128 + int(n))
+	}
+	os.Exit(1)
+}
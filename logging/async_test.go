@@ -0,0 +1,187 @@
+package logging
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// gatedWriter blocks the writes until its gate is opened.
+type gatedWriter struct {
+	gate chan struct{}
+	mu   sync.Mutex
+	buf  bytes.Buffer
+}
+
+func newGatedWriter() *gatedWriter {
+	return &gatedWriter{gate: make(chan struct{})}
+}
+
+func (w *gatedWriter) Write(p []byte) (int, error) {
+	<-w.gate
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.buf.Write(p)
+}
+
+func (w *gatedWriter) Sync() error { return nil }
+
+func (w *gatedWriter) lines() []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return strings.Fields(w.buf.String())
+}
+
+func TestAsyncWriterBlock(t *testing.T) {
+	out := newGatedWriter()
+	close(out.gate)
+	w := NewAsyncWriter(out, AsyncOptions{Policy: AsyncBlock, QueueSize: 4})
+	for i := 0; i < 100; i++ {
+		fmt.Fprintf(w, "%d\n", i)
+	}
+	w.Sync()
+	if got := len(out.lines()); got != 100 || w.Dropped() != 0 {
+		t.Fatalf("got %d lines and %d dropped, want 100 lines", got, w.Dropped())
+	}
+
+	w.Close()
+	if _, err := w.Write([]byte("x\n")); err != ErrAsyncClosed {
+		t.Fatalf("got %v writing after close", err)
+	}
+}
+
+func TestAsyncWriterDrop(t *testing.T) {
+	for _, tt := range []struct {
+		policy  AsyncPolicy
+		kept    []string
+		dropped []string
+	}{
+		{AsyncDropNewest, []string{"0", "1"}, []string{"5", "8"}},
+		{AsyncDropOldest, []string{"7", "8"}, []string{"3", "4"}},
+		{AsyncSample, []string{"0", "1"}, nil},
+	} {
+		out := newGatedWriter()
+		w := NewAsyncWriter(out, AsyncOptions{Policy: tt.policy, QueueSize: 2, SampleRate: 3})
+		// the writer holds one line and the queue two, the others are
+		// dropped, or wait for the gate every 3 lines with sample
+		time.AfterFunc(50*time.Millisecond, func() { close(out.gate) })
+		for i := 0; i < 9; i++ {
+			fmt.Fprintf(w, "%d\n", i)
+		}
+		w.Sync()
+
+		lines := out.lines()
+		if int64(len(lines))+w.Dropped() != 9 || w.Dropped() == 0 {
+			t.Errorf("%s: got %d lines and %d dropped", tt.policy, len(lines), w.Dropped())
+		}
+		got := " " + strings.Join(lines, " ") + " "
+		for _, l := range tt.kept {
+			if !strings.Contains(got, " "+l+" ") {
+				t.Errorf("%s: line %s is dropped, got %v", tt.policy, l, lines)
+			}
+		}
+		for _, l := range tt.dropped {
+			if strings.Contains(got, " "+l+" ") {
+				t.Errorf("%s: line %s is kept, got %v", tt.policy, l, lines)
+			}
+		}
+		w.Close()
+	}
+}
+
+func TestLoggerAsync(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logging")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "async.log")
+	l := NewLogger(&Options{AsyncPolicy: "drop_oldest", AsyncQueueSize: 16}, path)
+	defer l.closeFiles()
+	for i := 0; i < 10; i++ {
+		l.Infof("line %d", i)
+	}
+	l.Sync()
+	// the file is named by the daily rolling
+	files, _ := filepath.Glob(filepath.Join(dir, "async-*.log"))
+	if len(files) != 1 {
+		t.Fatalf("got files %v", files)
+	}
+	b, err := ioutil.ReadFile(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), "line 9") {
+		t.Fatalf("got %s", b)
+	}
+}
+
+func BenchmarkLogger(b *testing.B) {
+	for _, policy := range []AsyncPolicy{"", AsyncBlock, AsyncDropNewest, AsyncDropOldest, AsyncSample} {
+		name := string(policy)
+		if name == "" {
+			name = "sync"
+		}
+		b.Run(name, func(b *testing.B) {
+			f, err := ioutil.TempFile("", "logging")
+			if err != nil {
+				b.Fatal(err)
+			}
+			defer os.Remove(f.Name())
+			defer f.Close()
+
+			var out zapcore.WriteSyncer = f
+			var w *AsyncWriter
+			if policy != "" {
+				w = NewAsyncWriter(f, AsyncOptions{Policy: policy, QueueSize: 1024})
+				defer w.Close()
+				out = w
+			}
+			cfg := defaultEncoderConfig
+			logger := zap.New(zapcore.NewCore(NewConsoleEncoder(&cfg), out, zap.DebugLevel)).Sugar()
+			b.ReportAllocs()
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					logger.Infow("benchmark", "key", "value", "n", 42)
+				}
+			})
+			logger.Sync()
+			if w != nil {
+				b.ReportMetric(float64(w.Dropped())/float64(b.N), "dropped/op")
+			}
+		})
+	}
+}
+
+func TestSyncOnSignal(t *testing.T) {
+	exited := make(chan os.Signal, 1)
+	old := exit
+	exit = func(sig os.Signal) { exited <- sig }
+	defer func() { exit = old }()
+
+	SyncOnSignal(syscall.SIGTERM)
+	p, _ := os.FindProcess(os.Getpid())
+	if err := p.Signal(syscall.SIGTERM); err != nil {
+		t.Skip(err)
+	}
+	select {
+	case sig := <-exited:
+		if sig != syscall.SIGTERM {
+			t.Fatalf("got %v", sig)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("not exited")
+	}
+}
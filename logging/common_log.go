@@ -2,6 +2,8 @@ package logging
 
 import (
 	"strings"
+
+	"github.com/yunfeiyang1916/toolkit/rolling"
 )
 
 var slowlog *Logger = New()
@@ -19,6 +21,10 @@ type CommonLogConfig struct {
 	Rotate          string
 	GenLogLevel     string
 	BalanceLogLevel string
+	// the size rotation and retention of the common logs
+	Policy rolling.Policy
+	// the encoding of the common logs, console by default
+	Encoding string
 }
 
 var isInit bool = false
@@ -68,6 +74,10 @@ func setCommonRotate(clc CommonLogConfig) {
 		balancelog.SetRotateByDay()
 		crashlog.SetRotateByDay()
 	}
+	slowlog.SetRotatePolicy(clc.Policy)
+	genlog.SetRotatePolicy(clc.Policy)
+	balancelog.SetRotatePolicy(clc.Policy)
+	crashlog.SetRotatePolicy(clc.Policy)
 }
 
 func setCommonLogLevel(clc CommonLogConfig) {
@@ -79,6 +89,12 @@ func setCommonLogLevel(clc CommonLogConfig) {
 }
 
 func setCommonOutput(clc CommonLogConfig) {
+	if clc.Encoding != "" {
+		slowlog.SetEncoding(clc.Encoding)
+		genlog.SetEncoding(clc.Encoding)
+		crashlog.SetEncoding(clc.Encoding)
+		balancelog.SetEncoding(clc.Encoding)
+	}
 	slowlog.SetOutputByName(getNewPathName(clc, "slow"))
 	genlog.SetOutputByName(getNewPathName(clc, "gen"))
 	crashlog.SetOutputByName(getNewPathName(clc, "crash"))
@@ -8,8 +8,6 @@ import (
 	"time"
 	"unicode/utf8"
 
-	"github.com/yunfeiyang1916/toolkit/go-tls"
-
 	"go.uber.org/zap/buffer"
 	"go.uber.org/zap/zapcore"
 )
@@ -60,6 +58,7 @@ func putConsoleEncoder(c *consoleEncoder) {
 	c.buf = nil
 	c.spaced = false
 	c.openNamespaces = 0
+	c.traced = false
 	_jsonPool.Put(c)
 }
 
@@ -68,6 +67,7 @@ type consoleEncoder struct {
 	buf            *buffer.Buffer
 	spaced         bool // include spaces after colons and commas
 	openNamespaces int
+	traced         bool // a trace id is added by With
 }
 
 func NewConsoleEncoder(cfg *zapcore.EncoderConfig) zapcore.Encoder {
@@ -143,6 +143,9 @@ func (c *consoleEncoder) OpenNamespace(key string) {
 }
 
 func (c *consoleEncoder) AddString(key, val string) {
+	if key == traceIDKey {
+		c.traced = true
+	}
 	c.addKey(key)
 	c.AppendString(val)
 }
@@ -329,13 +332,14 @@ func (c *consoleEncoder) clone() *consoleEncoder {
 	clone.EncoderConfig = c.EncoderConfig
 	clone.spaced = c.spaced
 	clone.openNamespaces = c.openNamespaces
+	clone.traced = c.traced
 	clone.buf = bufferGet()
 	return clone
 }
 
 func (c *consoleEncoder) writeContext(line *buffer.Buffer, extra []zapcore.Field) {
 	context := c.Clone().(*consoleEncoder)
-	addFields(context, extra)
+	addFields(context, extra, context.traced)
 	context.closeOpenNamespaces()
 	if context.buf.Len() == 0 {
 		context.buf.Free()
@@ -483,25 +487,9 @@ func (c *consoleEncoder) tryAddRuneError(r rune, size int) bool {
 	return false
 }
 
-func addFields(enc zapcore.ObjectEncoder, fields []zapcore.Field) {
-	traceIDExist := false
-	for i := range fields {
-		if fields[i].Key == traceIDKey {
-			traceIDExist = true
-		}
-		fields[i].AddTo(enc)
-	}
-	if !traceIDExist {
-		ctx, ok := tls.GetContext()
-		if ok {
-			enc.AddString(traceIDKey, extraTraceID(ctx))
-			for _, cb := range contextList {
-				k, v := cb(ctx)
-				if len(k) != 0 && len(v) != 0 {
-					enc.AddString(k, v)
-				}
-			}
-		}
+func addFields(enc zapcore.ObjectEncoder, fields []zapcore.Field, traced bool) {
+	for _, f := range withContextFields(fields, traced) {
+		f.AddTo(enc)
 	}
 }
 
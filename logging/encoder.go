@@ -0,0 +1,61 @@
+package logging
+
+import (
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+// The encodings of the log lines.
+const (
+	// ConsoleEncoding is the default "time caller level msg {fields}" lines.
+	ConsoleEncoding = "console"
+	// JSONEncoding is a JSON object per line.
+	JSONEncoding = "json"
+	// LogfmtEncoding is key=value pairs per line.
+	LogfmtEncoding = "logfmt"
+)
+
+var encodings = map[string]bool{
+	ConsoleEncoding: true,
+	JSONEncoding:    true,
+	LogfmtEncoding:  true,
+}
+
+// NewEncoder returns the encoder of an encoding, the console one for an
+// unknown encoding. The lines logged in a goroutine with a context get its
+// trace and service fields whatever the encoding, and the lines are redacted
+// by the Redactor set by SetRedactor.
+func NewEncoder(encoding string, cfg *zapcore.EncoderConfig) zapcore.Encoder {
+	switch encoding {
+	case JSONEncoding:
+		return redactEncoder{NewJSONEncoder(cfg)}
+	case LogfmtEncoding:
+		return redactEncoder{NewLogfmtEncoder(cfg)}
+	}
+	return redactEncoder{NewConsoleEncoder(cfg)}
+}
+
+// NewJSONEncoder returns the zap JSON encoder with the context fields.
+func NewJSONEncoder(cfg *zapcore.EncoderConfig) zapcore.Encoder {
+	return &jsonEncoder{Encoder: zapcore.NewJSONEncoder(*cfg)}
+}
+
+type jsonEncoder struct {
+	zapcore.Encoder
+	traced bool // a trace id is added by With
+}
+
+func (e *jsonEncoder) AddString(key, val string) {
+	if key == traceIDKey {
+		e.traced = true
+	}
+	e.Encoder.AddString(key, val)
+}
+
+func (e *jsonEncoder) Clone() zapcore.Encoder {
+	return &jsonEncoder{Encoder: e.Encoder.Clone(), traced: e.traced}
+}
+
+func (e *jsonEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	return e.Encoder.EncodeEntry(ent, withContextFields(fields, e.traced))
+}
@@ -0,0 +1,120 @@
+package logging
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	opentracing "github.com/opentracing/opentracing-go"
+	jaeger "github.com/uber/jaeger-client-go"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/yunfeiyang1916/toolkit/go-tls"
+)
+
+type testObject struct{}
+
+func (testObject) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddString("method", "GET")
+	enc.AddInt("code", 200)
+	return nil
+}
+
+func TestLogfmtEncoder(t *testing.T) {
+	cfg := defaultEncoderConfig
+	cfg.EncodeLevel = zapcore.CapitalLevelEncoder
+	cfg.CallerKey = ""
+	enc := NewLogfmtEncoder(&cfg)
+	enc.AddString("app", "demo")
+
+	ent := zapcore.Entry{
+		Level:   zapcore.ErrorLevel,
+		Time:    time.Date(2020, 1, 2, 3, 4, 5, 6e6, time.Local),
+		Message: "call failed",
+	}
+	buf, err := enc.EncodeEntry(ent, []zapcore.Field{
+		zap.String("err", `bad "x"=1`),
+		zap.Int("n", 3),
+		zap.Bool("ok", false),
+		zap.Strings("hosts", []string{"a", "b"}),
+		zap.Object("req", testObject{}),
+		zap.String("", "empty key"),
+		zap.String("empty", ""),
+		zap.Duration("cost", 1500*time.Millisecond),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `time="2020-01-02 03:04:05.006" level=ERROR msg="call failed" app=demo ` +
+		`err="bad \"x\"=1" n=3 ok=false hosts="[\"a\",\"b\"]" req.method=GET req.code=200 ` +
+		`_="empty key" empty="" cost=1.5s` + "\n"
+	if got := buf.String(); got != want {
+		t.Fatalf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestEncoderContextFields(t *testing.T) {
+	tracer, closer := jaeger.NewTracer("test", jaeger.NewConstSampler(true), jaeger.NewNullReporter())
+	defer closer.Close()
+	span := tracer.StartSpan("op")
+	defer span.Finish()
+	sc := span.Context().(jaeger.SpanContext)
+	ctx := opentracing.ContextWithSpan(context.Background(), span)
+
+	SetService("demo.svc", "ns1")
+	defer SetService("", "")
+	if got := TraceID(ctx); got != sc.TraceID().String() {
+		t.Fatalf("TraceID got %q, want %q", got, sc.TraceID())
+	}
+
+	cfg := defaultEncoderConfig
+	cfg.EncodeLevel = zapcore.CapitalLevelEncoder
+	for _, encoding := range []string{ConsoleEncoding, JSONEncoding, LogfmtEncoding} {
+		// the context of the goroutine
+		tls.SetContext(ctx)
+		buf, err := NewEncoder(encoding, &cfg).EncodeEntry(zapcore.Entry{Message: "m"}, nil)
+		tls.DeleteContext()
+		if err != nil {
+			t.Fatal(err)
+		}
+		line := buf.String()
+		for _, want := range []string{sc.TraceID().String(), sc.SpanID().String(), "demo.svc", "ns1", "span_id"} {
+			if !strings.Contains(line, want) {
+				t.Errorf("%s: %s is missing in %s", encoding, want, line)
+			}
+		}
+
+		// a trace id added by With is not added twice
+		enc := NewEncoder(encoding, &cfg)
+		enc.AddString(traceIDKey, "t1")
+		tls.SetContext(ctx)
+		buf, _ = enc.EncodeEntry(zapcore.Entry{Message: "m"}, nil)
+		tls.DeleteContext()
+		if n := strings.Count(buf.String(), traceIDKey); n != 1 {
+			t.Errorf("%s: got %d trace ids in %s", encoding, n, buf)
+		}
+	}
+
+	buf, _ := NewJSONEncoder(&cfg).EncodeEntry(zapcore.Entry{Message: "m"}, contextFields(ctx))
+	var m map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &m); err != nil {
+		t.Fatal(err)
+	}
+	if m[traceIDKey] != sc.TraceID().String() || m[serviceKey] != "demo.svc" || m[namespaceKey] != "ns1" {
+		t.Fatalf("got %v", m)
+	}
+}
+
+func TestLoggerEncoding(t *testing.T) {
+	out := &syncBuffer{}
+	l := New()
+	l.SetEncoding(LogfmtEncoding)
+	l.SetOutput(out)
+	l.Infow("hello", "k", "v w")
+	if got := out.String(); !strings.Contains(got, `level=INFO`) || !strings.Contains(got, `msg=hello k="v w"`) {
+		t.Fatalf("got %s", got)
+	}
+}
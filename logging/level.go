@@ -0,0 +1,154 @@
+package logging
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// LevelInfo is the level of a named logger.
+type LevelInfo struct {
+	Name  string `json:"name"`
+	Level string `json:"level"`
+	// Until is when a temporary level reverts to Base, zero without one.
+	Until time.Time `json:"until,omitempty"`
+	Base  string    `json:"base,omitempty"`
+}
+
+// levelOverride is a temporary level of a logger, which reverts to base.
+type levelOverride struct {
+	base  zapcore.Level
+	until time.Time
+	timer *time.Timer
+}
+
+var (
+	overridesMtx sync.Mutex
+	overrides    = map[string]*levelOverride{}
+)
+
+// Register adds a logger to the named loggers of Log, replacing the one of the
+// same name.
+func Register(name string, l *Logger) {
+	logsMtx.Lock()
+	logs[name] = l
+	logsMtx.Unlock()
+}
+
+// Names returns the sorted names of the named loggers.
+func Names() []string {
+	logsMtx.RLock()
+	names := make([]string, 0, len(logs))
+	for name := range logs {
+		names = append(names, name)
+	}
+	logsMtx.RUnlock()
+	sort.Strings(names)
+	return names
+}
+
+// Level returns the level of the logger: "debug", "info", "warn", "error" or
+// "fatal".
+func (l *Logger) Level() string {
+	return l.loglevel.Level().String()
+}
+
+// parseLevel is stringToLogLevel refusing the unknown levels.
+func parseLevel(level string) (zapcore.Level, error) {
+	if level != "warn" && !levelsMap[level] {
+		return zapcore.DebugLevel, fmt.Errorf("logging: unknown level %q", level)
+	}
+	return stringToLogLevel(level), nil
+}
+
+// SetLoggerLevel changes the level of the named logger. A positive ttl makes
+// it temporary, the level before the first temporary one comes back after ttl;
+// a permanent change drops the pending revert. by tells who changes it, each
+// change is logged to the gen log.
+func SetLoggerLevel(name, level string, ttl time.Duration, by string) error {
+	lvl, err := parseLevel(level)
+	if err != nil {
+		return err
+	}
+	l := Log(name)
+	if l == nil {
+		return fmt.Errorf("logging: unknown logger %q", name)
+	}
+
+	overridesMtx.Lock()
+	defer overridesMtx.Unlock()
+	old := l.loglevel.Level()
+	o := overrides[name]
+	if o != nil {
+		o.timer.Stop()
+		delete(overrides, name)
+	}
+	if ttl > 0 {
+		base := old
+		if o != nil {
+			base = o.base
+		}
+		o = &levelOverride{base: base, until: time.Now().Add(ttl)}
+		o.timer = time.AfterFunc(ttl, func() { revertLevel(name, o) })
+		overrides[name] = o
+	}
+	l.loglevel.SetLevel(lvl)
+
+	if ttl > 0 {
+		auditLevel("log level changed", "logger", name, "from", old.String(), "to", lvl.String(), "by", by, "ttl", ttl.String())
+	} else {
+		auditLevel("log level changed", "logger", name, "from", old.String(), "to", lvl.String(), "by", by)
+	}
+	return nil
+}
+
+// revertLevel ends a temporary level, unless it is replaced already.
+func revertLevel(name string, o *levelOverride) {
+	overridesMtx.Lock()
+	defer overridesMtx.Unlock()
+	if overrides[name] != o {
+		return
+	}
+	delete(overrides, name)
+	l := Log(name)
+	if l == nil {
+		return
+	}
+	old := l.loglevel.Level()
+	l.loglevel.SetLevel(o.base)
+	auditLevel("log level reverted", "logger", name, "from", old.String(), "to", o.base.String(), "by", "ttl")
+}
+
+// Levels returns the levels of the named loggers.
+func Levels() []LevelInfo {
+	names := Names()
+	overridesMtx.Lock()
+	defer overridesMtx.Unlock()
+	infos := make([]LevelInfo, 0, len(names))
+	for _, name := range names {
+		l := Log(name)
+		if l == nil {
+			continue
+		}
+		info := LevelInfo{Name: name, Level: l.Level()}
+		if o := overrides[name]; o != nil {
+			info.Until = o.until
+			info.Base = o.base.String()
+		}
+		infos = append(infos, info)
+	}
+	return infos
+}
+
+// auditLevel logs a level change at warn level to the gen log, or the default
+// logger before the common logs are set.
+func auditLevel(msg string, keysAndValues ...interface{}) {
+	if checkNeedLog() {
+		genlog.Warnw(msg, keysAndValues...)
+		return
+	}
+	_defaultLogger.Warnw(msg, keysAndValues...)
+}
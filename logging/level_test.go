@@ -0,0 +1,63 @@
+package logging
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSetLoggerLevel(t *testing.T) {
+	out := &syncBuffer{}
+	_defaultLogger.SetOutput(out)
+	defer _defaultLogger.SetOutput(os.Stderr)
+
+	l := New()
+	l.SetLevelByString("info")
+	Register("level_test", l)
+
+	if err := SetLoggerLevel("level_test", "verbose", 0, "test"); err == nil {
+		t.Fatal("an unknown level is set")
+	}
+	if err := SetLoggerLevel("nothing", "info", 0, "test"); err == nil {
+		t.Fatal("the level of an unknown logger is set")
+	}
+
+	if err := SetLoggerLevel("level_test", "error", 0, "test"); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetLoggerLevel("level_test", "debug", 50*time.Millisecond, "test"); err != nil {
+		t.Fatal(err)
+	}
+	// a second temporary level keeps the level to revert to
+	if err := SetLoggerLevel("level_test", "warning", 50*time.Millisecond, "test"); err != nil {
+		t.Fatal(err)
+	}
+	if got := l.Level(); got != "warn" {
+		t.Fatalf("got level %s", got)
+	}
+	var info LevelInfo
+	for _, i := range Levels() {
+		if i.Name == "level_test" {
+			info = i
+		}
+	}
+	if info.Level != "warn" || info.Base != "error" || info.Until.IsZero() {
+		t.Fatalf("got %+v", info)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	if got := l.Level(); got != "error" {
+		t.Fatalf("the level is not reverted, got %s", got)
+	}
+	audit := out.String()
+	for _, want := range []string{
+		`"from":"info","to":"error","by":"test"}`,
+		`"to":"debug","by":"test","ttl":"50ms"`,
+		`reverted {"logger":"level_test","from":"warn","to":"error","by":"ttl"}`,
+	} {
+		if !strings.Contains(audit, want) {
+			t.Errorf("%s is not in the audit lines\n%s", want, audit)
+		}
+	}
+}
@@ -0,0 +1,287 @@
+package logging
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"math"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+// logfmtEncoder writes the lines as key=value pairs, the values are quoted when
+// they have spaces, '=' or '"'. The fields of objects and namespaces are
+// flattened as parent.key, arrays are written as JSON.
+type logfmtEncoder struct {
+	*zapcore.EncoderConfig
+	buf        *buffer.Buffer
+	namespaces []string
+	traced     bool // a trace id is added by With
+}
+
+// NewLogfmtEncoder returns a logfmt encoder.
+func NewLogfmtEncoder(cfg *zapcore.EncoderConfig) zapcore.Encoder {
+	return &logfmtEncoder{EncoderConfig: cfg, buf: bufferGet()}
+}
+
+func (e *logfmtEncoder) AddArray(key string, arr zapcore.ArrayMarshaler) error {
+	m := zapcore.NewMapObjectEncoder()
+	if err := m.AddArray(key, arr); err != nil {
+		return err
+	}
+	return e.AddReflected(key, m.Fields[key])
+}
+
+func (e *logfmtEncoder) AddObject(key string, obj zapcore.ObjectMarshaler) error {
+	e.namespaces = append(e.namespaces, key)
+	err := obj.MarshalLogObject(e)
+	e.namespaces = e.namespaces[:len(e.namespaces)-1]
+	return err
+}
+
+func (e *logfmtEncoder) AddBinary(key string, val []byte) {
+	e.AddString(key, base64.StdEncoding.EncodeToString(val))
+}
+
+func (e *logfmtEncoder) AddByteString(key string, val []byte) {
+	e.AddString(key, string(val))
+}
+
+func (e *logfmtEncoder) AddBool(key string, val bool) {
+	e.addKey(key)
+	e.buf.AppendBool(val)
+}
+
+func (e *logfmtEncoder) AddComplex128(key string, val complex128) {
+	e.addKey(key)
+	e.buf.AppendFloat(real(val), 64)
+	e.buf.AppendByte('+')
+	e.buf.AppendFloat(imag(val), 64)
+	e.buf.AppendByte('i')
+}
+
+func (e *logfmtEncoder) AddDuration(key string, val time.Duration) {
+	if e.EncodeDuration == nil {
+		e.AddString(key, val.String())
+		return
+	}
+	e.addPrimitive(key, func(enc zapcore.PrimitiveArrayEncoder) { e.EncodeDuration(val, enc) })
+}
+
+func (e *logfmtEncoder) AddFloat64(key string, val float64) {
+	e.addFloat(key, val, 64)
+}
+
+func (e *logfmtEncoder) AddInt64(key string, val int64) {
+	e.addKey(key)
+	e.buf.AppendInt(val)
+}
+
+func (e *logfmtEncoder) AddReflected(key string, obj interface{}) error {
+	marshaled, err := json.Marshal(obj)
+	if err != nil {
+		return err
+	}
+	e.AddString(key, string(marshaled))
+	return nil
+}
+
+func (e *logfmtEncoder) OpenNamespace(key string) {
+	e.namespaces = append(e.namespaces, key)
+}
+
+func (e *logfmtEncoder) AddString(key, val string) {
+	if key == traceIDKey {
+		e.traced = true
+	}
+	e.addKey(key)
+	e.appendValue(val)
+}
+
+func (e *logfmtEncoder) AddTime(key string, val time.Time) {
+	if e.EncodeTime == nil {
+		e.AddString(key, val.Format(time.RFC3339Nano))
+		return
+	}
+	e.addPrimitive(key, func(enc zapcore.PrimitiveArrayEncoder) { e.EncodeTime(val, enc) })
+}
+
+func (e *logfmtEncoder) AddUint64(key string, val uint64) {
+	e.addKey(key)
+	e.buf.AppendUint(val)
+}
+
+func (e *logfmtEncoder) AddComplex64(k string, v complex64) { e.AddComplex128(k, complex128(v)) }
+func (e *logfmtEncoder) AddFloat32(k string, v float32)     { e.addFloat(k, float64(v), 32) }
+func (e *logfmtEncoder) AddInt(k string, v int)             { e.AddInt64(k, int64(v)) }
+func (e *logfmtEncoder) AddInt32(k string, v int32)         { e.AddInt64(k, int64(v)) }
+func (e *logfmtEncoder) AddInt16(k string, v int16)         { e.AddInt64(k, int64(v)) }
+func (e *logfmtEncoder) AddInt8(k string, v int8)           { e.AddInt64(k, int64(v)) }
+func (e *logfmtEncoder) AddUint(k string, v uint)           { e.AddUint64(k, uint64(v)) }
+func (e *logfmtEncoder) AddUint32(k string, v uint32)       { e.AddUint64(k, uint64(v)) }
+func (e *logfmtEncoder) AddUint16(k string, v uint16)       { e.AddUint64(k, uint64(v)) }
+func (e *logfmtEncoder) AddUint8(k string, v uint8)         { e.AddUint64(k, uint64(v)) }
+func (e *logfmtEncoder) AddUintptr(k string, v uintptr)     { e.AddUint64(k, uint64(v)) }
+
+func (e *logfmtEncoder) Clone() zapcore.Encoder {
+	clone := e.clone()
+	clone.buf.Write(e.buf.Bytes())
+	return clone
+}
+
+func (e *logfmtEncoder) clone() *logfmtEncoder {
+	return &logfmtEncoder{
+		EncoderConfig: e.EncoderConfig,
+		buf:           bufferGet(),
+		namespaces:    append([]string(nil), e.namespaces...),
+		traced:        e.traced,
+	}
+}
+
+func (e *logfmtEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	final := e.clone()
+	// the entry keys are not in the namespaces
+	final.namespaces = nil
+	if e.TimeKey != "" {
+		final.AddTime(e.TimeKey, ent.Time)
+	}
+	if e.LevelKey != "" && e.EncodeLevel != nil {
+		final.addPrimitive(e.LevelKey, func(enc zapcore.PrimitiveArrayEncoder) { e.EncodeLevel(ent.Level, enc) })
+	}
+	if ent.LoggerName != "" && e.NameKey != "" {
+		nameEncoder := e.EncodeName
+		if nameEncoder == nil {
+			nameEncoder = zapcore.FullNameEncoder
+		}
+		final.addPrimitive(e.NameKey, func(enc zapcore.PrimitiveArrayEncoder) { nameEncoder(ent.LoggerName, enc) })
+	}
+	if ent.Caller.Defined && e.CallerKey != "" && e.EncodeCaller != nil {
+		final.addPrimitive(e.CallerKey, func(enc zapcore.PrimitiveArrayEncoder) { e.EncodeCaller(ent.Caller, enc) })
+	}
+	if e.MessageKey != "" {
+		final.AddString(e.MessageKey, ent.Message)
+	}
+	if e.buf.Len() > 0 {
+		final.buf.AppendByte(' ')
+		final.buf.Write(e.buf.Bytes())
+	}
+	final.namespaces = append(final.namespaces, e.namespaces...)
+	addFields(final, fields, e.traced)
+	final.namespaces = nil
+	if ent.Stack != "" && e.StacktraceKey != "" {
+		final.AddString(e.StacktraceKey, ent.Stack)
+	}
+	if e.LineEnding != "" {
+		final.buf.AppendString(e.LineEnding)
+	} else {
+		final.buf.AppendString(zapcore.DefaultLineEnding)
+	}
+	return final.buf, nil
+}
+
+func (e *logfmtEncoder) addKey(key string) {
+	if e.buf.Len() > 0 {
+		e.buf.AppendByte(' ')
+	}
+	for _, ns := range e.namespaces {
+		e.appendKey(ns)
+		e.buf.AppendByte('.')
+	}
+	e.appendKey(key)
+	e.buf.AppendByte('=')
+}
+
+// appendKey writes key with the characters breaking the pairs replaced by '_'.
+func (e *logfmtEncoder) appendKey(key string) {
+	if key == "" {
+		e.buf.AppendByte('_')
+		return
+	}
+	for i := 0; i < len(key); i++ {
+		if b := key[i]; b <= ' ' || b == '=' || b == '"' || b == 0x7f {
+			e.buf.AppendByte('_')
+		} else {
+			e.buf.AppendByte(b)
+		}
+	}
+}
+
+func (e *logfmtEncoder) addFloat(key string, val float64, bitSize int) {
+	e.addKey(key)
+	switch {
+	case math.IsNaN(val):
+		e.buf.AppendString("NaN")
+	case math.IsInf(val, 1):
+		e.buf.AppendString("+Inf")
+	case math.IsInf(val, -1):
+		e.buf.AppendString("-Inf")
+	default:
+		e.buf.AppendFloat(val, bitSize)
+	}
+}
+
+// addPrimitive writes the value encoded by an encoder of the config.
+func (e *logfmtEncoder) addPrimitive(key string, encode func(zapcore.PrimitiveArrayEncoder)) {
+	buf := bufferGet()
+	enc := getLineEncoder(buf)
+	encode(enc)
+	putLineEncoder(enc)
+	e.addKey(key)
+	e.appendValue(buf.String())
+	buf.Free()
+}
+
+// appendValue writes s, quoted and escaped if needed.
+func (e *logfmtEncoder) appendValue(s string) {
+	if !needsQuote(s) {
+		e.buf.AppendString(s)
+		return
+	}
+	e.buf.AppendByte('"')
+	for i := 0; i < len(s); {
+		b := s[i]
+		if b >= utf8.RuneSelf {
+			r, size := utf8.DecodeRuneInString(s[i:])
+			if r == utf8.RuneError && size == 1 {
+				e.buf.AppendString("\ufffd")
+			} else {
+				e.buf.AppendString(s[i : i+size])
+			}
+			i += size
+			continue
+		}
+		switch b {
+		case '\\', '"':
+			e.buf.AppendByte('\\')
+			e.buf.AppendByte(b)
+		case '\n':
+			e.buf.AppendString(`\n`)
+		case '\r':
+			e.buf.AppendString(`\r`)
+		case '\t':
+			e.buf.AppendString(`\t`)
+		default:
+			if b < 0x20 {
+				e.buf.AppendString(`\u00`)
+				e.buf.AppendByte(_hex[b>>4])
+				e.buf.AppendByte(_hex[b&0xF])
+			} else {
+				e.buf.AppendByte(b)
+			}
+		}
+		i++
+	}
+	e.buf.AppendByte('"')
+}
+
+func needsQuote(s string) bool {
+	if s == "" {
+		return true
+	}
+	return strings.IndexFunc(s, func(r rune) bool {
+		return r <= ' ' || r == '=' || r == '"' || r == '\\' || r == utf8.RuneError
+	}) >= 0
+}
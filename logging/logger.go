@@ -26,9 +26,16 @@ type Logger struct {
 	// 目录
 	dir          string
 	rolling      rolling.RollingFormat
+	policy       rolling.Policy
 	rollingFiles []io.Writer
+	async        AsyncOptions
+	asyncWriters []*AsyncWriter
+	core         zapcore.Core
+	sinks        []*sinkCore
+	sampling     *suppressor
 	loglevel     zap.AtomicLevel
 	prefix       string
+	encoding     string
 	encoderCfg   zapcore.EncoderConfig
 	callSkip     int
 }
@@ -92,38 +99,48 @@ func Log(name string) *Logger {
 func New() *Logger {
 	cfg := defaultEncoderConfig
 	lvl := zap.NewAtomicLevelAt(zap.DebugLevel)
-	return &Logger{
-		SugaredLogger: zap.New(zapcore.NewCore(NewConsoleEncoder(&cfg), zapcore.Lock(os.Stderr), lvl)).WithOptions(zap.AddCaller(), zap.AddCallerSkip(1)).Sugar(),
-		path:          "",
-		dir:           "",
-		rolling:       rolling.DailyRolling,
-		rollingFiles:  nil,
-		loglevel:      lvl,
-		prefix:        "",
-		encoderCfg:    cfg,
+	l := &Logger{
+		path:         "",
+		dir:          "",
+		rolling:      rolling.DailyRolling,
+		rollingFiles: nil,
+		loglevel:     lvl,
+		prefix:       "",
+		encoderCfg:   cfg,
 	}
+	l.setCore(zapcore.NewCore(NewEncoder(ConsoleEncoding, &cfg), zapcore.Lock(os.Stderr), lvl))
+	return l
 }
 
 // NewJSON build json data format logger
 func NewJSON(path string, r rolling.RollingFormat) (*Logger, error) {
+	return NewEncoded(path, r, JSONEncoding)
+}
+
+// NewEncoded builds a data logger like NewJSON, with the lines in an encoding
+// of NewEncoder.
+func NewEncoded(path string, r rolling.RollingFormat, encoding string) (*Logger, error) {
 	cfg := defaultEncoderConfig
 	cfg.LevelKey = ""
 	cfg.MessageKey = "topic"
+	cfg.EncodeLevel = zapcore.CapitalLevelEncoder
 	lvl := zap.NewAtomicLevelAt(zap.DebugLevel)
 	rollFile, err := rolling.NewRollingFile(path, r)
 	if err != nil {
 		return nil, err
 	}
-	return &Logger{
-		SugaredLogger: zap.New(zapcore.NewCore(zapcore.NewJSONEncoder(cfg), rollFile, lvl)).WithOptions(zap.AddCaller(), zap.AddCallerSkip(1)).Sugar(),
-		path:          path,
-		dir:           "",
-		rolling:       rolling.DailyRolling,
-		rollingFiles:  []io.Writer{rollFile},
-		loglevel:      lvl,
-		prefix:        "",
-		encoderCfg:    cfg,
-	}, nil
+	l := &Logger{
+		path:         path,
+		dir:          "",
+		rolling:      rolling.DailyRolling,
+		rollingFiles: []io.Writer{rollFile},
+		loglevel:     lvl,
+		prefix:       "",
+		encoding:     encoding,
+		encoderCfg:   cfg,
+	}
+	l.setCore(zapcore.NewCore(NewEncoder(encoding, &cfg), rollFile, lvl))
+	return l, nil
 }
 
 // InitData logger
@@ -165,23 +182,65 @@ func NewLogger(opt *Options, paths ...string) *Logger {
 		if res == nil {
 			res = logger
 		}
-		s := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
-		logsMtx.Lock()
-		logs[s] = logger
-		logsMtx.Unlock()
+		Register(strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)), logger)
 	}
 	return res
 }
 
 func (l *Logger) SetOutput(out io.Writer) {
-	l.SugaredLogger = zap.New(zapcore.NewCore(NewConsoleEncoder(&l.encoderCfg), zapcore.Lock(zapcore.AddSync(out)), zap.DebugLevel)).WithOptions(zap.AddCaller(), zap.AddCallerSkip(1)).Sugar()
+	l.setCore(zapcore.NewCore(l.newEncoder(), zapcore.Lock(zapcore.AddSync(out)), zap.DebugLevel))
 	l.SugaredLogger.Named(l.prefix)
 }
 
+// setCore makes core the output of the logger with the sinks, sampled if
+// SetSampling is set.
+func (l *Logger) setCore(core zapcore.Core) {
+	l.core = core
+	if len(l.sinks) > 0 {
+		cores := []zapcore.Core{core}
+		for _, c := range l.sinks {
+			cores = append(cores, c)
+		}
+		core = zapcore.NewTee(cores...)
+	}
+	if l.sampling != nil {
+		core = l.sampling.wrap(core)
+	}
+	l.SugaredLogger = zap.New(core).WithOptions(zap.AddCaller(), zap.AddCallerSkip(1)).Sugar()
+}
+
+// SetSampling samples and rate limits the lines of the logger, and logs the
+// counts of the suppressed lines periodically. The zero options stop it.
+func (l *Logger) SetSampling(opt SamplingOptions) {
+	if l.sampling != nil {
+		l.sampling.close()
+		l.sampling = nil
+	}
+	if opt.enabled() {
+		l.sampling = newSuppressor(opt)
+	}
+	if l.core != nil {
+		l.setCore(l.core)
+	}
+}
+
 func (l *Logger) GetOutput() io.Writer {
 	return nil
 }
 
+// SetEncoding sets the encoding of the outputs set after it: "console", the
+// default, "json" or "logfmt". The levels are not colored in json and logfmt.
+func (l *Logger) SetEncoding(encoding string) {
+	l.encoding = encoding
+	if encoding != "" && encoding != ConsoleEncoding {
+		l.SetColors(false)
+	}
+}
+
+func (l *Logger) newEncoder() zapcore.Encoder {
+	return NewEncoder(l.encoding, &l.encoderCfg)
+}
+
 func (l *Logger) SetColors(color bool) {
 	if !color {
 		l.encoderCfg.EncodeLevel = zapcore.CapitalLevelEncoder
@@ -210,10 +269,17 @@ func (l *Logger) refreshRotate() {
 		r, ok := w.(*rolling.RollingFile)
 		if ok {
 			r.SetRolling(l.rolling)
+			r.SetPolicy(l.policy)
 		}
 	}
 }
 
+// SetRotatePolicy sets the size rotation and the retention of the log files.
+func (l *Logger) SetRotatePolicy(p rolling.Policy) {
+	l.policy = p
+	l.refreshRotate()
+}
+
 func (l *Logger) SetRotateByHour() {
 	l.rolling = rolling.HourlyRolling
 	l.refreshRotate()
@@ -266,16 +332,38 @@ func (l *Logger) SetOutputByName(path string) error {
 	if err != nil {
 		return err
 	}
+	debugFile.SetPolicy(l.policy)
 	core := zapcore.NewTee(
-		zapcore.NewCore(NewConsoleEncoder(&l.encoderCfg), debugFile, l.loglevel),
+		zapcore.NewCore(l.newEncoder(), l.writeSyncer(debugFile), l.loglevel),
 	)
 	l.rollingFiles = []io.Writer{debugFile}
-	l.SugaredLogger = zap.New(core).WithOptions(zap.AddCaller(), zap.AddCallerSkip(1)).Sugar()
+	l.setCore(core)
 	l.SugaredLogger.Named(l.prefix)
 	return nil
 }
 
+// SetAsync makes the outputs set after it asynchronous, an empty policy makes
+// them synchronous again.
+func (l *Logger) SetAsync(opt AsyncOptions) {
+	l.async = opt
+}
+
+// writeSyncer returns the output of a rolling file, which is asynchronous if
+// SetAsync is set.
+func (l *Logger) writeSyncer(f *rolling.RollingFile) zapcore.WriteSyncer {
+	if l.async.Policy == "" {
+		return f
+	}
+	w := NewAsyncWriter(f, l.async)
+	l.asyncWriters = append(l.asyncWriters, w)
+	return w
+}
+
 func (l *Logger) closeFiles() {
+	for _, w := range l.asyncWriters {
+		w.Close()
+	}
+	l.asyncWriters = nil
 	for _, w := range l.rollingFiles {
 		r, ok := w.(*rolling.RollingFile)
 		if ok {
@@ -304,6 +392,9 @@ func (l *Logger) SetOutputPath(path string) error {
 	if err != nil {
 		return err
 	}
+	for _, f := range []*rolling.RollingFile{debugFile, infoFile, errorFile} {
+		f.SetPolicy(l.policy)
+	}
 	debugLogEnabler := zap.LevelEnablerFunc(func(lvl zapcore.Level) bool {
 		if l.loglevel.Level() > zapcore.DebugLevel {
 			return false
@@ -317,12 +408,12 @@ func (l *Logger) SetOutputPath(path string) error {
 		return l.loglevel.Level() <= zapcore.InfoLevel && zapcore.InfoLevel == lvl
 	})
 	core := zapcore.NewTee(
-		zapcore.NewCore(NewConsoleEncoder(&l.encoderCfg), debugFile, debugLogEnabler),
-		zapcore.NewCore(NewConsoleEncoder(&l.encoderCfg), infoFile, infologEnabler),
-		zapcore.NewCore(NewConsoleEncoder(&l.encoderCfg), errorFile, errorlogEnabler),
+		zapcore.NewCore(l.newEncoder(), l.writeSyncer(debugFile), debugLogEnabler),
+		zapcore.NewCore(l.newEncoder(), l.writeSyncer(infoFile), infologEnabler),
+		zapcore.NewCore(l.newEncoder(), l.writeSyncer(errorFile), errorlogEnabler),
 	)
 	l.rollingFiles = []io.Writer{debugFile, infoFile, errorFile}
-	l.SugaredLogger = zap.New(core).WithOptions(zap.AddCaller(), zap.AddCallerSkip(1)).Sugar()
+	l.setCore(core)
 	l.SugaredLogger.Named(l.prefix)
 	return nil
 }
@@ -358,6 +449,28 @@ func SetRotateByDay() {
 	_defaultLogger.SetRotateByDay()
 }
 
+// SetAsync makes the outputs of the default logger set after it asynchronous.
+func SetAsync(opt AsyncOptions) {
+	_defaultLogger.SetAsync(opt)
+}
+
+// SetEncoding sets the encoding of the outputs of the default logger set
+// after it.
+func SetEncoding(encoding string) {
+	_defaultLogger.SetEncoding(encoding)
+}
+
+// SetSampling samples and rate limits the lines of the default logger.
+func SetSampling(opt SamplingOptions) {
+	_defaultLogger.SetSampling(opt)
+}
+
+// SetRotatePolicy sets the size rotation and the retention of the default
+// logger.
+func SetRotatePolicy(p rolling.Policy) {
+	_defaultLogger.SetRotatePolicy(p)
+}
+
 func SetLevelByString(level string) {
 	_defaultLogger.SetLevelByString(level)
 }
@@ -422,14 +535,12 @@ func With(args ...interface{}) *Logger {
 	return &Logger{SugaredLogger: _defaultLogger.SugaredLogger.With(args...).Desugar().WithOptions(zap.AddCallerSkip(-1)).Sugar()}
 }
 
+// For returns the default logger with the trace and service fields of ctx.
 func For(ctx context.Context, args ...interface{}) *Logger {
-	tid := extraTraceID(ctx)
-	var fields []interface{}
-	if len(tid) != 0 {
-		fields = make([]interface{}, 0, len(args)+2)
-		fields = append(fields, traceIDKey, extraTraceID(ctx))
-	} else {
-		fields = make([]interface{}, 0, len(args))
+	ctxFields := contextFields(ctx)
+	fields := make([]interface{}, 0, len(ctxFields)+len(args))
+	for _, f := range ctxFields {
+		fields = append(fields, f)
 	}
 	fields = append(fields, args...)
 	return &Logger{SugaredLogger: _defaultLogger.With(fields...).Desugar().WithOptions(zap.AddCallerSkip(-1)).Sugar()}
@@ -531,6 +642,20 @@ func normalizeLoggerWithOption(res *Logger, opt *Options) {
 	if opt.Rolling != "" {
 		res.rolling = rolling.RollingFormat(opt.Rolling)
 	}
+	res.policy = opt.RotatePolicy()
+	if opt.AsyncPolicy != "" {
+		res.async = AsyncOptions{
+			Policy:     AsyncPolicy(opt.AsyncPolicy),
+			QueueSize:  opt.AsyncQueueSize,
+			SampleRate: opt.AsyncSampleRate,
+		}
+	}
+	if opt.Encoding != "" {
+		res.SetEncoding(opt.Encoding)
+	}
+	if sampling := opt.Sampling(); sampling.enabled() {
+		res.SetSampling(sampling)
+	}
 }
 
 type logWriter struct {
@@ -1,5 +1,11 @@
 package logging
 
+import (
+	"time"
+
+	"github.com/yunfeiyang1916/toolkit/rolling"
+)
+
 var (
 	levelsMap = map[string]bool{
 		"debug":   true,
@@ -44,6 +50,10 @@ type Options struct {
 	// TimesFormat to use for display when a full timestamp is printed
 	TimesFormat string
 
+	// Encoding of the lines: "console", "json" or "logfmt", console is default
+	// value.
+	Encoding string
+
 	// Whether printf level string when logging or not
 	DisableLevel bool
 
@@ -55,6 +65,43 @@ type Options struct {
 	// "daily", "hourly", default is not rolling
 	Rolling string
 
+	// MaxSize rotates the file at MaxSize megabytes on top of the rolling by
+	// time, 0 is no limit.
+	MaxSize int
+
+	// MaxBackups is the number of rotated files to keep, 0 keeps them all.
+	MaxBackups int
+
+	// MaxAge is the number of days to keep the rotated files, 0 keeps them all.
+	MaxAge int
+
+	// Compress gzips the rotated files in the background.
+	Compress bool
+
+	// Symlink keeps a symlink <name>-latest.log to the file being written.
+	Symlink bool
+
+	// AsyncPolicy writes the files asynchronously, what to do when the queue
+	// is full: "block", "drop_newest", "drop_oldest" or "sample". Default is
+	// writing synchronously.
+	AsyncPolicy string
+
+	// AsyncQueueSize is the number of lines queued, 8192 by default.
+	AsyncQueueSize int
+
+	// AsyncSampleRate is the one of N lines the "sample" policy keeps, 10 by
+	// default.
+	AsyncSampleRate int
+
+	// SampleFirst lines of each message and level are logged in a second,
+	// then every SampleThereafter-th, 0 is no sampling.
+	SampleFirst      int
+	SampleThereafter int
+
+	// RateLimit is the number of lines each call site logs in a second, 0 is
+	// no limit.
+	RateLimit int
+
 	// This option will not wrap empty fields in quotes if true
 	DisableQuoteEmptyFields bool
 
@@ -72,7 +119,35 @@ func (self *Options) init() {
 		self.Rolling = ""
 	}
 
+	if !encodings[self.Encoding] {
+		self.Encoding = ConsoleEncoding
+	}
+
+	if self.AsyncPolicy != "" && !asyncPolicies[AsyncPolicy(self.AsyncPolicy)] {
+		self.AsyncPolicy = string(AsyncBlock)
+	}
+
 	if self.TimesFormat == "" {
 		self.TimesFormat = TIMEMICRO
 	}
 }
+
+// RotatePolicy returns the size rotation and retention of the options.
+func (self *Options) RotatePolicy() rolling.Policy {
+	return rolling.Policy{
+		MaxSize:    int64(self.MaxSize) << 20,
+		MaxBackups: self.MaxBackups,
+		MaxAge:     time.Duration(self.MaxAge) * 24 * time.Hour,
+		Compress:   self.Compress,
+		Symlink:    self.Symlink,
+	}
+}
+
+// Sampling returns the sampling and rate limit of the options.
+func (self *Options) Sampling() SamplingOptions {
+	return SamplingOptions{
+		First:      self.SampleFirst,
+		Thereafter: self.SampleThereafter,
+		RateLimit:  self.RateLimit,
+	}
+}
@@ -0,0 +1,402 @@
+package logging
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync/atomic"
+
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+// RedactMode is how a sensitive value is redacted.
+type RedactMode string
+
+// The redaction modes.
+const (
+	// RedactMask replaces the value by "***".
+	RedactMask RedactMode = "mask"
+	// RedactPartial keeps the first and the last quarter of the value, at
+	// most 4 characters each: 6222********1234.
+	RedactPartial RedactMode = "partial"
+	// RedactHash replaces the value by the prefix of its salted sha256, so
+	// the lines of a value can still be matched: sha256:3f2a9c0d1e4b5a67.
+	RedactHash RedactMode = "hash"
+)
+
+// The preset patterns of the values redacted wherever they are in a string.
+var redactPresets = map[string]string{
+	// the numbers of the usual issuers which pass the Luhn check: visa,
+	// mastercard, discover, unionpay and jcb as 16 digits optionally grouped
+	// by 4, unionpay and visa up to 19 digits, amex as 15 digits grouped by
+	// 4-6-5. Other long numbers such as the timestamps and ids are kept.
+	"card": `\b(?:(?:4\d{3}|5[1-5]\d{2}|2(?:22[1-9]|2[3-9]\d|[3-6]\d{2}|7[01]\d|720)|6(?:011|5\d{2}|2\d{2})|35\d{2})(?:[ -]?\d{4}){3}\d{0,3}|3[47]\d{2}[ -]?\d{6}[ -]?\d{5})\b`,
+	// the mainland mobile numbers
+	"phone": `\b1[3-9]\d{9}\b`,
+	"email": `\b[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}\b`,
+}
+
+// RedactRule is a set of sensitive values and how they are redacted.
+type RedactRule struct {
+	// Fields are the names of the log fields, the JSON body fields and the
+	// form or query parameters whose values are redacted, case insensitive.
+	Fields []string
+	// Headers are the names of the headers whose values are redacted.
+	Headers []string
+	// Patterns are the regexps of the values redacted inside any string,
+	// "card", "phone" and "email" are presets.
+	Patterns []string
+	// Mode is RedactMask by default.
+	Mode RedactMode
+	// Salt of RedactHash.
+	Salt string
+}
+
+type redactPattern struct {
+	re   *regexp.Regexp
+	card bool
+	rule *RedactRule
+}
+
+// Redactor redacts the sensitive values of the log lines and of the bodies
+// logged.
+type Redactor struct {
+	fields   map[string]*RedactRule
+	headers  map[string]*RedactRule
+	patterns []redactPattern
+	// the name=value pairs of the fields in a text
+	pairs *regexp.Regexp
+}
+
+// NewRedactor returns a Redactor of rules, a field matched by several rules is
+// redacted by the first one.
+func NewRedactor(rules ...RedactRule) (*Redactor, error) {
+	r := &Redactor{fields: map[string]*RedactRule{}, headers: map[string]*RedactRule{}}
+	var names []string
+	for i := range rules {
+		rule := &rules[i]
+		switch rule.Mode {
+		case "":
+			rule.Mode = RedactMask
+		case RedactMask, RedactPartial, RedactHash:
+		default:
+			return nil, fmt.Errorf("logging: unknown redact mode %q", rule.Mode)
+		}
+		for _, f := range rule.Fields {
+			f = strings.ToLower(f)
+			if _, ok := r.fields[f]; !ok {
+				r.fields[f] = rule
+				names = append(names, regexp.QuoteMeta(f))
+			}
+		}
+		for _, h := range rule.Headers {
+			h = http.CanonicalHeaderKey(h)
+			if _, ok := r.headers[h]; !ok {
+				r.headers[h] = rule
+			}
+		}
+		for _, p := range rule.Patterns {
+			expr, preset := redactPresets[p]
+			if !preset {
+				expr = p
+			}
+			re, err := regexp.Compile(expr)
+			if err != nil {
+				return nil, fmt.Errorf("logging: redact pattern %q: %v", p, err)
+			}
+			r.patterns = append(r.patterns, redactPattern{re: re, card: preset && p == "card", rule: rule})
+		}
+	}
+	if len(names) > 0 {
+		r.pairs = regexp.MustCompile(`(?i)\b(` + strings.Join(names, "|") + `)=([^&\s]*)`)
+	}
+	return r, nil
+}
+
+// redact returns the redacted value by the mode of rule.
+func (rule *RedactRule) redact(s string) string {
+	switch rule.Mode {
+	case RedactPartial:
+		rs := []rune(s)
+		keep := len(rs) / 4
+		if keep > 4 {
+			keep = 4
+		}
+		return string(rs[:keep]) + strings.Repeat("*", len(rs)-2*keep) + string(rs[len(rs)-keep:])
+	case RedactHash:
+		sum := sha256.Sum256([]byte(rule.Salt + s))
+		return "sha256:" + hex.EncodeToString(sum[:8])
+	}
+	return "***"
+}
+
+// String redacts the values matching the patterns in s.
+func (r *Redactor) String(s string) string {
+	if r == nil {
+		return s
+	}
+	for _, p := range r.patterns {
+		s = p.re.ReplaceAllStringFunc(s, func(m string) string {
+			if p.card && !luhn(m) {
+				return m
+			}
+			return p.rule.redact(m)
+		})
+	}
+	if r.pairs != nil {
+		s = r.pairs.ReplaceAllStringFunc(s, func(m string) string {
+			i := strings.IndexByte(m, '=')
+			if i == len(m)-1 {
+				return m
+			}
+			return m[:i+1] + r.fields[strings.ToLower(m[:i])].redact(m[i+1:])
+		})
+	}
+	return s
+}
+
+// Field redacts the value of the field key: all of it if the key is a
+// sensitive field, the values matching the patterns otherwise.
+func (r *Redactor) Field(key, val string) string {
+	if r == nil {
+		return val
+	}
+	if rule, ok := r.fields[strings.ToLower(key)]; ok {
+		return rule.redact(val)
+	}
+	return r.String(val)
+}
+
+// Header redacts the value of the header name.
+func (r *Redactor) Header(name, val string) string {
+	if r == nil {
+		return val
+	}
+	if rule, ok := r.headers[http.CanonicalHeaderKey(name)]; ok {
+		return rule.redact(val)
+	}
+	return r.String(val)
+}
+
+// Headers returns a copy of h with the values redacted.
+func (r *Redactor) Headers(h http.Header) http.Header {
+	c := make(http.Header, len(h))
+	for k, vs := range h {
+		rvs := make([]string, len(vs))
+		for i, v := range vs {
+			rvs[i] = r.Header(k, v)
+		}
+		c[k] = rvs
+	}
+	return c
+}
+
+// Body redacts a request or a response body. The sensitive fields of a JSON
+// body are redacted whatever their depth, the values of the other strings by
+// the patterns; any other body is redacted as a text, with its name=value
+// pairs of the sensitive fields. A truncated JSON body is a text.
+func (r *Redactor) Body(body []byte) []byte {
+	if r == nil || len(body) == 0 {
+		return body
+	}
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err == nil && !dec.More() {
+		v, ok := r.json("", v)
+		if !ok {
+			return body
+		}
+		var buf bytes.Buffer
+		enc := json.NewEncoder(&buf)
+		enc.SetEscapeHTML(false)
+		if err := enc.Encode(v); err == nil {
+			return bytes.TrimRight(buf.Bytes(), "\n")
+		}
+	}
+	return []byte(r.String(string(body)))
+}
+
+// json returns the redacted JSON value v of the field key, false if it is as
+// it is.
+func (r *Redactor) json(key string, v interface{}) (interface{}, bool) {
+	if key != "" {
+		if rule, ok := r.fields[strings.ToLower(key)]; ok {
+			switch v := v.(type) {
+			case string:
+				return rule.redact(v), true
+			case json.Number:
+				return rule.redact(v.String()), true
+			case nil, bool:
+				return v, false
+			}
+			b, _ := json.Marshal(v)
+			return rule.redact(string(b)), true
+		}
+	}
+	redacted := false
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, e := range v {
+			if e, ok := r.json(k, e); ok {
+				v[k], redacted = e, true
+			}
+		}
+	case []interface{}:
+		for i, e := range v {
+			if e, ok := r.json(key, e); ok {
+				v[i], redacted = e, true
+			}
+		}
+	case string:
+		if s := r.String(v); s != v {
+			return s, true
+		}
+	case json.Number:
+		if s := r.String(v.String()); s != v.String() {
+			return s, true
+		}
+	}
+	return v, redacted
+}
+
+// luhn tells whether the digits of s pass the Luhn check.
+func luhn(s string) bool {
+	sum, n := 0, 0
+	for i := len(s) - 1; i >= 0; i-- {
+		c := s[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		d := int(c - '0')
+		if n%2 == 1 {
+			if d *= 2; d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		n++
+	}
+	return sum%10 == 0
+}
+
+var _redactor atomic.Value // *Redactor
+
+// SetRedactor sets the Redactor of the fields and the messages of all the
+// loggers, whatever their encoding, nil stops the redaction.
+func SetRedactor(r *Redactor) {
+	_redactor.Store(r)
+}
+
+// GetRedactor returns the Redactor set by SetRedactor, nil if none. The
+// methods of a nil Redactor return the values as they are.
+func GetRedactor() *Redactor {
+	r, _ := _redactor.Load().(*Redactor)
+	return r
+}
+
+// RedactBody redacts a body logged by the Redactor set, see Redactor.Body.
+func RedactBody(body []byte) []byte {
+	return GetRedactor().Body(body)
+}
+
+// redactEncoder redacts the fields and the message of the lines by the
+// Redactor set when they are encoded.
+type redactEncoder struct {
+	zapcore.Encoder
+}
+
+func (e redactEncoder) Clone() zapcore.Encoder {
+	return redactEncoder{e.Encoder.Clone()}
+}
+
+func (e redactEncoder) AddString(key, val string) {
+	e.Encoder.AddString(key, GetRedactor().Field(key, val))
+}
+
+func (e redactEncoder) AddByteString(key string, val []byte) {
+	if r := GetRedactor(); r != nil {
+		e.Encoder.AddString(key, r.Field(key, string(val)))
+		return
+	}
+	e.Encoder.AddByteString(key, val)
+}
+
+func (e redactEncoder) AddInt64(key string, val int64) {
+	if r := GetRedactor(); r != nil {
+		if rule, ok := r.fields[strings.ToLower(key)]; ok {
+			e.Encoder.AddString(key, rule.redact(fmt.Sprint(val)))
+			return
+		}
+	}
+	e.Encoder.AddInt64(key, val)
+}
+
+func (e redactEncoder) AddReflected(key string, val interface{}) error {
+	if r := GetRedactor(); r != nil {
+		if rule, ok := r.fields[strings.ToLower(key)]; ok {
+			e.Encoder.AddString(key, rule.redact(fmt.Sprint(val)))
+			return nil
+		}
+	}
+	return e.Encoder.AddReflected(key, val)
+}
+
+func (e redactEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	if r := GetRedactor(); r != nil {
+		ent.Message = r.String(ent.Message)
+		fields = r.zapFields(fields)
+	}
+	return e.Encoder.EncodeEntry(ent, fields)
+}
+
+// zapFields returns fields with the values redacted, the fields are copied
+// if any is.
+func (r *Redactor) zapFields(fields []zapcore.Field) []zapcore.Field {
+	var redacted []zapcore.Field
+	for i, f := range fields {
+		val, ok := r.zapField(f)
+		if !ok {
+			continue
+		}
+		if redacted == nil {
+			redacted = append([]zapcore.Field(nil), fields...)
+		}
+		redacted[i] = zapcore.Field{Key: f.Key, Type: zapcore.StringType, String: val}
+	}
+	if redacted == nil {
+		return fields
+	}
+	return redacted
+}
+
+// zapField returns the redacted value of f, false if it is as it is.
+func (r *Redactor) zapField(f zapcore.Field) (string, bool) {
+	var val string
+	switch f.Type {
+	case zapcore.StringType:
+		val = f.String
+	case zapcore.ByteStringType:
+		val = string(f.Interface.([]byte))
+	case zapcore.Int64Type, zapcore.Int32Type, zapcore.Uint64Type, zapcore.Uint32Type,
+		zapcore.StringerType, zapcore.ReflectType, zapcore.BinaryType:
+		rule, ok := r.fields[strings.ToLower(f.Key)]
+		if !ok {
+			return "", false
+		}
+		if f.Interface != nil {
+			return rule.redact(fmt.Sprint(f.Interface)), true
+		}
+		return rule.redact(fmt.Sprint(f.Integer)), true
+	default:
+		return "", false
+	}
+	s := r.Field(f.Key, val)
+	return s, s != val
+}
@@ -0,0 +1,87 @@
+package logging
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRedactor(t *testing.T) {
+	r, err := NewRedactor(
+		RedactRule{Fields: []string{"token", "password"}, Headers: []string{"authorization"}},
+		RedactRule{Fields: []string{"mobile"}, Patterns: []string{"phone", "card", "email"}, Mode: RedactPartial},
+		RedactRule{Fields: []string{"uid"}, Mode: RedactHash, Salt: "s"},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range []struct{ in, want string }{
+		{"call 13812345678 now", "call 13*******78 now"},
+		{"card 4111 1111 1111 1111", "card 4111***********1111"},
+		{"id 1234567890123456789", "id 1234567890123456789"}, // not a card
+		{"amex 3782-822463-10005", "amex 3782*********0005"},
+		{"ts 1718000000000126", "ts 1718000000000126"}, // passes Luhn, no issuer
+		{"id 9012345678901238", "id 9012345678901238"},
+		{"mail bob@example.com", "mail bob*********com"},
+		{"/a?token=abc&x=1", "/a?token=***&x=1"},
+	} {
+		if got := r.String(c.in); got != c.want {
+			t.Errorf("String(%q) = %q, want %q", c.in, got, c.want)
+		}
+	}
+
+	if got := r.Header("Authorization", "Bearer x"); got != "***" {
+		t.Errorf("got %q", got)
+	}
+	if got, again := r.Field("UID", "42"), r.Field("uid", "42"); !strings.HasPrefix(got, "sha256:") || got != again {
+		t.Errorf("got %q and %q", got, again)
+	}
+
+	body := `{"user":{"mobile":13812345678,"password":"p","tags":["a@b.co"]},"ok":true,"n":1}`
+	want := `{"n":1,"ok":true,"user":{"mobile":"13*******78","password":"***","tags":["a****o"]}}`
+	if got := string(r.Body([]byte(body))); got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+	// the bodies left as they are keep their order
+	if got := string(r.Body([]byte(`{"b":1,"a":"<x>"}`))); got != `{"b":1,"a":"<x>"}` {
+		t.Errorf("got %s", got)
+	}
+	// a truncated body is a text
+	if got := string(r.Body([]byte(`{"password":"p","mobile":"1381234`))); got != `{"password":"p","mobile":"1381234` {
+		t.Errorf("got %s", got)
+	}
+	if got := string(r.Body([]byte(`password=p&a=1`))); got != `password=***&a=1` {
+		t.Errorf("got %s", got)
+	}
+
+	if _, err := NewRedactor(RedactRule{Mode: "drop"}); err == nil {
+		t.Error("an unknown mode is accepted")
+	}
+}
+
+func TestLoggerRedact(t *testing.T) {
+	r, err := NewRedactor(RedactRule{Fields: []string{"token", "mobile"}, Patterns: []string{"email"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	SetRedactor(r)
+	defer SetRedactor(nil)
+
+	for _, encoding := range []string{ConsoleEncoding, JSONEncoding, LogfmtEncoding} {
+		var buf syncBuffer
+		l := New()
+		l.SetEncoding(encoding)
+		l.SetOutput(&buf)
+		l.With("token", "t0").Infow("sent to bob@example.com", "mobile", 13812345678, "Token", []byte("t1"), "n", 1)
+		l.Sync()
+		got := buf.String()
+		for _, leak := range []string{"t0", "t1", "13812345678", "bob@example.com"} {
+			if strings.Contains(got, leak) {
+				t.Errorf("%s: %q leaks %s", encoding, got, leak)
+			}
+		}
+		if !strings.Contains(got, "1") || strings.Count(got, "***") != 4 {
+			t.Errorf("%s: got %q", encoding, got)
+		}
+	}
+}
@@ -0,0 +1,269 @@
+package logging
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/yunfeiyang1916/toolkit/metrics"
+)
+
+const (
+	defaultSampleInterval  = time.Second
+	defaultSummaryInterval = time.Minute
+
+	// the meter of the lines suppressed by the sampling and the rate limit,
+	// tagged by reason
+	suppressedMetric = "logging.suppressed"
+	// the number of messages and call sites listed in a summary line
+	summaryTop = 5
+	// the longest message listed in a summary line
+	summaryMessageLen = 64
+)
+
+// SamplingOptions limits the lines of a logger, the zero value limits nothing.
+type SamplingOptions struct {
+	// First lines of each message and level are logged in an Interval, then
+	// every Thereafter-th, the rest are dropped with Thereafter 0. First 0 is
+	// no sampling.
+	First      int
+	Thereafter int
+
+	// RateLimit is the number of lines each call site logs in an Interval, 0
+	// is no limit.
+	RateLimit int
+
+	// Interval of the sampling and the rate limit, 1s by default.
+	Interval time.Duration
+
+	// SummaryInterval is the period of the summary line of the suppressed
+	// lines, 1m by default.
+	SummaryInterval time.Duration
+}
+
+func (o SamplingOptions) enabled() bool {
+	return o.First > 0 || o.RateLimit > 0
+}
+
+func (o *SamplingOptions) init() {
+	if o.Interval <= 0 {
+		o.Interval = defaultSampleInterval
+	}
+	if o.SummaryInterval <= 0 {
+		o.SummaryInterval = defaultSummaryInterval
+	}
+}
+
+// suppressor samples and rate limits the lines of a logger, and logs the
+// counts of the suppressed lines every SummaryInterval.
+type suppressor struct {
+	opt  SamplingOptions
+	stop chan struct{}
+	done chan struct{}
+
+	mu sync.Mutex
+	// the core the summary lines are written to
+	core zapcore.Core
+	// the sampled lines by level and message, the rate limited ones by call
+	// site, since the last summary
+	sampled map[string]int64
+	limited map[string]int64
+	sites   map[string]*siteWindow
+}
+
+type siteWindow struct {
+	start time.Time
+	n     int
+}
+
+func newSuppressor(opt SamplingOptions) *suppressor {
+	opt.init()
+	s := &suppressor{
+		opt:     opt,
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+		sampled: make(map[string]int64),
+		limited: make(map[string]int64),
+		sites:   make(map[string]*siteWindow),
+	}
+	go s.run()
+	return s
+}
+
+// wrap returns core with the sampling and the rate limit, the summary lines go
+// to core itself.
+func (s *suppressor) wrap(core zapcore.Core) zapcore.Core {
+	s.mu.Lock()
+	s.core = core
+	s.mu.Unlock()
+	if s.opt.RateLimit > 0 {
+		core = &rateLimitCore{Core: core, s: s}
+	}
+	if s.opt.First > 0 {
+		thereafter := s.opt.Thereafter
+		if thereafter <= 0 {
+			thereafter = math.MaxInt32
+		}
+		core = zapcore.NewSamplerWithOptions(core, s.opt.Interval, s.opt.First, thereafter,
+			zapcore.SamplerHook(func(ent zapcore.Entry, dec zapcore.SamplingDecision) {
+				if dec&zapcore.LogDropped != 0 {
+					s.addSampled(ent)
+				}
+			}))
+	}
+	return core
+}
+
+func (s *suppressor) addSampled(ent zapcore.Entry) {
+	key := ent.Level.CapitalString() + " " + ent.Message
+	if len(key) > summaryMessageLen {
+		key = key[:summaryMessageLen] + "..."
+	}
+	s.mu.Lock()
+	s.sampled[key]++
+	s.mu.Unlock()
+}
+
+// allow counts a line of the call site, and reports whether it is within the
+// rate limit.
+func (s *suppressor) allow(ent zapcore.Entry) bool {
+	site := ent.Caller.TrimmedPath()
+	if !ent.Caller.Defined {
+		site = "unknown"
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	w, ok := s.sites[site]
+	if !ok {
+		w = &siteWindow{}
+		s.sites[site] = w
+	}
+	if ent.Time.Sub(w.start) >= s.opt.Interval || ent.Time.Before(w.start) {
+		w.start = ent.Time
+		w.n = 0
+	}
+	w.n++
+	if w.n <= s.opt.RateLimit {
+		return true
+	}
+	s.limited[site]++
+	return false
+}
+
+func (s *suppressor) run() {
+	ticker := time.NewTicker(s.opt.SummaryInterval)
+	defer func() {
+		ticker.Stop()
+		close(s.done)
+	}()
+	for {
+		select {
+		case <-ticker.C:
+			s.summary()
+		case <-s.stop:
+			s.summary()
+			return
+		}
+	}
+}
+
+// close stops the summaries after a last one.
+func (s *suppressor) close() {
+	close(s.stop)
+	<-s.done
+}
+
+// summary logs the counts of the lines suppressed since the last summary.
+func (s *suppressor) summary() {
+	s.mu.Lock()
+	core, sampled, limited := s.core, s.sampled, s.limited
+	s.sampled = make(map[string]int64)
+	s.limited = make(map[string]int64)
+	s.mu.Unlock()
+
+	nsampled, topSampled := topCounts(sampled)
+	nlimited, topLimited := topCounts(limited)
+	if nsampled == 0 && nlimited == 0 {
+		return
+	}
+	if nsampled > 0 {
+		metrics.Meter(suppressedMetric, int(nsampled), "reason", "sampled")
+	}
+	if nlimited > 0 {
+		metrics.Meter(suppressedMetric, int(nlimited), "reason", "rate_limited")
+	}
+	if core == nil {
+		return
+	}
+	ent := zapcore.Entry{Level: zapcore.WarnLevel, Time: time.Now(), Message: "suppressed log lines"}
+	if ce := core.Check(ent, nil); ce != nil {
+		ce.Write(
+			zap.Int64("sampled", nsampled),
+			zap.String("sampled_top", topSampled),
+			zap.Int64("rate_limited", nlimited),
+			zap.String("rate_limited_top", topLimited),
+		)
+	}
+}
+
+// topCounts returns the total of counts and its largest ones as
+// "key=n; key=n".
+func topCounts(counts map[string]int64) (int64, string) {
+	var total int64
+	keys := make([]string, 0, len(counts))
+	for k, n := range counts {
+		total += n
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if counts[keys[i]] != counts[keys[j]] {
+			return counts[keys[i]] > counts[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+	if len(keys) > summaryTop {
+		keys = keys[:summaryTop]
+	}
+	top := make([]string, len(keys))
+	for i, k := range keys {
+		top[i] = fmt.Sprintf("%s=%d", k, counts[k])
+	}
+	return total, strings.Join(top, "; ")
+}
+
+// rateLimitCore drops the lines of a call site over the rate limit. The caller
+// of an entry is only known once it is written, so the limit is applied in
+// Write.
+type rateLimitCore struct {
+	zapcore.Core
+	s *suppressor
+}
+
+func (c *rateLimitCore) With(fields []zapcore.Field) zapcore.Core {
+	return &rateLimitCore{Core: c.Core.With(fields), s: c.s}
+}
+
+func (c *rateLimitCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *rateLimitCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	if !c.s.allow(ent) {
+		return nil
+	}
+	// the inner core checks its levels again, a tee writes to all its cores
+	// otherwise
+	if ce := c.Core.Check(ent, nil); ce != nil {
+		ce.Write(fields...)
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package logging
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestLoggerSampling(t *testing.T) {
+	out := &syncBuffer{}
+	l := New()
+	l.SetColors(false)
+	l.SetOutput(out)
+	l.SetSampling(SamplingOptions{First: 2, Thereafter: 5, Interval: time.Hour, SummaryInterval: time.Hour})
+	for i := 0; i < 12; i++ {
+		l.Errorw("downstream failed", "i", i)
+	}
+	l.Info("other")
+	// the first 2 lines, then the 7th and the 12th
+	if got := strings.Count(out.String(), "downstream failed"); got != 4 {
+		t.Fatalf("got %d lines\n%s", got, out)
+	}
+	if !strings.Contains(out.String(), "other") {
+		t.Fatalf("another message is sampled\n%s", out)
+	}
+
+	l.SetSampling(SamplingOptions{})
+	s := out.String()
+	if !strings.Contains(s, "suppressed log lines") || !strings.Contains(s, "ERROR downstream failed=8") {
+		t.Fatalf("got no summary\n%s", s)
+	}
+}
+
+func TestLoggerRateLimit(t *testing.T) {
+	out := &syncBuffer{}
+	l := New()
+	l.SetColors(false)
+	l.SetOutput(out)
+	l.SetSampling(SamplingOptions{RateLimit: 3, Interval: time.Hour, SummaryInterval: time.Hour})
+	// the logger reports the caller of its caller, as the package functions
+	errorf := func(format string, v ...interface{}) { l.Errorf(format, v...) }
+	for i := 0; i < 10; i++ {
+		errorf("site a %d", i)
+	}
+	for i := 0; i < 2; i++ {
+		errorf("site b %d", i)
+	}
+	s := out.String()
+	if got := strings.Count(s, "site a"); got != 3 {
+		t.Fatalf("got %d lines of site a\n%s", got, s)
+	}
+	if got := strings.Count(s, "site b"); got != 2 {
+		t.Fatalf("got %d lines of site b\n%s", got, s)
+	}
+
+	l.SetSampling(SamplingOptions{})
+	if s := out.String(); !strings.Contains(s, "sampling_test.go") || !strings.Contains(s, "=7") {
+		t.Fatalf("got no summary\n%s", s)
+	}
+	for i := 0; i < 10; i++ {
+		errorf("site a again")
+	}
+	if strings.Count(out.String(), "site a again") != 10 {
+		t.Fatal("the rate limit is not removed")
+	}
+}
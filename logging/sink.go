@@ -0,0 +1,85 @@
+package logging
+
+import (
+	"go.uber.org/zap/zapcore"
+)
+
+// Sink is an output added to a logger by AddSink, next to its files. The
+// network sinks are in the logging/sink package.
+type Sink interface {
+	// WriteEntry writes the encoded line of an entry, line is reused after
+	// the call.
+	WriteEntry(ent zapcore.Entry, line []byte) error
+	Sync() error
+	Close() error
+}
+
+// sinkCore encodes the entries of a logger for a sink.
+type sinkCore struct {
+	zapcore.LevelEnabler
+	enc  zapcore.Encoder
+	sink Sink
+}
+
+func (c *sinkCore) With(fields []zapcore.Field) zapcore.Core {
+	enc := c.enc.Clone()
+	for i := range fields {
+		fields[i].AddTo(enc)
+	}
+	return &sinkCore{LevelEnabler: c.LevelEnabler, enc: enc, sink: c.sink}
+}
+
+func (c *sinkCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *sinkCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	buf, err := c.enc.EncodeEntry(ent, fields)
+	if err != nil {
+		return err
+	}
+	err = c.sink.WriteEntry(ent, buf.Bytes())
+	buf.Free()
+	if err != nil {
+		return err
+	}
+	if ent.Level > zapcore.ErrorLevel {
+		// the process may exit right after
+		c.Sync()
+	}
+	return nil
+}
+
+func (c *sinkCore) Sync() error {
+	return c.sink.Sync()
+}
+
+// AddSink sends the lines of the logger to s too, in an encoding of
+// NewEncoder without colors. The sink has the level of the logger.
+func (l *Logger) AddSink(s Sink, encoding string) {
+	cfg := l.encoderCfg
+	cfg.EncodeLevel = zapcore.CapitalLevelEncoder
+	l.sinks = append(l.sinks, &sinkCore{LevelEnabler: l.loglevel, enc: NewEncoder(encoding, &cfg), sink: s})
+	if l.core != nil {
+		l.setCore(l.core)
+	}
+}
+
+// CloseSinks removes the sinks of the logger and closes them.
+func (l *Logger) CloseSinks() error {
+	sinks := l.sinks
+	l.sinks = nil
+	if l.core != nil {
+		l.setCore(l.core)
+	}
+	var err error
+	for _, c := range sinks {
+		if e := c.sink.Close(); e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
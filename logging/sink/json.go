@@ -0,0 +1,172 @@
+package sink
+
+import (
+	"bufio"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+const defaultSpoolMaxSize = 64 << 20
+
+// JSONOptions configures a JSON lines sink.
+type JSONOptions struct {
+	Options
+
+	// Network is "tcp", the default, or "udp", a datagram per line.
+	Network string
+	Addr    string
+
+	// SpoolDir keeps the lines which fail to be sent in the file
+	// <SpoolDir>/<Name>.spool, they are sent first once a line is sent again.
+	// Empty drops them.
+	SpoolDir string
+	Name     string
+
+	// SpoolMaxSize is the size of the spool file the lines are dropped
+	// beyond, 64MB by default.
+	SpoolMaxSize int64
+}
+
+// JSON sends the lines as they are, it is meant for a logger with the JSON
+// encoding. A line may be sent twice when the connection breaks while the
+// spool is sent, or when the process restarts before the spool file is cut.
+type JSON struct {
+	*buffered
+}
+
+// NewJSON returns a JSON lines sink, the server is dialed on the first line.
+func NewJSON(opt JSONOptions) *JSON {
+	opt.Options.init()
+	if opt.Network == "" {
+		opt.Network = "tcp"
+	}
+	if opt.Name == "" {
+		opt.Name = "json"
+	}
+	if opt.SpoolMaxSize <= 0 {
+		opt.SpoolMaxSize = defaultSpoolMaxSize
+	}
+	s := &jsonSender{conn: conn{network: opt.Network, addr: opt.Addr, opt: opt.Options}, opt: opt}
+	if opt.SpoolDir != "" {
+		s.spoolPath = filepath.Join(opt.SpoolDir, opt.Name+".spool")
+		if fi, err := os.Stat(s.spoolPath); err == nil {
+			// the lines spooled by the last process
+			s.spoolSize = fi.Size()
+		}
+	}
+	return &JSON{buffered: newBuffered("json", s, opt.Options)}
+}
+
+// WriteEntry queues a line.
+func (j *JSON) WriteEntry(ent zapcore.Entry, line []byte) error {
+	_, err := j.w.Write(line)
+	return err
+}
+
+type jsonSender struct {
+	conn
+	opt         JSONOptions
+	spoolPath   string
+	spoolSize   int64 // the size of the spool file
+	spoolOffset int64 // the bytes of the spool file sent
+}
+
+func (s *jsonSender) send(line []byte) error {
+	err := s.unspool()
+	if err == nil {
+		err = s.write(line)
+	}
+	if err != nil {
+		s.spool(line)
+	}
+	return err
+}
+
+// spool appends a line to the spool file, unless the lines left to send
+// fill it.
+func (s *jsonSender) spool(line []byte) {
+	if s.spoolPath == "" || s.spoolSize-s.spoolOffset+int64(len(line)) > s.opt.SpoolMaxSize {
+		return
+	}
+	if err := os.MkdirAll(s.opt.SpoolDir, 0755); err != nil {
+		return
+	}
+	f, err := os.OpenFile(s.spoolPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return
+	}
+	n, _ := f.Write(line)
+	f.Close()
+	s.spoolSize += int64(n)
+}
+
+// unspool sends the spooled lines from the offset, the ones left by a
+// failure stay spooled. The file is truncated once all of them are sent,
+// and the lines sent are cut from it once they are half of it.
+func (s *jsonSender) unspool() error {
+	if s.spoolSize == s.spoolOffset {
+		return nil
+	}
+	if s.c == nil && time.Now().Before(s.next) {
+		// the file is not read while the connection is down
+		return errBackoff
+	}
+	f, err := os.Open(s.spoolPath)
+	if err != nil {
+		s.spoolSize, s.spoolOffset = 0, 0
+		return nil
+	}
+	defer f.Close()
+	if _, err = f.Seek(s.spoolOffset, io.SeekStart); err != nil {
+		return nil
+	}
+	sent := false
+	r := bufio.NewReader(f)
+	for {
+		line, rerr := r.ReadBytes('\n')
+		if len(line) > 0 {
+			if err = s.write(line); err != nil {
+				break
+			}
+			s.spoolOffset += int64(len(line))
+			sent = true
+		}
+		if rerr != nil {
+			break
+		}
+	}
+	if !sent {
+		return err
+	}
+	switch {
+	case s.spoolOffset >= s.spoolSize:
+		if os.Truncate(s.spoolPath, 0) == nil {
+			s.spoolSize, s.spoolOffset = 0, 0
+		}
+	case s.spoolOffset >= s.spoolSize/2:
+		s.compact(f)
+	}
+	return err
+}
+
+// compact rewrites the spool file without the lines sent.
+func (s *jsonSender) compact(f *os.File) {
+	if _, err := f.Seek(s.spoolOffset, io.SeekStart); err != nil {
+		return
+	}
+	rest, err := ioutil.ReadAll(f)
+	if err != nil {
+		return
+	}
+	tmp := s.spoolPath + ".tmp"
+	if ioutil.WriteFile(tmp, rest, 0644) != nil || os.Rename(tmp, s.spoolPath) != nil {
+		os.Remove(tmp)
+		return
+	}
+	s.spoolSize, s.spoolOffset = int64(len(rest)), 0
+}
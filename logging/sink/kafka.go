@@ -0,0 +1,97 @@
+package sink
+
+import (
+	"bytes"
+	"context"
+	"sync/atomic"
+
+	opentracing "github.com/opentracing/opentracing-go"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/yunfeiyang1916/toolkit/kafka"
+	"github.com/yunfeiyang1916/toolkit/metrics"
+)
+
+// KafkaOptions configures a kafka sink.
+type KafkaOptions struct {
+	Options
+
+	Topic string
+	// Key of the messages, a random one by default.
+	Key string
+}
+
+// Kafka sends the lines as the messages of a topic. It reads the errors and
+// the successes of the client, which should be dedicated to it; the client
+// logs its failures to the default logger, the sink should not be added to
+// it.
+type Kafka struct {
+	*buffered
+	done chan struct{}
+}
+
+// NewKafka returns a kafka sink sending with client.
+func NewKafka(client *kafka.KafkaClient, opt KafkaOptions) *Kafka {
+	opt.Options.init()
+	s := &kafkaSender{
+		client: client,
+		opt:    opt,
+		// the lines are not traced
+		ctx: opentracing.ContextWithSpan(context.Background(), opentracing.NoopTracer{}.StartSpan("logging")),
+	}
+	k := &Kafka{buffered: newBuffered("kafka", s, opt.Options), done: make(chan struct{})}
+	go k.report(client)
+	return k
+}
+
+// WriteEntry queues a line.
+func (k *Kafka) WriteEntry(ent zapcore.Entry, line []byte) error {
+	_, err := k.w.Write(line)
+	return err
+}
+
+// Close sends the buffered lines, the client is not closed.
+func (k *Kafka) Close() error {
+	err := k.buffered.Close()
+	close(k.done)
+	return err
+}
+
+// report counts the messages the client fails to send.
+func (k *Kafka) report(client *kafka.KafkaClient) {
+	for {
+		select {
+		case _, ok := <-client.Errors():
+			if !ok {
+				return
+			}
+			atomic.AddInt64(&k.failed, 1)
+			metrics.Meter(failedMetric, 1, "sink", k.name)
+		case _, ok := <-client.Success():
+			if !ok {
+				return
+			}
+		case <-k.done:
+			return
+		}
+	}
+}
+
+type kafkaSender struct {
+	client *kafka.KafkaClient
+	opt    KafkaOptions
+	ctx    context.Context
+}
+
+func (s *kafkaSender) send(line []byte) error {
+	_, _, err := s.client.Send(s.ctx, &kafka.ProducerMessage{
+		Topic: s.opt.Topic,
+		Key:   s.opt.Key,
+		Value: bytes.TrimRight(line, "\r\n"),
+	})
+	return err
+}
+
+func (s *kafkaSender) close() error {
+	return nil
+}
@@ -0,0 +1,154 @@
+// Package sink ships the lines of the loggers over the network: RFC5424
+// syslog, newline delimited JSON over TCP or UDP, and kafka. A sink is added
+// to a logger with Logger.AddSink, it buffers a bounded number of lines and
+// sends them from a goroutine, so logging never waits for the network.
+package sink
+
+import (
+	"errors"
+	"net"
+	"sync/atomic"
+	"time"
+
+	"github.com/yunfeiyang1916/toolkit/logging"
+	"github.com/yunfeiyang1916/toolkit/metrics"
+)
+
+const (
+	defaultTimeout       = 3 * time.Second
+	defaultRetryInterval = time.Second
+
+	// the meter of the lines a sink fails to send, tagged by sink
+	failedMetric = "logging.sink.failed"
+)
+
+var errBackoff = errors.New("sink: waiting to redial")
+
+// Options are the buffering and the timeouts of a sink.
+type Options struct {
+	// QueueSize is the number of lines buffered, 8192 by default.
+	QueueSize int
+
+	// Policy is what to do with a line when the buffer is full, drop_newest
+	// by default. The lines dropped are counted by the logging.async.dropped
+	// meter.
+	Policy logging.AsyncPolicy
+
+	// Timeout of the dials and the writes, 3s by default.
+	Timeout time.Duration
+
+	// RetryInterval is the least time between two dials after a failure, 1s
+	// by default.
+	RetryInterval time.Duration
+}
+
+func (o *Options) init() {
+	if o.Policy == "" {
+		o.Policy = logging.AsyncDropNewest
+	}
+	if o.Timeout <= 0 {
+		o.Timeout = defaultTimeout
+	}
+	if o.RetryInterval <= 0 {
+		o.RetryInterval = defaultRetryInterval
+	}
+}
+
+// sender sends a line from the goroutine of the buffer.
+type sender interface {
+	send(line []byte) error
+	close() error
+}
+
+// buffered is the buffering of the sinks, the lines written are sent by a
+// sender from the goroutine of an AsyncWriter.
+type buffered struct {
+	name   string
+	s      sender
+	w      *logging.AsyncWriter
+	failed int64
+}
+
+func newBuffered(name string, s sender, opt Options) *buffered {
+	b := &buffered{name: name, s: s}
+	b.w = logging.NewAsyncWriter(writerFunc(b.write), logging.AsyncOptions{
+		Policy:    opt.Policy,
+		QueueSize: opt.QueueSize,
+	})
+	return b
+}
+
+func (b *buffered) write(line []byte) (int, error) {
+	if err := b.s.send(line); err != nil {
+		atomic.AddInt64(&b.failed, 1)
+		metrics.Meter(failedMetric, 1, "sink", b.name)
+		return 0, err
+	}
+	return len(line), nil
+}
+
+// Sync waits for the lines written before it to be sent.
+func (b *buffered) Sync() error {
+	return b.w.Sync()
+}
+
+// Close sends the buffered lines and closes the sink.
+func (b *buffered) Close() error {
+	b.w.Close()
+	return b.s.close()
+}
+
+// Dropped returns the number of lines dropped by a full buffer.
+func (b *buffered) Dropped() int64 {
+	return b.w.Dropped()
+}
+
+// Failed returns the number of lines the sink failed to send.
+func (b *buffered) Failed() int64 {
+	return atomic.LoadInt64(&b.failed)
+}
+
+type writerFunc func([]byte) (int, error)
+
+func (f writerFunc) Write(p []byte) (int, error) { return f(p) }
+func (f writerFunc) Sync() error                 { return nil }
+
+// conn is a connection dialed on the first write and redialed after a
+// failure, at most once per retry interval.
+type conn struct {
+	network, addr string
+	opt           Options
+	c             net.Conn
+	next          time.Time
+}
+
+func (c *conn) write(p []byte) error {
+	if c.c == nil {
+		if time.Now().Before(c.next) {
+			return errBackoff
+		}
+		nc, err := net.DialTimeout(c.network, c.addr, c.opt.Timeout)
+		if err != nil {
+			c.next = time.Now().Add(c.opt.RetryInterval)
+			return err
+		}
+		c.c = nc
+	}
+	c.c.SetWriteDeadline(time.Now().Add(c.opt.Timeout))
+	if _, err := c.c.Write(p); err != nil {
+		c.c.Close()
+		c.c = nil
+		c.next = time.Now().Add(c.opt.RetryInterval)
+		return err
+	}
+	return nil
+}
+
+func (c *conn) close() error {
+	if c.c == nil {
+		return nil
+	}
+	err := c.c.Close()
+	c.c = nil
+	return err
+}
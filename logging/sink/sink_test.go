@@ -0,0 +1,296 @@
+package sink
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/yunfeiyang1916/toolkit/kafka"
+	"github.com/yunfeiyang1916/toolkit/logging"
+)
+
+func newLogger(s logging.Sink, encoding string) *logging.Logger {
+	l := logging.New()
+	l.SetOutput(ioutil.Discard)
+	l.AddSink(s, encoding)
+	return l
+}
+
+func TestSyslogUDP(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pc.Close()
+
+	s := NewSyslog(SyslogOptions{Addr: pc.LocalAddr().String(), Hostname: "host", AppName: "app"})
+	l := newLogger(s, logging.LogfmtEncoding)
+	defer l.CloseSinks()
+	l.Errorw("boom", "k", 1)
+	l.Debug("debug line")
+	l.Sync()
+
+	buf := make([]byte, 4096)
+	pc.SetReadDeadline(time.Now().Add(time.Second))
+	for _, want := range []string{"<11>1 ", "<15>1 "} {
+		n, _, err := pc.ReadFrom(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		msg := string(buf[:n])
+		if !strings.HasPrefix(msg, want) || !strings.Contains(msg, " host app "+strconv.Itoa(os.Getpid())+" - - ") {
+			t.Fatalf("got %q, want the prefix %q", msg, want)
+		}
+	}
+	if s.Failed() != 0 || s.Dropped() != 0 {
+		t.Fatalf("got %d failed and %d dropped", s.Failed(), s.Dropped())
+	}
+}
+
+func TestSyslogTCP(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	s := NewSyslog(SyslogOptions{Network: "tcp", Addr: ln.Addr().String(), Facility: 16})
+	l := newLogger(s, logging.JSONEncoding)
+	defer l.CloseSinks()
+	l.Warnw("first line")
+	l.Infow("second\nline")
+
+	c, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	r := bufio.NewReader(c)
+	for _, want := range []string{"<132>1 ", "<134>1 "} {
+		size, err := r.ReadString(' ')
+		if err != nil {
+			t.Fatal(err)
+		}
+		n, _ := strconv.Atoi(strings.TrimSpace(size))
+		msg := make([]byte, n)
+		if _, err := io.ReadFull(r, msg); err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasPrefix(string(msg), want) || strings.Contains(string(msg), "\n") {
+			t.Fatalf("got %q, want the prefix %q", msg, want)
+		}
+	}
+}
+
+// lineServer sends the lines read from its connections.
+type lineServer struct {
+	ln    net.Listener
+	mu    sync.Mutex
+	conns []net.Conn
+}
+
+func newLineServer(addr string, lines chan<- string) (*lineServer, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	s := &lineServer{ln: ln}
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			s.mu.Lock()
+			s.conns = append(s.conns, c)
+			s.mu.Unlock()
+			go func() {
+				sc := bufio.NewScanner(c)
+				for sc.Scan() {
+					lines <- sc.Text()
+				}
+			}()
+		}
+	}()
+	return s, nil
+}
+
+func (s *lineServer) close() {
+	s.ln.Close()
+	s.mu.Lock()
+	for _, c := range s.conns {
+		c.Close()
+	}
+	s.mu.Unlock()
+}
+
+func TestJSONSpool(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sink")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	lines := make(chan string, 100)
+	srv, err := newLineServer("127.0.0.1:0", lines)
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := srv.ln.Addr().String()
+
+	s := NewJSON(JSONOptions{
+		Options:  Options{RetryInterval: 10 * time.Millisecond},
+		Addr:     addr,
+		SpoolDir: dir,
+		Name:     "test",
+	})
+	l := newLogger(s, logging.JSONEncoding)
+	defer l.CloseSinks()
+	l.Infow("before", "n", 0)
+	l.Sync()
+	var m map[string]interface{}
+	if err := json.Unmarshal([]byte(<-lines), &m); err != nil || m["msg"] != "before" {
+		t.Fatalf("got %v, %v", m, err)
+	}
+
+	// the server is gone, the lines are spooled once the sink fails
+	srv.close()
+	time.Sleep(50 * time.Millisecond)
+	for i := 1; s.Failed() == 0; i++ {
+		if i > 100 {
+			t.Fatal("the sink does not fail")
+		}
+		l.Infow("down", "n", i)
+		l.Sync()
+	}
+	if fi, err := os.Stat(dir + "/test.spool"); err != nil || fi.Size() == 0 {
+		t.Fatalf("nothing is spooled: %v", err)
+	}
+
+	if srv, err = newLineServer(addr, lines); err != nil {
+		t.Skipf("the address is taken again: %v", err)
+	}
+	defer srv.close()
+	time.Sleep(20 * time.Millisecond)
+	l.Infow("after")
+	l.Sync()
+
+	var got []string
+	for {
+		select {
+		case line := <-lines:
+			got = append(got, line)
+			if strings.Contains(line, `"msg":"after"`) {
+				// the spooled lines come before
+				if len(got) < 2 || !strings.Contains(got[len(got)-2], `"msg":"down"`) {
+					t.Fatalf("got %v", got)
+				}
+				if fi, _ := os.Stat(dir + "/test.spool"); fi.Size() != 0 {
+					t.Fatalf("the spool is not sent, %d bytes left", fi.Size())
+				}
+				return
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("got %v", got)
+		}
+	}
+}
+
+func TestKafka(t *testing.T) {
+	client, mock, err := kafka.NewMockAsyncProducerClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mock.Close()
+	mock.ExpectInputAndSucceed()
+	mock.ExpectInputAndFail(errors.New("broker down"))
+
+	s := NewKafka(client, KafkaOptions{Topic: "logs"})
+	l := newLogger(s, logging.JSONEncoding)
+	defer l.CloseSinks()
+	l.Infow("one")
+	l.Infow("two")
+	l.Sync()
+	for i := 0; s.Failed() != 1; i++ {
+		if i > 100 {
+			t.Fatalf("got %d failed", s.Failed())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestJSONUnspool(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sink")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	spooled := "a\nb\nc\n"
+	path := dir + "/test.spool"
+	if err := ioutil.WriteFile(path, []byte(spooled), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := make(chan string, 10)
+	srv, err := newLineServer("127.0.0.1:0", lines)
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := srv.ln.Addr().String()
+	srv.close()
+
+	opt := JSONOptions{Options: Options{RetryInterval: time.Hour}, Addr: addr, SpoolDir: dir, Name: "test"}
+	opt.Options.init()
+	opt.SpoolMaxSize = defaultSpoolMaxSize
+	s := &jsonSender{conn: conn{network: "tcp", addr: addr, opt: opt.Options}, opt: opt, spoolPath: path, spoolSize: int64(len(spooled))}
+
+	// nothing is sent, the file is left as is
+	if err := s.unspool(); err == nil {
+		t.Fatal("the server is down")
+	}
+	// in backoff the file is not read: its absence is not noticed
+	os.Rename(path, path+".away")
+	if err := s.unspool(); err != errBackoff || s.spoolSize != int64(len(spooled)) {
+		t.Fatalf("got %v, size %d", err, s.spoolSize)
+	}
+	os.Rename(path+".away", path)
+	if b, _ := ioutil.ReadFile(path); string(b) != spooled || s.spoolOffset != 0 {
+		t.Fatalf("got %q, offset %d", b, s.spoolOffset)
+	}
+
+	if srv, err = newLineServer(addr, lines); err != nil {
+		t.Skipf("the address is taken again: %v", err)
+	}
+	defer srv.close()
+	s.next = time.Time{}
+	if err := s.unspool(); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"a", "b", "c"} {
+		if got := <-lines; got != want {
+			t.Fatalf("got %q, want %q", got, want)
+		}
+	}
+	if fi, _ := os.Stat(path); fi.Size() != 0 || s.spoolSize != 0 || s.spoolOffset != 0 {
+		t.Fatalf("the spool is not truncated, %d bytes, offset %d", fi.Size(), s.spoolOffset)
+	}
+
+	// the lines sent are cut once they are half of the file
+	ioutil.WriteFile(path, []byte(spooled), 0644)
+	s.spoolSize, s.spoolOffset = int64(len(spooled)), 2
+	f, _ := os.Open(path)
+	s.compact(f)
+	f.Close()
+	if b, _ := ioutil.ReadFile(path); string(b) != "b\nc\n" || s.spoolSize != 4 || s.spoolOffset != 0 {
+		t.Fatalf("got %q, size %d, offset %d", b, s.spoolSize, s.spoolOffset)
+	}
+}
@@ -0,0 +1,121 @@
+package sink
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// the RFC5424 timestamp, at most 6 digits of fraction
+const syslogTime = "2006-01-02T15:04:05.000000Z07:00"
+
+// SyslogOptions configures a syslog sink.
+type SyslogOptions struct {
+	Options
+
+	// Network is "udp", the default, or "tcp". The messages over tcp are
+	// framed by octet counting of RFC6587.
+	Network string
+	Addr    string
+
+	// Facility of the messages, 1 (user) by default.
+	Facility int
+
+	// Hostname and AppName of the messages, the host and the program names
+	// by default.
+	Hostname string
+	AppName  string
+}
+
+// Syslog sends the lines as RFC5424 syslog messages, the severity is the level
+// of the line.
+type Syslog struct {
+	*buffered
+	opt    SyslogOptions
+	header string // "HOSTNAME APP-NAME PROCID MSGID SD "
+}
+
+// NewSyslog returns a syslog sink, the server is dialed on the first line.
+func NewSyslog(opt SyslogOptions) *Syslog {
+	opt.Options.init()
+	if opt.Network == "" {
+		opt.Network = "udp"
+	}
+	if opt.Facility <= 0 {
+		opt.Facility = 1
+	}
+	if opt.Hostname == "" {
+		opt.Hostname, _ = os.Hostname()
+	}
+	if opt.AppName == "" {
+		opt.AppName = filepath.Base(os.Args[0])
+	}
+	s := &Syslog{
+		opt:    opt,
+		header: syslogField(opt.Hostname) + " " + syslogField(opt.AppName) + " " + strconv.Itoa(os.Getpid()) + " - - ",
+	}
+	s.buffered = newBuffered("syslog", &syslogSender{conn: conn{network: opt.Network, addr: opt.Addr, opt: opt.Options}, framed: opt.Network != "udp"}, opt.Options)
+	return s
+}
+
+// WriteEntry queues the message of a line.
+func (s *Syslog) WriteEntry(ent zapcore.Entry, line []byte) error {
+	msg := make([]byte, 0, len(line)+len(s.header)+48)
+	msg = append(msg, '<')
+	msg = strconv.AppendInt(msg, int64(s.opt.Facility*8+severity(ent.Level)), 10)
+	msg = append(msg, ">1 "...)
+	msg = ent.Time.AppendFormat(msg, syslogTime)
+	msg = append(msg, ' ')
+	msg = append(msg, s.header...)
+	msg = append(msg, bytes.TrimRight(line, "\r\n")...)
+	_, err := s.w.Write(msg)
+	return err
+}
+
+// severity returns the syslog severity of a level.
+func severity(lvl zapcore.Level) int {
+	switch lvl {
+	case zapcore.DebugLevel:
+		return 7
+	case zapcore.InfoLevel:
+		return 6
+	case zapcore.WarnLevel:
+		return 4
+	case zapcore.ErrorLevel:
+		return 3
+	}
+	return 2
+}
+
+// syslogField returns s as a header field: printable ASCII, "-" if empty.
+func syslogField(s string) string {
+	b := []byte(s)
+	for i, c := range b {
+		if c <= ' ' || c > '~' {
+			b[i] = '_'
+		}
+	}
+	if len(b) == 0 {
+		return "-"
+	}
+	return string(b)
+}
+
+type syslogSender struct {
+	conn
+	framed bool
+}
+
+func (s *syslogSender) send(msg []byte) error {
+	if !s.framed {
+		return s.write(msg)
+	}
+	framed := make([]byte, 0, len(msg)+8)
+	framed = strconv.AppendInt(framed, int64(len(msg)), 10)
+	framed = append(framed, ' ')
+	framed = append(framed, msg...)
+	return s.write(framed)
+}
@@ -5,7 +5,23 @@ import (
 	"strings"
 
 	opentracing "github.com/opentracing/opentracing-go"
+	jaeger "github.com/uber/jaeger-client-go"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 	"golang.org/x/net/context"
+
+	"github.com/yunfeiyang1916/toolkit/go-tls"
+)
+
+const (
+	spanIDKey    = "span_id"
+	serviceKey   = "service"
+	namespaceKey = "namespace"
+)
+
+var (
+	serviceName      string
+	serviceNamespace string
 )
 
 type contextFunc func(ctx context.Context) (string, string)
@@ -16,13 +32,81 @@ func RegisteCtx(cb contextFunc) {
 	contextList = append(contextList, cb)
 }
 
-func extraTraceID(ctx context.Context) string {
+// SetService sets the service name and the namespace logged by the context
+// aware lines.
+func SetService(name, namespace string) {
+	serviceName = name
+	serviceNamespace = namespace
+}
+
+// TraceID returns the trace id of the span of ctx, empty without a span.
+func TraceID(ctx context.Context) string {
+	traceID, _ := extraTrace(ctx)
+	return traceID
+}
+
+// SpanID returns the id of the span of ctx, empty without a span.
+func SpanID(ctx context.Context) string {
+	_, spanID := extraTrace(ctx)
+	return spanID
+}
+
+// extraTrace returns the trace and span ids of the span of ctx. The jaeger
+// spans are read directly, the others are parsed from the
+// "trace:span:parent:flags" form of their context.
+func extraTrace(ctx context.Context) (string, string) {
 	span := opentracing.SpanFromContext(ctx)
-	var traceID string
 	if span == nil {
-		traceID = ""
-	} else {
-		traceID = strings.SplitN(fmt.Sprintf("%s", span.Context()), ":", 2)[0]
+		return "", ""
 	}
-	return traceID
+	if sc, ok := span.Context().(jaeger.SpanContext); ok {
+		return sc.TraceID().String(), sc.SpanID().String()
+	}
+	parts := strings.SplitN(fmt.Sprintf("%s", span.Context()), ":", 3)
+	if len(parts) < 2 {
+		return parts[0], ""
+	}
+	return parts[0], parts[1]
+}
+
+// contextFields returns the trace and service fields of ctx, with the ones of
+// the callbacks registered by RegisteCtx. The trace id is always there, even
+// empty, the others only if set.
+func contextFields(ctx context.Context) []zapcore.Field {
+	traceID, spanID := extraTrace(ctx)
+	fields := []zapcore.Field{zap.String(traceIDKey, traceID)}
+	if spanID != "" {
+		fields = append(fields, zap.String(spanIDKey, spanID))
+	}
+	if serviceName != "" {
+		fields = append(fields, zap.String(serviceKey, serviceName))
+	}
+	if serviceNamespace != "" {
+		fields = append(fields, zap.String(namespaceKey, serviceNamespace))
+	}
+	for _, cb := range contextList {
+		k, v := cb(ctx)
+		if len(k) != 0 && len(v) != 0 {
+			fields = append(fields, zap.String(k, v))
+		}
+	}
+	return fields
+}
+
+// withContextFields adds the context fields of the goroutine to fields, unless
+// the line already has a trace id, traced tells the logger has one.
+func withContextFields(fields []zapcore.Field, traced bool) []zapcore.Field {
+	if traced {
+		return fields
+	}
+	for i := range fields {
+		if fields[i].Key == traceIDKey {
+			return fields
+		}
+	}
+	ctx, ok := tls.GetContext()
+	if !ok {
+		return fields
+	}
+	return append(fields[:len(fields):len(fields)], contextFields(ctx)...)
 }
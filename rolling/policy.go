@@ -0,0 +1,179 @@
+package rolling
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	compressSuffix = ".gz"
+	symlinkSuffix  = "-latest.log"
+)
+
+// Policy is the size rotation and the retention of a RollingFile, the zero
+// value rotates by time only and keeps every file.
+type Policy struct {
+	// MaxSize is the size in bytes a file is rotated at, on top of the time
+	// rotation. The files of a time fragment are numbered like
+	// base-20060102.log, base-20060102.1.log. 0 is no limit.
+	MaxSize int64
+
+	// MaxBackups is the number of rotated files to keep, 0 keeps them all.
+	MaxBackups int
+
+	// MaxAge is how long to keep the rotated files, by modification time. 0
+	// keeps them all.
+	MaxAge time.Duration
+
+	// Compress gzips the rotated files in the background.
+	Compress bool
+
+	// Symlink keeps a symlink base-latest.log to the file being written.
+	Symlink bool
+}
+
+// full reports whether writing n more bytes to a file of size bytes would
+// exceed the max size, an empty file takes any write.
+func (p Policy) full(size int64, n int) bool {
+	return p.MaxSize > 0 && size > 0 && size+int64(n) > p.MaxSize
+}
+
+// mill wakes up the mill routine, which compresses and removes the rotated
+// files.
+func (r *RollingFile) mill() {
+	select {
+	case r.millCh <- struct{}{}:
+	default:
+	}
+}
+
+func (r *RollingFile) millRoutine() {
+	for {
+		select {
+		case <-r.millCh:
+			r.millRun()
+		case <-r.exit:
+			return
+		}
+	}
+}
+
+func (r *RollingFile) millRun() {
+	r.rollMutex.RLock()
+	policy, active := r.policy, r.active
+	r.rollMutex.RUnlock()
+	// nothing is written yet, the file of this fragment may be appended to
+	if active == "" || !policy.Compress && policy.MaxBackups == 0 && policy.MaxAge == 0 {
+		return
+	}
+	files, err := r.backups(active)
+	if err != nil {
+		return
+	}
+
+	// newest first
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].ModTime().After(files[j].ModTime())
+	})
+	dir := filepath.Dir(r.basePath)
+	var keep []os.FileInfo
+	for i, f := range files {
+		if policy.MaxBackups > 0 && i >= policy.MaxBackups ||
+			policy.MaxAge > 0 && time.Since(f.ModTime()) > policy.MaxAge {
+			os.Remove(filepath.Join(dir, f.Name()))
+			continue
+		}
+		keep = append(keep, f)
+	}
+	if !policy.Compress {
+		return
+	}
+	for _, f := range keep {
+		if !strings.HasSuffix(f.Name(), compressSuffix) {
+			compressFile(filepath.Join(dir, f.Name()), f)
+		}
+	}
+}
+
+// backups returns the rotated files of the base path, which are all the files
+// of it but the active one.
+func (r *RollingFile) backups(active string) ([]os.FileInfo, error) {
+	dir, filename := filepath.Split(r.basePath)
+	if dir == "" {
+		dir = "."
+	}
+	d, err := os.Open(dir)
+	if err != nil {
+		return nil, err
+	}
+	infos, err := d.Readdir(-1)
+	d.Close()
+	if err != nil {
+		return nil, err
+	}
+	var files []os.FileInfo
+	for _, f := range infos {
+		name := f.Name()
+		if !f.Mode().IsRegular() || name == filepath.Base(active) ||
+			!strings.HasPrefix(name, filename) || !isBackup(name[len(filename):]) {
+			continue
+		}
+		files = append(files, f)
+	}
+	return files, nil
+}
+
+// isBackup reports whether rest, the name of a file after the file name of
+// the base path, is the name of one of its files like -20060102.1.log.gz.
+func isBackup(rest string) bool {
+	rest = strings.TrimSuffix(rest, compressSuffix)
+	if !strings.HasSuffix(rest, ".log") {
+		return false
+	}
+	rest = strings.TrimSuffix(rest, ".log")
+	if rest == "" {
+		return true
+	}
+	if rest[0] != '-' && rest[0] != '.' {
+		return false
+	}
+	for _, c := range rest[1:] {
+		if (c < '0' || c > '9') && c != '.' {
+			return false
+		}
+	}
+	return len(rest) > 1
+}
+
+// compressFile gzips the file and removes it, the archive keeps its
+// modification time for the retention by age.
+func compressFile(path string, fi os.FileInfo) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	gzPath := path + compressSuffix
+	gzf, err := os.OpenFile(gzPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, fi.Mode())
+	if err != nil {
+		return err
+	}
+	zw := gzip.NewWriter(gzf)
+	if _, err = io.Copy(zw, f); err == nil {
+		err = zw.Close()
+	}
+	if cerr := gzf.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(gzPath)
+		return err
+	}
+	os.Chtimes(gzPath, fi.ModTime(), fi.ModTime())
+	return os.Remove(path)
+}
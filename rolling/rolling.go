@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -25,9 +26,16 @@ type RollingFile struct {
 	basePath string
 	filePath string
 	fileFrag string
+	fileSize int64
+	// the index of the file in the time fragment, it grows by size rotation
+	fileIndex int
 
 	rollMutex sync.RWMutex
 	rolling   RollingFormat
+	policy    Policy
+	// the file being written, the mill routine leaves it alone
+	active string
+	millCh chan struct{}
 }
 
 var ErrClosedRollingFile = errors.New("rolling file is closed")
@@ -54,17 +62,34 @@ func (r *RollingFile) SetRolling(fmt RollingFormat) {
 	r.rollMutex.Unlock()
 }
 
-func (r *RollingFile) roll() error {
+// SetPolicy sets the size rotation and the retention of the rotated files,
+// it applies from the next write.
+func (r *RollingFile) SetPolicy(p Policy) {
+	r.rollMutex.Lock()
+	r.policy = p
+	r.rollMutex.Unlock()
+	r.mill()
+}
+
+// roll opens the file to write n more bytes to, a new file is opened when the
+// time fragment changes or the current file would exceed the max size.
+func (r *RollingFile) roll(n int) error {
 	r.rollMutex.RLock()
-	roll := r.rolling
+	roll, policy := r.rolling, r.policy
 	r.rollMutex.RUnlock()
 	suffix := time.Now().Format(string(roll))
 	if r.file != nil {
-		if suffix == r.fileFrag {
+		if suffix == r.fileFrag && !policy.full(r.fileSize, n) {
 			return nil
 		}
 		r.file.Close()
 		r.file = nil
+		if suffix == r.fileFrag {
+			r.fileIndex++
+		}
+	}
+	if suffix != r.fileFrag {
+		r.fileIndex = 0
 	}
 	r.fileFrag = suffix
 	dir, filename := filepath.Split(r.basePath)
@@ -73,19 +98,49 @@ func (r *RollingFile) roll() error {
 			return err
 		}
 	}
-	if r.fileFrag == "" {
-		r.filePath = filepath.Join(dir, filename+".log")
-	} else {
-		r.filePath = filepath.Join(dir, filename+"-"+r.fileFrag+".log")
+	// skip the files of this fragment filled before a restart
+	for {
+		r.filePath = r.fragPath(r.fileIndex)
+		r.fileSize = 0
+		if fi, err := os.Stat(r.filePath); err == nil {
+			r.fileSize = fi.Size()
+		} else if _, err := os.Stat(r.filePath + compressSuffix); err == nil {
+			r.fileIndex++
+			continue
+		}
+		if r.fileSize == 0 || !policy.full(r.fileSize, n) {
+			break
+		}
+		r.fileIndex++
 	}
 	f, err := os.OpenFile(r.filePath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
 	if err != nil {
 		return err
 	}
 	r.file = f
+	r.rollMutex.Lock()
+	r.active = r.filePath
+	r.rollMutex.Unlock()
+	if policy.Symlink {
+		r.createSymLink(filepath.Base(r.filePath), filepath.Join(dir, filename+symlinkSuffix))
+	}
+	r.mill()
 	return nil
 }
 
+// fragPath returns the path of the file of the current time fragment with
+// the index, like base-20060102.log, base-20060102.1.log.
+func (r *RollingFile) fragPath(index int) string {
+	name := r.basePath
+	if r.fileFrag != "" {
+		name += "-" + r.fileFrag
+	}
+	if index > 0 {
+		name += "." + strconv.Itoa(index)
+	}
+	return name + ".log"
+}
+
 func (r *RollingFile) createSymLink(real, sym string) {
 	if _, err := os.Lstat(sym); err == nil {
 		os.Remove(sym)
@@ -146,9 +201,10 @@ func (r *RollingFile) Sync() error {
 
 func (r *RollingFile) writeBuffer(buff *bytes.Buffer) {
 	if buff != nil && buff.Len() > 0 {
-		if err := r.roll(); err != nil {
+		if err := r.roll(buff.Len()); err != nil {
 		} else {
-			buff.WriteTo(r.file)
+			n, _ := buff.WriteTo(r.file)
+			r.fileSize += n
 		}
 	}
 }
@@ -226,8 +282,10 @@ func NewRollingFile(basePath string, rolling RollingFormat) (*RollingFile, error
 		closed:     false,
 		fullBuffer: make(chan *bytes.Buffer, logPageNumber+1),
 		current:    getBuffer(),
+		millCh:     make(chan struct{}, 1),
 	}
 	// fill ready buffer
 	go r.flushRoutine()
+	go r.millRoutine()
 	return r, nil
 }
@@ -0,0 +1,102 @@
+package rolling
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRollingFilePolicy(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rolling")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	r, err := NewRollingFile(filepath.Join(dir, "app.log"), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	r.SetPolicy(Policy{MaxSize: 10, MaxBackups: 1, Compress: true, Symlink: true})
+	for i := 0; i < 3; i++ {
+		if _, err := r.Write([]byte("012345678\n")); err != nil {
+			t.Fatal(err)
+		}
+		r.Sync()
+	}
+
+	// the first file is removed, the second one is compressed by the mill
+	want := []string{"app-latest.log", "app.1.log.gz", "app.2.log"}
+	var got []string
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		got = readDir(t, dir)
+		if strings.Join(got, ",") == strings.Join(want, ",") {
+			break
+		}
+	}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Fatalf("got files %v, want %v", got, want)
+	}
+	if link, err := os.Readlink(filepath.Join(dir, "app-latest.log")); err != nil || link != "app.2.log" {
+		t.Fatalf("got symlink to %q, %v", link, err)
+	}
+}
+
+func TestRollingFileRestart(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rolling")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := ioutil.WriteFile(filepath.Join(dir, "app.log"), []byte("0123456789"), 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := NewRollingFile(filepath.Join(dir, "app"), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.SetPolicy(Policy{MaxSize: 10})
+	r.Write([]byte("a\n"))
+	r.Sync()
+	r.Close()
+
+	if got := readDir(t, dir); strings.Join(got, ",") != "app.1.log,app.log" {
+		t.Fatalf("got files %v", got)
+	}
+}
+
+func TestIsBackup(t *testing.T) {
+	for rest, want := range map[string]bool{
+		".log":              true,
+		".1.log":            true,
+		"-20060102.log":     true,
+		"-20060102.3.log":   true,
+		"-20060102.log.gz":  true,
+		"-latest.log":       false,
+		"2-20060102.log":    false,
+		"-20060102.log.bak": false,
+	} {
+		if got := isBackup(rest); got != want {
+			t.Errorf("isBackup(%q) = %v, want %v", rest, got, want)
+		}
+	}
+}
+
+func readDir(t *testing.T, dir string) []string {
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, fi := range infos {
+		names = append(names, fi.Name())
+	}
+	sort.Strings(names)
+	return names
+}
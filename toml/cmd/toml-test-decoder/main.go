@@ -0,0 +1,27 @@
+// Command toml-test-decoder reads a TOML document from stdin and writes it as
+// the tagged JSON of toml-test to stdout, to run the toml-test suite against
+// the toml package:
+//
+//	toml-test toml-test-decoder
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"os"
+
+	"github.com/yunfeiyang1916/toolkit/toml"
+	"github.com/yunfeiyang1916/toolkit/toml/internal/tag"
+)
+
+func main() {
+	log.SetFlags(0)
+
+	var v interface{}
+	if _, err := toml.DecodeReader(os.Stdin, &v); err != nil {
+		log.Fatalf("Error decoding TOML: %s", err)
+	}
+	if err := json.NewEncoder(os.Stdout).Encode(tag.Add(v)); err != nil {
+		log.Fatalf("Error encoding JSON: %s", err)
+	}
+}
@@ -0,0 +1,31 @@
+// Command toml-test-encoder reads the tagged JSON of toml-test from stdin and
+// writes it as a TOML document to stdout, to run the toml-test suite against
+// the toml package:
+//
+//	toml-test -encoder toml-test-encoder
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"os"
+
+	"github.com/yunfeiyang1916/toolkit/toml"
+	"github.com/yunfeiyang1916/toolkit/toml/internal/tag"
+)
+
+func main() {
+	log.SetFlags(0)
+
+	var tagged interface{}
+	if err := json.NewDecoder(os.Stdin).Decode(&tagged); err != nil {
+		log.Fatalf("Error decoding JSON: %s", err)
+	}
+	v, err := tag.Remove(tagged)
+	if err != nil {
+		log.Fatalf("Error decoding JSON: %s", err)
+	}
+	if err := toml.NewEncoder(os.Stdout).Encode(v); err != nil {
+		log.Fatalf("Error encoding TOML: %s", err)
+	}
+}
@@ -199,6 +199,12 @@ func (md *MetaData) unify(data interface{}, rv reflect.Value) error {
 	if rv.Type().AssignableTo(rvalue(time.Time{}).Type()) {
 		return md.unifyDatetime(data, rv)
 	}
+	// indirect returns a *time.Time since it is a TextUnmarshaler, but the
+	// text of a local datetime, date or time loses its location.
+	if rv.Kind() == reflect.Ptr &&
+		rv.Type().Elem() == rvalue(time.Time{}).Type() {
+		return md.unifyDatetime(data, rv.Elem())
+	}
 
 	// Special case. Look for a value satisfying the TextUnmarshaler interface.
 	if v, ok := rv.Interface().(TextUnmarshaler); ok {
@@ -97,7 +97,7 @@ func (k Key) maybeQuotedAll() string {
 }
 
 func (k Key) maybeQuoted(i int) string {
-	quote := false
+	quote := len(k[i]) == 0
 	for _, c := range k[i] {
 		if !isBareKeyChar(c) {
 			quote = true
@@ -105,7 +105,7 @@ func (k Key) maybeQuoted(i int) string {
 		}
 	}
 	if quote {
-		return "\"" + strings.Replace(k[i], "\"", "\\\"", -1) + "\""
+		return quoteString(k[i])
 	}
 	return k[i]
 }
@@ -1459,3 +1459,126 @@ type cable struct {
 func (c *cable) Name() string {
 	return fmt.Sprintf("CABLE: %s", c.ID)
 }
+
+func TestDecodeDottedKeys(t *testing.T) {
+	const input = `
+a.b = 1
+a."c d".e = 2
+[t]
+x.y = 3
+inline = { p.q = 4, r = 5 }
+`
+	var v map[string]interface{}
+	md, err := Decode(input, &v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{
+		"a": map[string]interface{}{
+			"b":   int64(1),
+			"c d": map[string]interface{}{"e": int64(2)},
+		},
+		"t": map[string]interface{}{
+			"x": map[string]interface{}{"y": int64(3)},
+			"inline": map[string]interface{}{
+				"p": map[string]interface{}{"q": int64(4)},
+				"r": int64(5),
+			},
+		},
+	}
+	if !reflect.DeepEqual(v, want) {
+		t.Fatalf("got %v, want %v", v, want)
+	}
+	if typ := md.Type("a", "c d"); typ != "Hash" {
+		t.Errorf("got type %q of a dotted table", typ)
+	}
+
+	for _, s := range []string{
+		"a = 1\na.b = 2",
+		"a.b = 1\n[a]\nc = 2",
+		"[a]\nb = 1\n[x]\na.c = 2\n[a.b]",
+		"a = {b = 1}\na.c = 2",
+		"a = {b = 1}\n[a.c]",
+		"a = {b.c = 1, b = 2}",
+		"a = {b = 1,}",
+	} {
+		if _, err := Decode(s, &v); err == nil {
+			t.Errorf("Decode(%q) succeeded", s)
+		}
+	}
+}
+
+func TestDecodeTOML10Values(t *testing.T) {
+	const input = `
+hex = 0xdead_beef
+oct = 0o755
+bin = 0b1101
+inf = -inf
+mixed = [1, "a", {b = 2}]
+empty = {}
+ldt = 1979-05-27 07:32:00.5
+ld = 1979-05-27
+lt = 07:32:00
+str = """a ""quoted"" \
+      string"""
+`
+	var v struct {
+		Hex, Oct, Bin int64
+		Inf           float64
+		Mixed         []interface{}
+		Empty         map[string]interface{}
+		Ldt, Ld, Lt   time.Time
+		Str           string
+	}
+	md, err := Decode(input, &v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.Hex != 0xdeadbeef || v.Oct != 0755 || v.Bin != 13 {
+		t.Errorf("got integers %d, %d, %d", v.Hex, v.Oct, v.Bin)
+	}
+	if !math.IsInf(v.Inf, -1) {
+		t.Errorf("got %v, want -inf", v.Inf)
+	}
+	mixed := []interface{}{int64(1), "a", map[string]interface{}{"b": int64(2)}}
+	if !reflect.DeepEqual(v.Mixed, mixed) {
+		t.Errorf("got %v, want %v", v.Mixed, mixed)
+	}
+	if v.Empty == nil || len(v.Empty) != 0 {
+		t.Errorf("got %v, want an empty table", v.Empty)
+	}
+	if v.Str != `a ""quoted"" string` {
+		t.Errorf("got %q", v.Str)
+	}
+
+	for _, tt := range []struct {
+		key, typ, local string
+		t               time.Time
+	}{
+		{"ldt", "DatetimeLocal", LocalDatetime, v.Ldt},
+		{"ld", "DateLocal", LocalDate, v.Ld},
+		{"lt", "TimeLocal", LocalTime, v.Lt},
+	} {
+		if typ := md.Type(tt.key); typ != tt.typ {
+			t.Errorf("got type %q of %s, want %q", typ, tt.key, tt.typ)
+		}
+		if local := LocalType(tt.t); local != tt.local {
+			t.Errorf("got local type %q of %s, want %q", local, tt.key, tt.local)
+		}
+	}
+	if got := v.Ldt.Format("2006-01-02T15:04:05.9"); got != "1979-05-27T07:32:00.5" {
+		t.Errorf("got %s", got)
+	}
+	if got := v.Lt.Format("15:04:05"); got != "07:32:00" {
+		t.Errorf("got %s", got)
+	}
+
+	for _, s := range []string{
+		"a = 0x", "a = 012", "a = 0.1_", "a = 01.5", "a = +true",
+		"a = 1979-05-27T7:32:00", "a = \"\x01\"", "a = 1\rb = 2",
+	} {
+		if _, err := Decode(s, &v); err == nil {
+			t.Errorf("Decode(%q) succeeded", s)
+		}
+	}
+}
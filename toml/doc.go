@@ -4,7 +4,7 @@ files via reflection. There is also support for delaying decoding with
 the Primitive type, and querying the set of keys in a TOML document with the
 MetaData type.
 
-The specification implemented: https://github.com/toml-lang/toml
+The specification implemented: https://toml.io/en/v1.0.0
 
 The sub-command github.com/BurntSushi/toml/cmd/tomlv can be used to verify
 whether a file is a valid TOML document. It can also be used to print the
@@ -19,7 +19,8 @@ decoder and encoder.
 
 The second type of testing is used to verify the implementation's adherence
 to the TOML specification. These tests have been factored into their own
-project: https://github.com/BurntSushi/toml-test
+project: https://github.com/toml-lang/toml-test, TestSuite runs them when
+TOML_TEST_DIR is set to its tests directory.
 
 The reason the tests are in a separate project is so that they can be used by
 any implementation of TOML. Namely, it is language agnostic.
@@ -0,0 +1,790 @@
+package toml
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Document is a TOML document that can be edited and written back keeping the
+// comments, whitespace and key order of the original text. Only the edited
+// values and the added keys and tables are formatted by the encoder.
+//
+// The keys of a Document are full key paths, an element of an array of
+// tables is indexed by its position, e.g.
+//
+//	// [[fruit]]
+//	// name = "apple"
+//	doc.Get(Key{"fruit", "0", "name"})
+//
+// Every edit is checked by the parser, an edit that would make the document
+// invalid returns an error and leaves the document unchanged.
+type Document struct {
+	nl       string     // the newline of the original text
+	sections []*section // the root table is the first one
+	arrays   map[string]int
+}
+
+type lineKind int
+
+const (
+	lineTrivia lineKind = iota // a blank or comment line
+	lineKeyValue
+)
+
+// line is a trivia or a key/value, a key/value may span multiple lines.
+type line struct {
+	kind   lineKind
+	key    Key    // relative to the table of the section
+	prefix string // the indentation, key and equals sign
+	value  string // the raw value
+	suffix string // the comment and newline after the value, the whole trivia
+}
+
+func (l *line) String() string {
+	return l.prefix + l.value + l.suffix
+}
+
+// section is a table header and the lines up to the next header.
+type section struct {
+	key    Key // nil for the root table
+	array  bool
+	header string // the raw header line
+	path   Key    // the key with the indexes of the arrays of tables
+	lines  []*line
+}
+
+// ParseDocument parses a TOML document for editing.
+func ParseDocument(data []byte) (*Document, error) {
+	if _, err := parse(string(data)); err != nil {
+		return nil, err
+	}
+	d := &Document{}
+	if err := d.load(string(data)); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// Bytes returns the text of the document.
+func (d *Document) Bytes() []byte {
+	return []byte(d.String())
+}
+
+// String returns the text of the document.
+func (d *Document) String() string {
+	var b strings.Builder
+	for _, sec := range d.sections {
+		b.WriteString(sec.header)
+		for _, l := range sec.lines {
+			b.WriteString(l.String())
+		}
+	}
+	return b.String()
+}
+
+// Decode decodes the document like Decode.
+func (d *Document) Decode(v interface{}) (MetaData, error) {
+	return Decode(d.String(), v)
+}
+
+// Get returns the value of the key, a table is a map[string]interface{} and
+// an array of tables is a []map[string]interface{}. The values are the ones
+// decoded into an interface{}.
+func (d *Document) Get(key Key) (interface{}, bool) {
+	var m map[string]interface{}
+	if _, err := Decode(d.String(), &m); err != nil {
+		return nil, false
+	}
+	var v interface{} = m
+	for _, k := range key {
+		switch node := v.(type) {
+		case map[string]interface{}:
+			vv, ok := node[k]
+			if !ok {
+				return nil, false
+			}
+			v = vv
+		case []map[string]interface{}:
+			i, err := strconv.Atoi(k)
+			if err != nil || i < 0 || i >= len(node) {
+				return nil, false
+			}
+			v = node[i]
+		case []interface{}:
+			i, err := strconv.Atoi(k)
+			if err != nil || i < 0 || i >= len(node) {
+				return nil, false
+			}
+			v = node[i]
+		default:
+			return nil, false
+		}
+	}
+	return v, true
+}
+
+// Set sets the value of the key. The raw value of an existing key is replaced
+// keeping its comment, a new key is added after the last key of its table and
+// the table is added when it doesn't exist. Use InsertTable and
+// AppendArrayTable to add the tables.
+func (d *Document) Set(key Key, v interface{}) error {
+	if len(key) == 0 {
+		return e("cannot set an empty key")
+	}
+	raw, err := encodeValue(v)
+	if err != nil {
+		return err
+	}
+	return d.edit(func() error {
+		if sec, i := d.find(key); sec != nil {
+			sec.lines[i].value = raw
+			return nil
+		}
+		for i := 1; i < len(key); i++ {
+			if sec, _ := d.find(key[:i]); sec != nil {
+				return e("key '%s' is inside the value of '%s'", key, key[:i])
+			}
+		}
+		if cur, ok := d.Get(key); ok {
+			switch cur.(type) {
+			case map[string]interface{}, []map[string]interface{}:
+				return e("key '%s' is a table", key)
+			}
+			return e("key '%s' is not a key/value", key)
+		}
+
+		parent := key[:len(key)-1]
+		if sec := d.dottedSection(parent); sec != nil {
+			sec.insert(d, key[len(sec.path):], raw)
+			return nil
+		}
+		sec := d.section(parent)
+		if sec == nil {
+			if err := d.insertTable(parent, false); err != nil {
+				return err
+			}
+			sec = d.section(parent)
+		}
+		sec.insert(d, key[len(key)-1:], raw)
+		return nil
+	})
+}
+
+// Delete deletes the key/value or the table of the key, with the comment lines
+// right above it, a blank line ends the comments that go with it. Deleting an
+// array of tables deletes all its elements.
+func (d *Document) Delete(key Key) error {
+	if len(key) == 0 {
+		return e("cannot delete an empty key")
+	}
+	return d.edit(func() error {
+		var found bool
+		for _, sec := range d.sections {
+			for i := 0; i < len(sec.lines); i++ {
+				l := sec.lines[i]
+				if l.kind != lineKeyValue || !keyHasPrefix(sec.path.join(l.key), key) {
+					continue
+				}
+				start := sec.leading(i)
+				sec.lines = append(sec.lines[:start], sec.lines[i+1:]...)
+				i = start - 1
+				found = true
+			}
+		}
+		for i := len(d.sections) - 1; i > 0; i-- {
+			sec := d.sections[i]
+			if !keyHasPrefix(sec.path, key) {
+				continue
+			}
+			// the comments right above the header go with the table, the
+			// ones at the end of the table stay for the next header
+			prev := d.sections[i-1]
+			prev.lines = append(prev.lines[:prev.leading(len(prev.lines))], sec.lines[sec.leading(len(sec.lines)):]...)
+			d.sections = append(d.sections[:i], d.sections[i+1:]...)
+			found = true
+		}
+		if !found {
+			return e("key '%s' not found", key)
+		}
+		return nil
+	})
+}
+
+// InsertTable adds the table header of the key, after the last table under
+// the parent of the key. The key of a sub table of an array of tables has the
+// index of the element, e.g. fruit.0.physical.
+func (d *Document) InsertTable(key Key) error {
+	if len(key) == 0 {
+		return e("cannot insert an empty table")
+	}
+	return d.edit(func() error {
+		return d.insertTable(key, false)
+	})
+}
+
+// AppendArrayTable adds an element to the array of tables of the key, after
+// its last element, and returns the index of the new element.
+func (d *Document) AppendArrayTable(key Key) (int, error) {
+	if len(key) == 0 {
+		return 0, e("cannot append to an empty array of tables")
+	}
+	var idx int
+	err := d.edit(func() error {
+		idx = d.arrays[key.id()]
+		return d.insertTable(key, true)
+	})
+	return idx, err
+}
+
+// edit applies fn and checks the document, the document is restored when fn
+// fails or leaves the document invalid.
+func (d *Document) edit(fn func() error) error {
+	old := d.String()
+	err := fn()
+	if err == nil {
+		_, err = parse(d.String())
+	}
+	if err != nil {
+		if lerr := d.load(old); lerr != nil {
+			return lerr
+		}
+		return err
+	}
+	d.index()
+	return nil
+}
+
+func (d *Document) insertTable(key Key, array bool) error {
+	if !array && d.section(key) != nil {
+		return e("table '%s' already exists", key)
+	}
+	header, err := d.headerKey(key)
+	if err != nil {
+		return err
+	}
+
+	// keep the tables of a parent together
+	at := len(d.sections)
+	start := key[:len(key)-1]
+	if array {
+		start = key
+	}
+	for p := start; len(p) > 0; p = p[:len(p)-1] {
+		if last := d.lastUnder(p); last > 0 {
+			at = last + 1
+			break
+		}
+	}
+
+	sec := &section{key: header, array: array}
+	prev := d.sections[at-1]
+	if at < len(d.sections) {
+		// the blank and comment lines at the end of the previous table are
+		// the ones of the next header
+		i := len(prev.lines)
+		for i > 0 && prev.lines[i-1].kind == lineTrivia {
+			i--
+		}
+		sec.lines = prev.lines[i:len(prev.lines):len(prev.lines)]
+		prev.lines = prev.lines[:i]
+		if len(sec.lines) == 0 || !sec.lines[0].blank() {
+			sec.lines = append([]*line{{kind: lineTrivia, suffix: d.nl}}, sec.lines...)
+		}
+	}
+	prev.terminate(d.nl)
+	if n := len(prev.lines); (n > 0 && !prev.lines[n-1].blank()) || (n == 0 && at > 1) {
+		prev.lines = append(prev.lines, &line{kind: lineTrivia, suffix: d.nl})
+	}
+	if array {
+		sec.header = "[[" + header.quoted() + "]]" + d.nl
+	} else {
+		sec.header = "[" + header.quoted() + "]" + d.nl
+	}
+	d.sections = append(d.sections[:at], append([]*section{sec}, d.sections[at:]...)...)
+	d.index()
+	return nil
+}
+
+// headerKey returns the key of a table header, the key without the indexes
+// of the arrays of tables.
+func (d *Document) headerKey(key Key) (Key, error) {
+	var header Key
+	for i := 0; i < len(key); i++ {
+		header = append(header, key[i])
+		n, ok := d.arrays[key[:i+1].id()]
+		if !ok || i == len(key)-1 {
+			continue
+		}
+		if idx, err := strconv.Atoi(key[i+1]); err != nil || idx < 0 || idx >= n {
+			return nil, e("array of tables '%s' has no element '%s'", key[:i+1], key[i+1])
+		}
+		if i++; i == len(key)-1 {
+			return nil, e("key '%s' is an element of an array of tables", key)
+		}
+	}
+	return header, nil
+}
+
+// find returns the section and the line index of the key/value of the key.
+func (d *Document) find(key Key) (*section, int) {
+	for _, sec := range d.sections {
+		if !keyHasPrefix(key, sec.path) {
+			continue
+		}
+		for i, l := range sec.lines {
+			if l.kind == lineKeyValue && keyEqual(sec.path.join(l.key), key) {
+				return sec, i
+			}
+		}
+	}
+	return nil, -1
+}
+
+// dottedSection returns the section which defines the table key with dotted
+// keys.
+func (d *Document) dottedSection(key Key) *section {
+	for _, sec := range d.sections {
+		if len(key) <= len(sec.path) || !keyHasPrefix(key, sec.path) {
+			continue
+		}
+		for _, l := range sec.lines {
+			full := sec.path.join(l.key)
+			if l.kind == lineKeyValue && len(full) > len(key) && keyHasPrefix(full, key) {
+				return sec
+			}
+		}
+	}
+	return nil
+}
+
+// section returns the section of the table of the key, the root table is the
+// empty key.
+func (d *Document) section(key Key) *section {
+	for _, sec := range d.sections {
+		if keyEqual(sec.path, key) {
+			return sec
+		}
+	}
+	return nil
+}
+
+// lastUnder returns the index of the last section under the key, or 0.
+func (d *Document) lastUnder(key Key) int {
+	for i := len(d.sections) - 1; i > 0; i-- {
+		if keyHasPrefix(d.sections[i].path, key) {
+			return i
+		}
+	}
+	return 0
+}
+
+// index sets the paths of the sections and counts the arrays of tables.
+func (d *Document) index() {
+	d.arrays = make(map[string]int)
+	for _, sec := range d.sections[1:] {
+		var path Key
+		for i, k := range sec.key {
+			path = append(path, k)
+			id := path.id()
+			if i == len(sec.key)-1 && sec.array {
+				path = append(path, strconv.Itoa(d.arrays[id]))
+				d.arrays[id]++
+			} else if n, ok := d.arrays[id]; ok {
+				path = append(path, strconv.Itoa(n-1))
+			}
+		}
+		sec.path = path
+	}
+}
+
+func (d *Document) load(data string) error {
+	d.nl = "\n"
+	if strings.Contains(data, "\r\n") {
+		d.nl = "\r\n"
+	}
+	cur := &section{}
+	d.sections = []*section{cur}
+	sc := &scanner{s: data}
+	for !sc.eof() {
+		start := sc.pos
+		sc.skipSpace()
+		switch c := sc.peek(); c {
+		case 0, '\r', '\n', '#':
+			sc.lineEnd()
+			cur.lines = append(cur.lines, &line{kind: lineTrivia, suffix: data[start:sc.pos]})
+		case '[':
+			sc.pos++
+			array := sc.peek() == '['
+			if array {
+				sc.pos++
+			}
+			key, err := sc.key()
+			if err != nil {
+				return err
+			}
+			if err := sc.expect(']'); err != nil {
+				return err
+			}
+			if array {
+				if err := sc.expect(']'); err != nil {
+					return err
+				}
+			}
+			sc.lineEnd()
+			cur = &section{key: key, array: array, header: data[start:sc.pos]}
+			d.sections = append(d.sections, cur)
+		default:
+			key, err := sc.key()
+			if err != nil {
+				return err
+			}
+			if err := sc.expect('='); err != nil {
+				return err
+			}
+			sc.skipSpace()
+			vstart := sc.pos
+			if err := sc.value(); err != nil {
+				return err
+			}
+			vend := sc.pos
+			sc.lineEnd()
+			cur.lines = append(cur.lines, &line{
+				kind:   lineKeyValue,
+				key:    key,
+				prefix: data[start:vstart],
+				value:  data[vstart:vend],
+				suffix: data[vend:sc.pos],
+			})
+		}
+	}
+	d.index()
+	return nil
+}
+
+// insert adds a key/value after the last key/value of the section.
+func (sec *section) insert(d *Document, key Key, raw string) {
+	at, indent := -1, ""
+	for i, l := range sec.lines {
+		if l.kind == lineKeyValue {
+			at = i + 1
+			indent = l.prefix[:len(l.prefix)-len(strings.TrimLeft(l.prefix, " \t"))]
+		}
+	}
+	if at < 0 {
+		at = 0
+		if sec.key == nil {
+			// keep the comments of the first header above it
+			at = sec.leading(len(sec.lines))
+		}
+		indent = sec.header[:len(sec.header)-len(strings.TrimLeft(sec.header, " \t"))]
+	}
+	if at > 0 {
+		if l := sec.lines[at-1]; !strings.HasSuffix(l.suffix, "\n") {
+			l.suffix += d.nl
+		}
+	} else if len(sec.header) > 0 && !strings.HasSuffix(sec.header, "\n") {
+		sec.header += d.nl
+	}
+	l := &line{kind: lineKeyValue, key: key, prefix: indent + key.quoted() + " = ", value: raw, suffix: d.nl}
+	sec.lines = append(sec.lines[:at], append([]*line{l}, sec.lines[at:]...)...)
+}
+
+// leading returns the index of the comment lines right above the line i, the
+// comments before a blank line are not attached to the line i.
+func (sec *section) leading(i int) int {
+	for i > 0 && sec.lines[i-1].comment() {
+		i--
+	}
+	return i
+}
+
+// terminate ends the section with a newline.
+func (sec *section) terminate(nl string) {
+	if n := len(sec.lines); n > 0 {
+		if l := sec.lines[n-1]; !strings.HasSuffix(l.suffix, "\n") {
+			l.suffix += nl
+		}
+	} else if len(sec.header) > 0 && !strings.HasSuffix(sec.header, "\n") {
+		sec.header += nl
+	}
+}
+
+func (l *line) comment() bool {
+	return l.kind == lineTrivia && strings.HasPrefix(strings.TrimLeft(l.suffix, " \t"), "#")
+}
+
+func (l *line) blank() bool {
+	return l.kind == lineTrivia && len(strings.TrimSpace(l.suffix)) == 0
+}
+
+// encodeValue returns the raw TOML of a value that is not a table.
+func encodeValue(v interface{}) (raw string, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			if terr, ok := r.(tomlEncodeError); ok {
+				err = terr.error
+				return
+			}
+			panic(r)
+		}
+	}()
+	rv := eindirect(reflect.ValueOf(v))
+	switch typ := tomlTypeOfGo(rv); {
+	case typ == nil:
+		return "", e("cannot set a nil value")
+	case typeEqual(typ, tomlHash), typeEqual(typ, tomlArrayHash):
+		return "", e("cannot set a table as a value, use InsertTable or AppendArrayTable")
+	}
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf)
+	enc.eElement(rv)
+	if err := enc.w.Flush(); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// id returns the key as a map key.
+func (k Key) id() string {
+	return strings.Join(k, "\x00")
+}
+
+func (k Key) join(sub Key) Key {
+	key := make(Key, 0, len(k)+len(sub))
+	return append(append(key, k...), sub...)
+}
+
+// quoted returns the key with the parts quoted when needed.
+func (k Key) quoted() string {
+	return k.maybeQuotedAll()
+}
+
+func keyEqual(a, b Key) bool {
+	return len(a) == len(b) && keyHasPrefix(a, b)
+}
+
+func keyHasPrefix(k, prefix Key) bool {
+	if len(k) < len(prefix) {
+		return false
+	}
+	for i := range prefix {
+		if k[i] != prefix[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// scanner finds the extent of the keys and values of a valid document.
+type scanner struct {
+	s   string
+	pos int
+}
+
+var errUnterminated = errors.New("toml: unterminated value")
+
+func (sc *scanner) eof() bool {
+	return sc.pos >= len(sc.s)
+}
+
+func (sc *scanner) peek() byte {
+	if sc.eof() {
+		return 0
+	}
+	return sc.s[sc.pos]
+}
+
+func (sc *scanner) skipSpace() {
+	for !sc.eof() && (sc.s[sc.pos] == ' ' || sc.s[sc.pos] == '\t') {
+		sc.pos++
+	}
+}
+
+// skipTrivia skips the whitespace, newlines and comments in an array.
+func (sc *scanner) skipTrivia() {
+	for !sc.eof() {
+		switch sc.s[sc.pos] {
+		case ' ', '\t', '\r', '\n':
+			sc.pos++
+		case '#':
+			for !sc.eof() && sc.s[sc.pos] != '\n' {
+				sc.pos++
+			}
+		default:
+			return
+		}
+	}
+}
+
+// lineEnd moves past the comment and the newline of the line.
+func (sc *scanner) lineEnd() {
+	for !sc.eof() && sc.s[sc.pos] != '\n' {
+		sc.pos++
+	}
+	if !sc.eof() {
+		sc.pos++
+	}
+}
+
+func (sc *scanner) expect(c byte) error {
+	sc.skipSpace()
+	if sc.peek() != c {
+		return e("expected '%c' at offset %d", c, sc.pos)
+	}
+	sc.pos++
+	return nil
+}
+
+func (sc *scanner) key() (Key, error) {
+	var key Key
+	for {
+		sc.skipSpace()
+		start := sc.pos
+		var k string
+		switch sc.peek() {
+		case '"':
+			if err := sc.str(); err != nil {
+				return nil, err
+			}
+			s, err := strconv.Unquote(sc.s[start:sc.pos])
+			if err != nil {
+				return nil, e("bad quoted key %s", sc.s[start:sc.pos])
+			}
+			k = s
+		case '\'':
+			if err := sc.str(); err != nil {
+				return nil, err
+			}
+			k = sc.s[start+1 : sc.pos-1]
+		default:
+			for !sc.eof() && isBareKeyChar(rune(sc.s[sc.pos])) {
+				sc.pos++
+			}
+			if sc.pos == start {
+				return nil, e("expected a key at offset %d", sc.pos)
+			}
+			k = sc.s[start:sc.pos]
+		}
+		key = append(key, k)
+		sc.skipSpace()
+		if sc.peek() != '.' {
+			return key, nil
+		}
+		sc.pos++
+	}
+}
+
+// str moves past a string of any kind.
+func (sc *scanner) str() error {
+	q := sc.s[sc.pos]
+	delim := strings.Repeat(string(q), 3)
+	if strings.HasPrefix(sc.s[sc.pos:], delim) {
+		sc.pos += 3
+		for !sc.eof() {
+			if q == '"' && sc.s[sc.pos] == '\\' {
+				sc.pos += 2
+				continue
+			}
+			if strings.HasPrefix(sc.s[sc.pos:], delim) {
+				sc.pos += 3
+				// up to two quotes can be right before the delimiter
+				for i := 0; i < 2 && sc.peek() == q; i++ {
+					sc.pos++
+				}
+				return nil
+			}
+			sc.pos++
+		}
+		return errUnterminated
+	}
+	sc.pos++
+	for !sc.eof() {
+		switch c := sc.s[sc.pos]; {
+		case c == '\\' && q == '"':
+			sc.pos += 2
+			continue
+		case c == q:
+			sc.pos++
+			return nil
+		case c == '\n':
+			return errUnterminated
+		}
+		sc.pos++
+	}
+	return errUnterminated
+}
+
+// value moves past a value.
+func (sc *scanner) value() error {
+	switch sc.peek() {
+	case '"', '\'':
+		return sc.str()
+	case '[':
+		sc.pos++
+		for {
+			sc.skipTrivia()
+			switch sc.peek() {
+			case 0:
+				return errUnterminated
+			case ']':
+				sc.pos++
+				return nil
+			}
+			if err := sc.value(); err != nil {
+				return err
+			}
+			sc.skipTrivia()
+			if sc.peek() == ',' {
+				sc.pos++
+			}
+		}
+	case '{':
+		sc.pos++
+		for {
+			sc.skipSpace()
+			if sc.peek() == '}' {
+				sc.pos++
+				return nil
+			}
+			if _, err := sc.key(); err != nil {
+				return err
+			}
+			if err := sc.expect('='); err != nil {
+				return err
+			}
+			sc.skipSpace()
+			if err := sc.value(); err != nil {
+				return err
+			}
+			sc.skipSpace()
+			if sc.peek() == ',' {
+				sc.pos++
+			}
+		}
+	}
+
+	// a number, bool or datetime, the date and time may be separated by a space
+	start := sc.pos
+	for !sc.eof() {
+		c := sc.s[sc.pos]
+		if c == ' ' && isDate(sc.s[start:sc.pos]) && sc.pos+1 < len(sc.s) && isDigit(rune(sc.s[sc.pos+1])) {
+			sc.pos++
+			continue
+		}
+		if c == ' ' || c == '\t' || c == '\r' || c == '\n' || c == '#' || c == ',' || c == ']' || c == '}' {
+			break
+		}
+		sc.pos++
+	}
+	if sc.pos == start {
+		return e("expected a value at offset %d", sc.pos)
+	}
+	return nil
+}
+
+func isDate(s string) bool {
+	return len(s) == 10 && s[4] == '-' && s[7] == '-'
+}
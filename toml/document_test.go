@@ -0,0 +1,291 @@
+package toml
+
+import (
+	"reflect"
+	"testing"
+)
+
+const testDocument = `# the service config
+title = "demo"   # the title
+
+# the server
+[server]
+  port = 8080 # the port
+  hosts = [
+    "a", # the first
+    "b",
+  ]
+  desc = """
+multi "line" """
+  start = 1979-05-27T07:32:00Z
+  inline = { x = 1, y = "}" }
+
+# the clients
+[[client]]
+name = "a"
+
+[client.physical]
+color = "red"
+
+[[client]]
+name = "b"
+`
+
+func TestDocumentRoundTrip(t *testing.T) {
+	for _, s := range []string{testDocument, "", "a = 1", "# only a comment", "a = 1\r\n[b]\r\nc = 'x'\r\n"} {
+		doc, err := ParseDocument([]byte(s))
+		if err != nil {
+			t.Fatalf("%q: %v", s, err)
+		}
+		if got := doc.String(); got != s {
+			t.Fatalf("round trip got\n%s\nwant\n%s", got, s)
+		}
+	}
+	if _, err := ParseDocument([]byte("a = ")); err == nil {
+		t.Fatal("expected a parse error")
+	}
+}
+
+func TestDocumentGet(t *testing.T) {
+	doc, err := ParseDocument([]byte(testDocument))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		key  Key
+		want interface{}
+	}{
+		{Key{"title"}, "demo"},
+		{Key{"server", "port"}, int64(8080)},
+		{Key{"server", "hosts", "1"}, "b"},
+		{Key{"server", "inline", "y"}, "}"},
+		{Key{"client", "0", "physical", "color"}, "red"},
+		{Key{"client", "1", "name"}, "b"},
+	}
+	for _, tt := range tests {
+		got, ok := doc.Get(tt.key)
+		if !ok || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Get(%s) = %v, %v, want %v", tt.key, got, ok, tt.want)
+		}
+	}
+	if _, ok := doc.Get(Key{"client", "2"}); ok {
+		t.Error("Get of a missing element succeeded")
+	}
+}
+
+func TestDocumentSet(t *testing.T) {
+	doc, err := ParseDocument([]byte(testDocument))
+	if err != nil {
+		t.Fatal(err)
+	}
+	steps := []struct {
+		key Key
+		v   interface{}
+	}{
+		{Key{"title"}, "new"},
+		{Key{"server", "port"}, 9090},
+		{Key{"server", "hosts"}, []string{"c"}},
+		{Key{"server", "timeout"}, 1.5},
+		{Key{"client", "0", "weight"}, 2},
+		{Key{"client", "0", "physical", "size"}, "xl"},
+		{Key{"log", "level"}, "debug"},
+		{Key{"client", "1", "ext", "on"}, true},
+	}
+	for _, s := range steps {
+		if err := doc.Set(s.key, s.v); err != nil {
+			t.Fatalf("Set(%s): %v", s.key, err)
+		}
+	}
+	want := `# the service config
+title = "new"   # the title
+
+# the server
+[server]
+  port = 9090 # the port
+  hosts = ["c"]
+  desc = """
+multi "line" """
+  start = 1979-05-27T07:32:00Z
+  inline = { x = 1, y = "}" }
+  timeout = 1.5
+
+# the clients
+[[client]]
+name = "a"
+weight = 2
+
+[client.physical]
+color = "red"
+size = "xl"
+
+[[client]]
+name = "b"
+
+[client.ext]
+on = true
+
+[log]
+level = "debug"
+`
+	if got := doc.String(); got != want {
+		t.Fatalf("got\n%s\nwant\n%s", got, want)
+	}
+
+	for _, key := range []Key{{"server"}, {"server", "inline", "x"}, {"title", "x"}, {"client", "5", "name"}} {
+		if err := doc.Set(key, 1); err == nil {
+			t.Errorf("Set(%s) succeeded", key)
+		}
+	}
+	if err := doc.Set(Key{"a"}, map[string]int{"b": 1}); err == nil {
+		t.Error("Set of a table succeeded")
+	}
+	if got := doc.String(); got != want {
+		t.Fatalf("failed edits changed the document\n%s", got)
+	}
+}
+
+func TestDocumentSetDotted(t *testing.T) {
+	doc, err := ParseDocument([]byte("a.b = 1 # c\n[t]\nx.y = 2\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, ok := doc.Get(Key{"t", "x", "y"}); !ok || v != int64(2) {
+		t.Fatalf("Get got %v, %v", v, ok)
+	}
+	for _, key := range []Key{{"a", "b"}, {"a", "c"}, {"t", "x", "z"}} {
+		if err := doc.Set(key, 3); err != nil {
+			t.Fatalf("Set(%s): %v", key, err)
+		}
+	}
+	want := "a.b = 3 # c\na.c = 3\n[t]\nx.y = 2\nx.z = 3\n"
+	if got := doc.String(); got != want {
+		t.Fatalf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestDocumentDelete(t *testing.T) {
+	doc, err := ParseDocument([]byte(testDocument))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []Key{{"title"}, {"server", "hosts"}, {"client", "0"}} {
+		if err := doc.Delete(key); err != nil {
+			t.Fatalf("Delete(%s): %v", key, err)
+		}
+	}
+	// the comment right above title goes with it
+	want := `
+# the server
+[server]
+  port = 8080 # the port
+  desc = """
+multi "line" """
+  start = 1979-05-27T07:32:00Z
+  inline = { x = 1, y = "}" }
+
+[[client]]
+name = "b"
+`
+	if got := doc.String(); got != want {
+		t.Fatalf("got\n%s\nwant\n%s", got, want)
+	}
+	if err := doc.Delete(Key{"nothing"}); err == nil {
+		t.Error("Delete of a missing key succeeded")
+	}
+	if v, ok := doc.Get(Key{"client", "0", "name"}); !ok || v != "b" {
+		t.Errorf("the elements are not reindexed, got %v", v)
+	}
+}
+
+func TestDocumentDeleteDetached(t *testing.T) {
+	doc, err := ParseDocument([]byte(`# the header
+
+# about a
+a = 1
+# about b
+
+b = 2
+# about t
+
+[t]
+k = 1
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []Key{{"a"}, {"b"}, {"t"}} {
+		if err := doc.Delete(key); err != nil {
+			t.Fatalf("Delete(%s): %v", key, err)
+		}
+	}
+	// only the comments right above a key go with it
+	want := `# the header
+
+# about b
+
+# about t
+
+`
+	if got := doc.String(); got != want {
+		t.Fatalf("got\n%q\nwant\n%q", got, want)
+	}
+}
+
+func TestDocumentInsertTable(t *testing.T) {
+	doc, err := ParseDocument([]byte("# head\na = 1\n\n# about b\n[b]\nx = 1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := doc.InsertTable(Key{"b"}); err == nil {
+		t.Error("InsertTable of an existing table succeeded")
+	}
+	if err := doc.InsertTable(Key{"b", "c d"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := doc.Set(Key{"b", "c d", "y"}, 2); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		idx, err := doc.AppendArrayTable(Key{"srv"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if idx != i {
+			t.Fatalf("got index %d, want %d", idx, i)
+		}
+		if err := doc.Set(Key{"srv", "0", "n"}, i); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := doc.AppendArrayTable(Key{"a"}); err == nil {
+		t.Error("AppendArrayTable on a value succeeded")
+	}
+	want := `# head
+a = 1
+
+# about b
+[b]
+x = 1
+
+[b."c d"]
+y = 2
+
+[[srv]]
+n = 1
+
+[[srv]]
+`
+	if got := doc.String(); got != want {
+		t.Fatalf("got\n%s\nwant\n%s", got, want)
+	}
+
+	var v struct {
+		B struct {
+			X  int
+			CD struct{ Y int } `toml:"c d"`
+		}
+	}
+	if _, err := doc.Decode(&v); err != nil || v.B.CD.Y != 2 {
+		t.Fatalf("Decode got %+v, %v", v, err)
+	}
+}
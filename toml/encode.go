@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"math"
 	"reflect"
 	"sort"
 	"strconv"
@@ -15,28 +16,49 @@ import (
 type tomlEncodeError struct{ error }
 
 var (
-	errArrayMixedElementTypes = errors.New(
-		"toml: cannot encode array with mixed element types")
 	errArrayNilElement = errors.New(
 		"toml: cannot encode array with nil element")
 	errNonString = errors.New(
 		"toml: cannot encode a map with non-string key type")
 	errAnonNonStruct = errors.New(
 		"toml: cannot encode an anonymous field that is not a struct")
-	errArrayNoTable = errors.New(
-		"toml: TOML array element cannot contain a table")
 	errNoKey = errors.New(
 		"toml: top-level values must be Go maps or structs")
 	errAnything = errors.New("") // used in testing
 )
 
-var quotedReplacer = strings.NewReplacer(
-	"\t", "\\t",
-	"\n", "\\n",
-	"\r", "\\r",
-	"\"", "\\\"",
-	"\\", "\\\\",
-)
+// quoteString returns s as a TOML basic string, the control characters are
+// escaped.
+func quoteString(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '\b':
+			b.WriteString(`\b`)
+		case '\t':
+			b.WriteString(`\t`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\f':
+			b.WriteString(`\f`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '"':
+			b.WriteString(`\"`)
+		case '\\':
+			b.WriteString(`\\`)
+		default:
+			if isControl(r) {
+				fmt.Fprintf(&b, "\\u%04X", r)
+				continue
+			}
+			b.WriteRune(r)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
 
 // Encoder controls the encoding of Go values to a TOML document to some
 // io.Writer.
@@ -49,6 +71,11 @@ type Encoder struct {
 	// hasWritten is whether we have written any output to w yet.
 	hasWritten bool
 	w          *bufio.Writer
+
+	// inline is whether an inline table is being written, and inlineFirst
+	// whether its first key has not been written yet.
+	inline      bool
+	inlineFirst bool
 }
 
 // NewEncoder returns a TOML encoder that encodes Go values to the io.Writer
@@ -77,12 +104,16 @@ func NewEncoder(w io.Writer) *Encoder {
 // deterministic output. More control over this behavior may be provided if
 // there is demand for it.
 //
+// Maps and structs in arrays that are not arrays of tables, like the elements
+// of [][]map[string]string or []interface{}{1, map[string]int{}}, are encoded
+// as inline tables.
+//
+// A time.Time is encoded as an offset datetime in UTC, unless it is a local
+// datetime, date or time (see Local).
+//
 // Encoding Go values without a corresponding TOML representation---like map
 // types with non-string keys---will cause an error to be returned. Similarly
-// for mixed arrays/slices, arrays/slices with nil elements, embedded
-// non-struct types and nested slices containing maps or structs.
-// (e.g., [][]map[string]string is not allowed but []map[string]string is OK
-// and so is []map[string][]string.)
+// for arrays/slices with nil elements and embedded non-struct types.
 func (enc *Encoder) Encode(v interface{}) error {
 	rv := eindirect(reflect.ValueOf(v))
 	if err := enc.safeEncode(Key([]string{}), rv); err != nil {
@@ -106,6 +137,14 @@ func (enc *Encoder) safeEncode(key Key, rv reflect.Value) (err error) {
 }
 
 func (enc *Encoder) encode(key Key, rv reflect.Value) {
+	// Everything in an inline table is a key/value pair.
+	if enc.inline {
+		if rv := eindirect(rv); rv.IsValid() && !isNil(rv) {
+			enc.keyEqElement(key, rv)
+		}
+		return
+	}
+
 	// Special case. Time needs to be in ISO8601 format.
 	// Special case. If we can marshal the type to text, then we used that.
 	// Basically, this prevents the encoder for handling these types as
@@ -152,15 +191,24 @@ func (enc *Encoder) encode(key Key, rv reflect.Value) {
 	}
 }
 
-// eElement encodes any value that can be an array element (primitives and
-// arrays).
+// eElement encodes any value that can be an array element (primitives,
+// arrays and inline tables).
 func (enc *Encoder) eElement(rv reflect.Value) {
 	switch v := rv.Interface().(type) {
 	case time.Time:
 		// Special case time.Time as a primitive. Has to come before
 		// TextMarshaler below because time.Time implements
 		// encoding.TextMarshaler, but we need to always use UTC.
-		enc.wf(v.UTC().Format("2006-01-02T15:04:05Z"))
+		switch LocalType(v) {
+		case LocalDatetime:
+			enc.wf(v.Format("2006-01-02T15:04:05.999999999"))
+		case LocalDate:
+			enc.wf(v.Format("2006-01-02"))
+		case LocalTime:
+			enc.wf(v.Format("15:04:05.999999999"))
+		default:
+			enc.wf(v.UTC().Format("2006-01-02T15:04:05.999999999Z"))
+		}
 		return
 	case TextMarshaler:
 		// Special case. Use text marshaler if it's available for this value.
@@ -181,12 +229,14 @@ func (enc *Encoder) eElement(rv reflect.Value) {
 		reflect.Uint32, reflect.Uint64:
 		enc.wf(strconv.FormatUint(rv.Uint(), 10))
 	case reflect.Float32:
-		enc.wf(floatAddDecimal(strconv.FormatFloat(rv.Float(), 'f', -1, 32)))
+		enc.eFloat(rv.Float(), 32)
 	case reflect.Float64:
-		enc.wf(floatAddDecimal(strconv.FormatFloat(rv.Float(), 'f', -1, 64)))
+		enc.eFloat(rv.Float(), 64)
 	case reflect.Array, reflect.Slice:
 		enc.eArrayOrSliceElement(rv)
-	case reflect.Interface:
+	case reflect.Map, reflect.Struct:
+		enc.eInlineTable(rv)
+	case reflect.Interface, reflect.Ptr:
 		enc.eElement(rv.Elem())
 	case reflect.String:
 		enc.writeQuoted(rv.String())
@@ -195,6 +245,19 @@ func (enc *Encoder) eElement(rv reflect.Value) {
 	}
 }
 
+func (enc *Encoder) eFloat(f float64, bitSize int) {
+	switch {
+	case math.IsNaN(f):
+		enc.wf("nan")
+	case math.IsInf(f, 1):
+		enc.wf("inf")
+	case math.IsInf(f, -1):
+		enc.wf("-inf")
+	default:
+		enc.wf(floatAddDecimal(strconv.FormatFloat(f, 'f', -1, bitSize)))
+	}
+}
+
 // By the TOML spec, all floats must have a decimal with at least one
 // number on either side.
 func floatAddDecimal(fstr string) string {
@@ -205,7 +268,7 @@ func floatAddDecimal(fstr string) string {
 }
 
 func (enc *Encoder) writeQuoted(s string) {
-	enc.wf("\"%s\"", quotedReplacer.Replace(s))
+	enc.wf("%s", quoteString(s))
 }
 
 func (enc *Encoder) eArrayOrSliceElement(rv reflect.Value) {
@@ -213,6 +276,9 @@ func (enc *Encoder) eArrayOrSliceElement(rv reflect.Value) {
 	enc.wf("[")
 	for i := 0; i < length; i++ {
 		elem := rv.Index(i)
+		if tomlTypeOfGo(elem) == nil {
+			encPanic(errArrayNilElement)
+		}
 		enc.eElement(elem)
 		if i != length-1 {
 			enc.wf(", ")
@@ -221,6 +287,16 @@ func (enc *Encoder) eArrayOrSliceElement(rv reflect.Value) {
 	enc.wf("]")
 }
 
+// eInlineTable encodes a map or struct as an inline table.
+func (enc *Encoder) eInlineTable(rv reflect.Value) {
+	inline, inlineFirst := enc.inline, enc.inlineFirst
+	enc.inline, enc.inlineFirst = true, true
+	enc.wf("{")
+	enc.eMapOrStruct(Key{}, rv)
+	enc.wf("}")
+	enc.inline, enc.inlineFirst = inline, inlineFirst
+}
+
 func (enc *Encoder) eArrayOfTables(key Key, rv reflect.Value) {
 	if len(key) == 0 {
 		encPanic(errNoKey)
@@ -230,7 +306,6 @@ func (enc *Encoder) eArrayOfTables(key Key, rv reflect.Value) {
 		if isNil(trv) {
 			continue
 		}
-		panicIfInvalidKey(key)
 		enc.newline()
 		enc.wf("%s[[%s]]", enc.indentStr(key), key.maybeQuotedAll())
 		enc.newline()
@@ -239,7 +314,6 @@ func (enc *Encoder) eArrayOfTables(key Key, rv reflect.Value) {
 }
 
 func (enc *Encoder) eTable(key Key, rv reflect.Value) {
-	panicIfInvalidKey(key)
 	if len(key) == 1 {
 		// Output an extra newline between top-level tables.
 		// (The newline isn't written if nothing else has been written though.)
@@ -421,40 +495,24 @@ func tomlTypeOfGo(rv reflect.Value) tomlType {
 	}
 }
 
-// tomlArrayType returns the element type of a TOML array. The type returned
-// may be nil if it cannot be determined (e.g., a nil slice or a zero length
-// slize). This function may also panic if it finds a type that cannot be
-// expressed in TOML (such as nil elements, heterogeneous arrays or directly
-// nested arrays of tables).
+// tomlArrayType returns the element type of a TOML array, which is tomlHash if
+// every element is a table and tomlArray otherwise. The type returned may be
+// nil if it cannot be determined (e.g., a nil slice or a zero length slize).
+// This function may also panic if it finds a type that cannot be expressed in
+// TOML (such as nil elements).
 func tomlArrayType(rv reflect.Value) tomlType {
 	if isNil(rv) || !rv.IsValid() || rv.Len() == 0 {
 		return nil
 	}
-	firstType := tomlTypeOfGo(rv.Index(0))
-	if firstType == nil {
-		encPanic(errArrayNilElement)
-	}
-
-	rvlen := rv.Len()
-	for i := 1; i < rvlen; i++ {
-		elem := rv.Index(i)
-		switch elemType := tomlTypeOfGo(elem); {
+	for i := 0; i < rv.Len(); i++ {
+		switch elemType := tomlTypeOfGo(rv.Index(i)); {
 		case elemType == nil:
 			encPanic(errArrayNilElement)
-		case !typeEqual(firstType, elemType):
-			encPanic(errArrayMixedElementTypes)
-		}
-	}
-	// If we have a nested array, then we must make sure that the nested
-	// array contains ONLY primitives.
-	// This checks arbitrarily nested arrays.
-	if typeEqual(firstType, tomlArray) || typeEqual(firstType, tomlArrayHash) {
-		nest := tomlArrayType(eindirect(rv.Index(0)))
-		if typeEqual(nest, tomlHash) || typeEqual(nest, tomlArrayHash) {
-			encPanic(errArrayNoTable)
+		case !typeEqual(elemType, tomlHash):
+			return tomlArray
 		}
 	}
-	return firstType
+	return tomlHash
 }
 
 type tagOptions struct {
@@ -512,10 +570,18 @@ func (enc *Encoder) newline() {
 }
 
 func (enc *Encoder) keyEqElement(key Key, val reflect.Value) {
+	if enc.inline {
+		if !enc.inlineFirst {
+			enc.wf(", ")
+		}
+		enc.inlineFirst = false
+		enc.wf("%s = ", key.maybeQuoted(len(key)-1))
+		enc.eElement(val)
+		return
+	}
 	if len(key) == 0 {
 		encPanic(errNoKey)
 	}
-	panicIfInvalidKey(key)
 	enc.wf("%s%s = ", enc.indentStr(key), key.maybeQuoted(len(key)-1))
 	enc.eElement(val)
 	enc.newline()
@@ -553,16 +619,3 @@ func isNil(rv reflect.Value) bool {
 		return false
 	}
 }
-
-func panicIfInvalidKey(key Key) {
-	for _, k := range key {
-		if len(k) == 0 {
-			encPanic(e("Key '%s' is not a valid table name. Key names "+
-				"cannot be empty.", key.maybeQuotedAll()))
-		}
-	}
-}
-
-func isValidKeyName(s string) bool {
-	return len(s) != 0
-}
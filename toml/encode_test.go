@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"fmt"
 	"log"
+	"math"
 	"net"
 	"testing"
 	"time"
@@ -132,6 +133,15 @@ func TestEncode(t *testing.T) {
 			},
 			wantOutput: fmt.Sprintf("Date = %s\nInt = 1\n", dateStr),
 		},
+		"local datetime, date and time": {
+			input: struct{ Datetime, Date, Time time.Time }{
+				Local(LocalDatetime, time.Date(1979, 5, 27, 7, 32, 0, 5e8, time.UTC)),
+				Local(LocalDate, time.Date(1979, 5, 27, 0, 0, 0, 0, time.UTC)),
+				Local(LocalTime, time.Date(0, 1, 1, 7, 32, 0, 0, time.UTC)),
+			},
+			wantOutput: "Datetime = 1979-05-27T07:32:00.5\n" +
+				"Date = 1979-05-27\nTime = 07:32:00\n",
+		},
 		"array fields": {
 			input: struct {
 				IntArray0 [0]int
@@ -198,13 +208,13 @@ ArrayOfMixedSlices = [[1, 2], ["a", "b"]]
 			input:      struct{ Empty []interface{} }{[]interface{}{}},
 			wantOutput: "Empty = []\n",
 		},
-		"(error) slice with element type mismatch (string and integer)": {
-			input:     struct{ Mixed []interface{} }{[]interface{}{1, "a"}},
-			wantError: errArrayMixedElementTypes,
+		"slice with element type mismatch (string and integer)": {
+			input:      struct{ Mixed []interface{} }{[]interface{}{1, "a"}},
+			wantOutput: "Mixed = [1, \"a\"]\n",
 		},
-		"(error) slice with element type mismatch (integer and float)": {
-			input:     struct{ Mixed []interface{} }{[]interface{}{1, 2.5}},
-			wantError: errArrayMixedElementTypes,
+		"slice with element type mismatch (integer and float)": {
+			input:      struct{ Mixed []interface{} }{[]interface{}{1, 2.5}},
+			wantOutput: "Mixed = [1, 2.5]\n",
 		},
 		"slice with elems of differing Go types, same TOML types": {
 			input: struct {
@@ -220,11 +230,17 @@ ArrayOfMixedSlices = [[1, 2], ["a", "b"]]
 			wantOutput: "MixedInts = [1, 2, 3, 4, 5, 1, 2, 3, 4, 5]\n" +
 				"MixedFloats = [1.5, 2.5]\n",
 		},
-		"(error) slice w/ element type mismatch (one is nested array)": {
+		"slice w/ element type mismatch (one is nested array)": {
 			input: struct{ Mixed []interface{} }{
 				[]interface{}{1, []interface{}{2}},
 			},
-			wantError: errArrayMixedElementTypes,
+			wantOutput: "Mixed = [1, [2]]\n",
+		},
+		"slice with a table and a primitive": {
+			input: struct{ Mixed []interface{} }{
+				[]interface{}{1, map[string]interface{}{"a": 1, "b c": []int{2}}},
+			},
+			wantOutput: "Mixed = [1, {a = 1, \"b c\" = [2]}]\n",
 		},
 		"(error) slice with 1 nil element": {
 			input:     struct{ NilElement1 []interface{} }{[]interface{}{nil}},
@@ -365,27 +381,37 @@ ArrayOfMixedSlices = [[1, 2], ["a", "b"]]
 			input:     []struct{ Int int }{{1}, {2}, {3}},
 			wantError: errNoKey,
 		},
-		"(error) slice of slice": {
+		"slice of slice of tables": {
 			input: struct {
 				Slices [][]struct{ Int int }
 			}{
 				[][]struct{ Int int }{{{1}}, {{2}}, {{3}}},
 			},
-			wantError: errArrayNoTable,
+			wantOutput: "Slices = [[{Int = 1}], [{Int = 2}], [{Int = 3}]]\n",
 		},
 		"(error) map no string key": {
 			input:     map[int]string{1: ""},
 			wantError: errNonString,
 		},
-		"(error) empty key name": {
-			input:     map[string]int{"": 1},
-			wantError: errAnything,
+		"empty key name": {
+			input:      map[string]int{"": 1},
+			wantOutput: "\"\" = 1\n",
 		},
-		"(error) empty map name": {
+		"empty map name": {
 			input: map[string]interface{}{
 				"": map[string]int{"v": 1},
 			},
-			wantError: errAnything,
+			wantOutput: "[\"\"]\n  v = 1\n",
+		},
+		"special floats": {
+			input: struct{ NaN, Inf, NegInf float64 }{
+				math.NaN(), math.Inf(1), math.Inf(-1),
+			},
+			wantOutput: "NaN = nan\nInf = inf\nNegInf = -inf\n",
+		},
+		"control characters": {
+			input:      map[string]string{"a\x01": "\x7f\b"},
+			wantOutput: "\"a\\u0001\" = \"\\u007F\\b\"\n",
 		},
 	}
 	for label, test := range tests {
@@ -0,0 +1,133 @@
+// Package tag converts between decoded TOML values and the tagged JSON of
+// toml-test (https://github.com/toml-lang/toml-test), where every primitive is
+// an object like {"type": "integer", "value": "1"}.
+package tag
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/yunfeiyang1916/toolkit/toml"
+)
+
+// The formats of the datetime types, by their toml-test names.
+var formats = map[string]string{
+	"datetime":       "2006-01-02T15:04:05.999999999Z07:00",
+	"datetime-local": "2006-01-02T15:04:05.999999999",
+	"date-local":     "2006-01-02",
+	"time-local":     "15:04:05.999999999",
+}
+
+// Add returns the tagged form of a value decoded by toml.
+func Add(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			m[k] = Add(e)
+		}
+		return m
+	case []map[string]interface{}:
+		a := make([]interface{}, len(v))
+		for i, e := range v {
+			a[i] = Add(e)
+		}
+		return a
+	case []interface{}:
+		a := make([]interface{}, len(v))
+		for i, e := range v {
+			a[i] = Add(e)
+		}
+		return a
+	case time.Time:
+		typ := toml.LocalType(v)
+		if typ == "" {
+			typ = "datetime"
+		}
+		return tag(typ, v.Format(formats[typ]))
+	case bool:
+		return tag("bool", strconv.FormatBool(v))
+	case string:
+		return tag("string", v)
+	case int64:
+		return tag("integer", strconv.FormatInt(v, 10))
+	case float64:
+		switch {
+		case math.IsNaN(v):
+			return tag("float", "nan")
+		case math.IsInf(v, 1):
+			return tag("float", "inf")
+		case math.IsInf(v, -1):
+			return tag("float", "-inf")
+		}
+		return tag("float", strconv.FormatFloat(v, 'g', -1, 64))
+	}
+	panic(fmt.Sprintf("tag: unknown type %T", v))
+}
+
+func tag(typ, value string) map[string]interface{} {
+	return map[string]interface{}{"type": typ, "value": value}
+}
+
+// Remove returns the values of tagged JSON, as they would be decoded by toml.
+func Remove(v interface{}) (interface{}, error) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		if typ, ok := v["type"].(string); ok && len(v) == 2 {
+			if value, ok := v["value"].(string); ok {
+				return untag(typ, value)
+			}
+		}
+		m := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			var err error
+			if m[k], err = Remove(e); err != nil {
+				return nil, err
+			}
+		}
+		return m, nil
+	case []interface{}:
+		a := make([]interface{}, len(v))
+		for i, e := range v {
+			var err error
+			if a[i], err = Remove(e); err != nil {
+				return nil, err
+			}
+		}
+		return a, nil
+	}
+	return nil, fmt.Errorf("tag: unexpected JSON value %T", v)
+}
+
+func untag(typ, value string) (interface{}, error) {
+	switch typ {
+	case "string":
+		return value, nil
+	case "bool":
+		return strconv.ParseBool(value)
+	case "integer":
+		return strconv.ParseInt(value, 10, 64)
+	case "float":
+		switch strings.TrimPrefix(value, "+") {
+		case "nan":
+			return math.NaN(), nil
+		case "inf":
+			return math.Inf(1), nil
+		case "-inf":
+			return math.Inf(-1), nil
+		}
+		return strconv.ParseFloat(value, 64)
+	case "datetime":
+		return time.Parse(formats[typ], strings.ToUpper(strings.Replace(value, " ", "T", 1)))
+	case "datetime-local", "date-local", "time-local":
+		t, err := time.Parse(formats[typ], strings.ToUpper(strings.Replace(value, " ", "T", 1)))
+		if err != nil {
+			return nil, err
+		}
+		return toml.Local(typ, t), nil
+	}
+	return nil, fmt.Errorf("tag: unknown type %q", typ)
+}
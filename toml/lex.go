@@ -29,6 +29,7 @@ const (
 	itemArrayTableStart
 	itemArrayTableEnd
 	itemKeyStart
+	itemKeyEnd
 	itemCommentStart
 	itemInlineTableStart
 	itemInlineTableEnd
@@ -226,6 +227,9 @@ func (lx *lexer) errorf(format string, values ...interface{}) stateFn {
 // lexTop consumes elements at the top level of TOML data.
 func lexTop(lx *lexer) stateFn {
 	r := lx.next()
+	if r == '\r' && !lx.acceptCRLF() {
+		return lx.errorf("bare carriage return, expected \\r\\n")
+	}
 	if isWhitespace(r) || isNL(r) {
 		return lexSkip(lx, lexTop)
 	}
@@ -262,6 +266,8 @@ func lexTopEnd(lx *lexer) stateFn {
 		return lexCommentStart
 	case isWhitespace(r):
 		return lexTopEnd
+	case r == '\r' && !lx.acceptCRLF():
+		return lx.errorf("bare carriage return, expected \\r\\n")
 	case isNL(r):
 		lx.ignore()
 		return lexTop
@@ -314,6 +320,9 @@ func lexTableNameStart(lx *lexer) stateFn {
 		return lx.errorf("unexpected table separator " +
 			"(table names cannot be empty)")
 	case r == stringStart || r == rawStringStart:
+		if lx.multilineQuote() {
+			return lx.errorf("multi-line strings are not allowed in table names")
+		}
 		lx.ignore()
 		lx.push(lexTableNameEnd)
 		return lexValue // reuse string lexing
@@ -352,55 +361,71 @@ func lexTableNameEnd(lx *lexer) stateFn {
 	}
 }
 
-// lexKeyStart consumes a key name up until the first non-whitespace character.
-// lexKeyStart will ignore whitespace.
+// lexKeyStart starts a key, a key is one or more parts separated by '.', e.g.
+// the dotted key 'a.b.c'. lexKeyStart will ignore whitespace.
 func lexKeyStart(lx *lexer) stateFn {
-	r := lx.peek()
-	switch {
+	lx.skip(isWhitespace)
+	switch r := lx.peek(); {
 	case r == keySep:
 		return lx.errorf("unexpected key separator %q", keySep)
-	case isWhitespace(r) || isNL(r):
-		lx.next()
-		return lexSkip(lx, lexKeyStart)
+	case r == tableSep:
+		return lx.errorf("unexpected key part separator %q "+
+			"(key parts cannot be empty)", tableSep)
+	case isNL(r) || r == eof:
+		return lx.errorf("unexpected end of key")
+	}
+	lx.ignore()
+	lx.emit(itemKeyStart)
+	return lexKeyNameStart
+}
+
+// lexKeyNameStart consumes a part of a key, either a bare key or a quoted key.
+func lexKeyNameStart(lx *lexer) stateFn {
+	lx.skip(isWhitespace)
+	switch r := lx.peek(); {
 	case r == stringStart || r == rawStringStart:
+		if lx.multilineQuote() {
+			return lx.errorf("multi-line strings are not allowed as keys")
+		}
 		lx.ignore()
-		lx.emit(itemKeyStart)
 		lx.push(lexKeyEnd)
 		return lexValue // reuse string lexing
-	default:
-		lx.ignore()
-		lx.emit(itemKeyStart)
+	case isBareKeyChar(r):
 		return lexBareKey
+	case r == tableSep || r == keySep:
+		return lx.errorf("unexpected %q (key parts cannot be empty)", r)
+	default:
+		return lx.errorf("bare keys cannot contain %q", r)
 	}
 }
 
 // lexBareKey consumes the text of a bare key. Assumes that the first character
 // (which is not whitespace) has not yet been consumed.
 func lexBareKey(lx *lexer) stateFn {
-	switch r := lx.next(); {
-	case isBareKeyChar(r):
+	if r := lx.next(); isBareKeyChar(r) {
 		return lexBareKey
-	case isWhitespace(r):
-		lx.backup()
-		lx.emit(itemText)
-		return lexKeyEnd
-	case r == keySep:
-		lx.backup()
-		lx.emit(itemText)
-		return lexKeyEnd
-	default:
-		return lx.errorf("bare keys cannot contain %q", r)
 	}
+	lx.backup()
+	lx.emit(itemText)
+	return lexKeyEnd
 }
 
-// lexKeyEnd consumes the end of a key and trims whitespace (up to the key
-// separator).
+// lexKeyEnd consumes the end of a key part, which is followed by the next
+// part of a dotted key or the key separator. Whitespace is ignored.
 func lexKeyEnd(lx *lexer) stateFn {
+	lx.skip(isWhitespace)
 	switch r := lx.next(); {
+	case r == tableSep:
+		lx.ignore()
+		return lexKeyNameStart
 	case r == keySep:
+		lx.ignore()
+		lx.emit(itemKeyEnd)
 		return lexSkip(lx, lexValue)
-	case isWhitespace(r):
-		return lexSkip(lx, lexKeyEnd)
+	case isBareKeyChar(r) || r == stringStart || r == rawStringStart:
+		return lx.errorf("expected key separator %q or key part separator "+
+			"%q, but got %q instead (keys with spaces must be quoted)",
+			keySep, tableSep, r)
 	default:
 		return lx.errorf("expected key separator %q, but got %q instead",
 			keySep, r)
@@ -429,7 +454,7 @@ func lexValue(lx *lexer) stateFn {
 	case inlineTableStart:
 		lx.ignore()
 		lx.emit(itemInlineTableStart)
-		return lexInlineTableValue
+		return lexInlineTableStart
 	case stringStart:
 		if lx.accept(stringStart) {
 			if lx.accept(stringStart) {
@@ -459,7 +484,8 @@ func lexValue(lx *lexer) stateFn {
 		// Be permissive here; lexBool will give a nice error if the
 		// user wrote something like
 		//   x = foo
-		// (i.e. not 'true' or 'false' but is something else word-like.)
+		// (i.e. not 'true', 'false', 'inf' or 'nan' but is something else
+		// word-like.)
 		lx.backup()
 		return lexBool
 	}
@@ -520,6 +546,21 @@ func lexArrayEnd(lx *lexer) stateFn {
 	return lx.pop()
 }
 
+// lexInlineTableStart consumes the first key/value pair in an inline table, or
+// the end of an empty inline table. It assumes that '{' has already been
+// consumed. Whitespace is ignored.
+func lexInlineTableStart(lx *lexer) stateFn {
+	r := lx.next()
+	switch {
+	case isWhitespace(r):
+		return lexSkip(lx, lexInlineTableStart)
+	case r == inlineTableEnd:
+		return lexInlineTableEnd
+	}
+	lx.backup()
+	return lexInlineTableValue
+}
+
 // lexInlineTableValue consumes one key/value pair in an inline table.
 // It assumes that '{' or ',' have already been consumed. Whitespace is ignored.
 func lexInlineTableValue(lx *lexer) stateFn {
@@ -535,7 +576,7 @@ func lexInlineTableValue(lx *lexer) stateFn {
 	case r == comma:
 		return lx.errorf("unexpected comma")
 	case r == inlineTableEnd:
-		return lexInlineTableEnd
+		return lx.errorf("trailing comma not allowed in inline tables")
 	}
 	lx.backup()
 	lx.push(lexInlineTableValueEnd)
@@ -582,6 +623,8 @@ func lexString(lx *lexer) stateFn {
 		return lx.errorf("unexpected EOF")
 	case isNL(r):
 		return lx.errorf("strings cannot contain newlines")
+	case isControl(r):
+		return lx.errorf("control characters are not allowed in strings: %q", r)
 	case r == '\\':
 		lx.push(lexString)
 		return lexStringEscape
@@ -598,7 +641,8 @@ func lexString(lx *lexer) stateFn {
 // lexMultilineString consumes the inner contents of a string. It assumes that
 // the beginning '"""' has already been consumed and ignored.
 func lexMultilineString(lx *lexer) stateFn {
-	switch lx.next() {
+	r := lx.next()
+	switch r {
 	case eof:
 		return lx.errorf("unexpected EOF")
 	case '\\':
@@ -606,9 +650,20 @@ func lexMultilineString(lx *lexer) stateFn {
 	case stringEnd:
 		if lx.accept(stringEnd) {
 			if lx.accept(stringEnd) {
+				// Up to two quotes are allowed right before the closing
+				// delimiter, they're part of the string.
+				if lx.pos < len(lx.input) && lx.input[lx.pos] == stringEnd {
+					lx.backup()
+					lx.backup()
+					return lexMultilineString
+				}
 				lx.backup()
 				lx.backup()
 				lx.backup()
+				if tooManyQuotes(lx.current(), stringEnd) {
+					return lx.errorf("too many quotes before the end of " +
+						"a multi-line string")
+				}
 				lx.emit(itemMultilineString)
 				lx.next()
 				lx.next()
@@ -619,6 +674,9 @@ func lexMultilineString(lx *lexer) stateFn {
 			lx.backup()
 		}
 	}
+	if bad := lx.badMultilineRune(r); bad != nil {
+		return bad
+	}
 	return lexMultilineString
 }
 
@@ -631,6 +689,8 @@ func lexRawString(lx *lexer) stateFn {
 		return lx.errorf("unexpected EOF")
 	case isNL(r):
 		return lx.errorf("strings cannot contain newlines")
+	case isControl(r):
+		return lx.errorf("control characters are not allowed in strings: %q", r)
 	case r == rawStringEnd:
 		lx.backup()
 		lx.emit(itemRawString)
@@ -645,15 +705,27 @@ func lexRawString(lx *lexer) stateFn {
 // a string. It assumes that the beginning "'''" has already been consumed and
 // ignored.
 func lexMultilineRawString(lx *lexer) stateFn {
-	switch lx.next() {
+	r := lx.next()
+	switch r {
 	case eof:
 		return lx.errorf("unexpected EOF")
 	case rawStringEnd:
 		if lx.accept(rawStringEnd) {
 			if lx.accept(rawStringEnd) {
+				// Up to two quotes are allowed right before the closing
+				// delimiter, they're part of the string.
+				if lx.pos < len(lx.input) && lx.input[lx.pos] == rawStringEnd {
+					lx.backup()
+					lx.backup()
+					return lexMultilineRawString
+				}
 				lx.backup()
 				lx.backup()
 				lx.backup()
+				if tooManyQuotes(lx.current(), rawStringEnd) {
+					return lx.errorf("too many quotes before the end of " +
+						"a multi-line string")
+				}
 				lx.emit(itemRawMultilineString)
 				lx.next()
 				lx.next()
@@ -664,17 +736,58 @@ func lexMultilineRawString(lx *lexer) stateFn {
 			lx.backup()
 		}
 	}
+	if bad := lx.badMultilineRune(r); bad != nil {
+		return bad
+	}
 	return lexMultilineRawString
 }
 
+// badMultilineRune returns an error state when r is not allowed in a
+// multi-line string, the newlines are allowed but a bare carriage return is
+// not.
+func (lx *lexer) badMultilineRune(r rune) stateFn {
+	switch {
+	case r == '\r':
+		if !lx.acceptCRLF() {
+			return lx.errorf("bare carriage return in a multi-line string")
+		}
+	case r != '\n' && isControl(r):
+		return lx.errorf("control characters are not allowed in strings: %q", r)
+	}
+	return nil
+}
+
+// tooManyQuotes reports whether the contents of a multi-line string end with
+// three or more unescaped quotes, which close the string.
+func tooManyQuotes(s string, quote byte) bool {
+	n := 0
+	for n < len(s) && s[len(s)-1-n] == quote {
+		n++
+	}
+	if quote == stringEnd {
+		// an escaped quote is not a delimiter
+		backslashes := 0
+		for i := len(s) - 1 - n; i >= 0 && s[i] == '\\'; i-- {
+			backslashes++
+		}
+		if backslashes%2 == 1 {
+			n--
+		}
+	}
+	return n >= 3
+}
+
 // lexMultilineStringEscape consumes an escaped character. It assumes that the
 // preceding '\\' has already been consumed.
 func lexMultilineStringEscape(lx *lexer) stateFn {
-	// Handle the special case first:
-	if isNL(lx.next()) {
+	// Handle the special case first: a line ending backslash, which may be
+	// followed by whitespace.
+	rest := strings.TrimLeft(lx.input[lx.pos:], " \t")
+	if strings.HasPrefix(rest, "\n") || strings.HasPrefix(rest, "\r\n") {
+		for r := lx.next(); r != '\n'; r = lx.next() {
+		}
 		return lexMultilineString
 	}
-	lx.backup()
 	lx.push(lexMultilineString)
 	return lexStringEscape(lx)
 }
@@ -733,6 +846,19 @@ func lexLongUnicodeEscape(lx *lexer) stateFn {
 // lexNumberOrDateStart consumes either an integer, a float, or datetime.
 func lexNumberOrDateStart(lx *lexer) stateFn {
 	r := lx.next()
+	if r == '0' {
+		switch lx.peek() {
+		case 'x':
+			lx.next()
+			return lexHexInteger
+		case 'o':
+			lx.next()
+			return lexOctalInteger
+		case 'b':
+			lx.next()
+			return lexBinaryInteger
+		}
+	}
 	if isDigit(r) {
 		return lexNumberOrDate
 	}
@@ -754,7 +880,7 @@ func lexNumberOrDate(lx *lexer) stateFn {
 		return lexNumberOrDate
 	}
 	switch r {
-	case '-':
+	case '-', ':':
 		return lexDatetime
 	case '_':
 		return lexNumber
@@ -775,8 +901,14 @@ func lexDatetime(lx *lexer) stateFn {
 		return lexDatetime
 	}
 	switch r {
-	case '-', 'T', ':', '.', 'Z', '+':
+	case '-', 'T', 't', ':', '.', 'Z', 'z', '+':
 		return lexDatetime
+	case ' ':
+		// The date and time may be separated by a space instead of a 'T'.
+		if len(lx.current()) == len("2006-01-02 ") &&
+			lx.pos < len(lx.input) && isDigit(rune(lx.input[lx.pos])) {
+			return lexDatetime
+		}
 	}
 
 	lx.backup()
@@ -784,12 +916,46 @@ func lexDatetime(lx *lexer) stateFn {
 	return lx.pop()
 }
 
+// lexHexInteger consumes a hexadecimal integer after its '0x' prefix.
+func lexHexInteger(lx *lexer) stateFn {
+	if r := lx.next(); isHexadecimal(r) || r == '_' {
+		return lexHexInteger
+	}
+	lx.backup()
+	lx.emit(itemInteger)
+	return lx.pop()
+}
+
+// lexOctalInteger consumes an octal integer after its '0o' prefix.
+func lexOctalInteger(lx *lexer) stateFn {
+	if r := lx.next(); (r >= '0' && r <= '7') || r == '_' {
+		return lexOctalInteger
+	}
+	lx.backup()
+	lx.emit(itemInteger)
+	return lx.pop()
+}
+
+// lexBinaryInteger consumes a binary integer after its '0b' prefix.
+func lexBinaryInteger(lx *lexer) stateFn {
+	if r := lx.next(); r == '0' || r == '1' || r == '_' {
+		return lexBinaryInteger
+	}
+	lx.backup()
+	lx.emit(itemInteger)
+	return lx.pop()
+}
+
 // lexNumberStart consumes either an integer or a float. It assumes that a sign
 // has already been read, but that *no* digits have been consumed.
 // lexNumberStart will move to the appropriate integer or float states.
 func lexNumberStart(lx *lexer) stateFn {
-	// We MUST see a digit. Even floats have to start with a digit.
+	// We MUST see a digit, or the 'inf' or 'nan' of a float.
 	r := lx.next()
+	if r == 'i' || r == 'n' {
+		lx.backup()
+		return lexBool
+	}
 	if !isDigit(r) {
 		if r == '.' {
 			return lx.errorf("floats must start with a digit, not '.'")
@@ -835,7 +1001,8 @@ func lexFloat(lx *lexer) stateFn {
 	return lx.pop()
 }
 
-// lexBool consumes a bool string: 'true' or 'false.
+// lexBool consumes a bool string: 'true' or 'false', or the special floats
+// 'inf' and 'nan', which may be signed.
 func lexBool(lx *lexer) stateFn {
 	var rs []rune
 	for {
@@ -849,8 +1016,14 @@ func lexBool(lx *lexer) stateFn {
 	s := string(rs)
 	switch s {
 	case "true", "false":
+		if s != lx.current() {
+			return lx.errorf("a bool cannot be signed: %q", lx.current())
+		}
 		lx.emit(itemBool)
 		return lx.pop()
+	case "inf", "nan":
+		lx.emit(itemFloat)
+		return lx.pop()
 	}
 	return lx.errorf("expected value but found %q instead", s)
 }
@@ -872,6 +1045,9 @@ func lexComment(lx *lexer) stateFn {
 		lx.emit(itemText)
 		return lx.pop()
 	}
+	if isControl(r) {
+		return lx.errorf("control characters are not allowed in comments: %q", r)
+	}
 	lx.next()
 	return lexComment
 }
@@ -894,6 +1070,25 @@ func isNL(r rune) bool {
 	return r == '\n' || r == '\r'
 }
 
+// isControl returns true if `r` is a control character other than a tab,
+// which is not allowed in strings and comments.
+func isControl(r rune) bool {
+	return (r < 0x20 && r != '\t') || r == 0x7f
+}
+
+// acceptCRLF reports whether the '\r' just consumed starts a "\r\n" newline, a
+// bare carriage return is not a newline.
+func (lx *lexer) acceptCRLF() bool {
+	return lx.pos < len(lx.input) && lx.input[lx.pos] == '\n'
+}
+
+// multilineQuote reports whether the next characters are the delimiter of a
+// multi-line string.
+func (lx *lexer) multilineQuote() bool {
+	rest := lx.input[lx.pos:]
+	return strings.HasPrefix(rest, `"""`) || strings.HasPrefix(rest, "'''")
+}
+
 func isDigit(r rune) bool {
 	return r >= '0' && r <= '9'
 }
@@ -938,12 +1133,22 @@ func (itype itemType) String() string {
 		return "TableEnd"
 	case itemKeyStart:
 		return "KeyStart"
+	case itemKeyEnd:
+		return "KeyEnd"
 	case itemArray:
 		return "Array"
 	case itemArrayEnd:
 		return "ArrayEnd"
 	case itemCommentStart:
 		return "CommentStart"
+	case itemArrayTableStart:
+		return "ArrayTableStart"
+	case itemArrayTableEnd:
+		return "ArrayTableEnd"
+	case itemInlineTableStart:
+		return "InlineTableStart"
+	case itemInlineTableEnd:
+		return "InlineTableEnd"
 	}
 	panic(fmt.Sprintf("BUG: Unknown type '%d'.", int(itype)))
 }
@@ -2,6 +2,7 @@ package toml
 
 import (
 	"fmt"
+	"math"
 	"strconv"
 	"strings"
 	"time"
@@ -28,6 +29,12 @@ type parser struct {
 
 	// A map of 'key.group.names' to whether they were created implicitly.
 	implicits map[string]bool
+
+	// The tables created by dotted keys and the inline tables, an inline
+	// table cannot be extended and a table created by dotted keys cannot be
+	// defined by a table header.
+	dotted map[string]bool
+	inline map[string]bool
 }
 
 type parseError string
@@ -47,12 +54,17 @@ func parse(data string) (p *parser, err error) {
 		}
 	}()
 
+	if err := checkInput(data); err != nil {
+		return nil, err
+	}
 	p = &parser{
 		mapping:   make(map[string]interface{}),
 		types:     make(map[string]tomlType),
 		lx:        lex(data),
 		ordered:   make([]Key, 0),
 		implicits: make(map[string]bool),
+		dotted:    make(map[string]bool),
+		inline:    make(map[string]bool),
 	}
 	for {
 		item := p.next()
@@ -65,6 +77,27 @@ func parse(data string) (p *parser, err error) {
 	return p, nil
 }
 
+// checkInput checks that data is valid UTF-8 without NUL characters, which the
+// lexer takes for the end of the input.
+func checkInput(data string) error {
+	for i, r := range data {
+		var msg string
+		switch {
+		case r == 0:
+			msg = "control characters are not allowed"
+		case r == utf8.RuneError:
+			if _, w := utf8.DecodeRuneInString(data[i:]); w == 1 {
+				msg = "invalid UTF-8"
+			}
+		}
+		if len(msg) > 0 {
+			return parseError(fmt.Sprintf("Near line %d: %s",
+				strings.Count(data[:i], "\n")+1, msg))
+		}
+	}
+	return nil
+}
+
 func (p *parser) panicf(format string, v ...interface{}) {
 	msg := fmt.Sprintf("Near line %d (last key parsed '%s'): %s",
 		p.approxLine, p.current(), fmt.Sprintf(format, v...))
@@ -127,20 +160,36 @@ func (p *parser) topLevel(item item) {
 		p.setType("", tomlArrayHash)
 		p.ordered = append(p.ordered, key)
 	case itemKeyStart:
-		kname := p.next()
-		p.approxLine = kname.line
-		p.currentKey = p.keyString(kname)
+		key := p.key()
+		outerContext := p.context
+		p.context = p.establishDotted(key[:len(key)-1])
+		p.currentKey = key[len(key)-1]
 
 		val, typ := p.value(p.next())
 		p.setValue(p.currentKey, val)
 		p.setType(p.currentKey, typ)
 		p.ordered = append(p.ordered, p.context.add(p.currentKey))
+		if _, ok := val.(map[string]interface{}); ok {
+			p.inline[p.context.add(p.currentKey).String()] = true
+		}
+		p.context = outerContext
 		p.currentKey = ""
 	default:
 		p.bug("Unexpected type at top level: %s", item.typ)
 	}
 }
 
+// key reads the parts of a key after its itemKeyStart, a dotted key has more
+// than one part.
+func (p *parser) key() Key {
+	var key Key
+	for it := p.next(); it.typ != itemKeyEnd; it = p.next() {
+		p.approxLine = it.line
+		key = append(key, p.keyString(it))
+	}
+	return key
+}
+
 // Gets a string for a key (or part of a key in a table name).
 func (p *parser) keyString(it item) string {
 	switch it.typ {
@@ -163,7 +212,7 @@ func (p *parser) value(it item) (interface{}, tomlType) {
 	case itemString:
 		return p.replaceEscapes(it.val), p.typeOfPrimitive(it)
 	case itemMultilineString:
-		trimmed := stripFirstNewline(stripEscapedWhitespace(it.val))
+		trimmed := stripEscapedNewlines(stripFirstNewline(it.val))
 		return p.replaceEscapes(trimmed), p.typeOfPrimitive(it)
 	case itemRawString:
 		return it.val, p.typeOfPrimitive(it)
@@ -178,82 +227,11 @@ func (p *parser) value(it item) (interface{}, tomlType) {
 		}
 		p.bug("Expected boolean value, but got '%s'.", it.val)
 	case itemInteger:
-		if !numUnderscoresOK(it.val) {
-			p.panicf("Invalid integer %q: underscores must be surrounded by digits",
-				it.val)
-		}
-		val := strings.Replace(it.val, "_", "", -1)
-		num, err := strconv.ParseInt(val, 10, 64)
-		if err != nil {
-			// Distinguish integer values. Normally, it'd be a bug if the lexer
-			// provides an invalid integer, but it's possible that the number is
-			// out of range of valid values (which the lexer cannot determine).
-			// So mark the former as a bug but the latter as a legitimate user
-			// error.
-			if e, ok := err.(*strconv.NumError); ok &&
-				e.Err == strconv.ErrRange {
-
-				p.panicf("Integer '%s' is out of the range of 64-bit "+
-					"signed integers.", it.val)
-			} else {
-				p.bug("Expected integer value, but got '%s'.", it.val)
-			}
-		}
-		return num, p.typeOfPrimitive(it)
+		return p.valueInteger(it), p.typeOfPrimitive(it)
 	case itemFloat:
-		parts := strings.FieldsFunc(it.val, func(r rune) bool {
-			switch r {
-			case '.', 'e', 'E':
-				return true
-			}
-			return false
-		})
-		for _, part := range parts {
-			if !numUnderscoresOK(part) {
-				p.panicf("Invalid float %q: underscores must be "+
-					"surrounded by digits", it.val)
-			}
-		}
-		if !numPeriodsOK(it.val) {
-			// As a special case, numbers like '123.' or '1.e2',
-			// which are valid as far as Go/strconv are concerned,
-			// must be rejected because TOML says that a fractional
-			// part consists of '.' followed by 1+ digits.
-			p.panicf("Invalid float %q: '.' must be followed "+
-				"by one or more digits", it.val)
-		}
-		val := strings.Replace(it.val, "_", "", -1)
-		num, err := strconv.ParseFloat(val, 64)
-		if err != nil {
-			if e, ok := err.(*strconv.NumError); ok &&
-				e.Err == strconv.ErrRange {
-
-				p.panicf("Float '%s' is out of the range of 64-bit "+
-					"IEEE-754 floating-point numbers.", it.val)
-			} else {
-				p.panicf("Invalid float value: %q", it.val)
-			}
-		}
-		return num, p.typeOfPrimitive(it)
+		return p.valueFloat(it), p.typeOfPrimitive(it)
 	case itemDatetime:
-		var t time.Time
-		var ok bool
-		var err error
-		for _, format := range []string{
-			"2006-01-02T15:04:05Z07:00",
-			"2006-01-02T15:04:05",
-			"2006-01-02",
-		} {
-			t, err = time.ParseInLocation(format, it.val, time.Local)
-			if err == nil {
-				ok = true
-				break
-			}
-		}
-		if !ok {
-			p.panicf("Invalid TOML Datetime: %q.", it.val)
-		}
-		return t, p.typeOfPrimitive(it)
+		return p.valueDatetime(it)
 	case itemArray:
 		array := make([]interface{}, 0)
 		types := make([]tomlType, 0)
@@ -274,32 +252,55 @@ func (p *parser) value(it item) (interface{}, tomlType) {
 			hash         = make(map[string]interface{})
 			outerContext = p.context
 			outerKey     = p.currentKey
+			tableContext = p.context.add(p.currentKey)
+			dotted       = make(map[string]bool)
 		)
 
-		p.context = append(p.context, p.currentKey)
-		p.currentKey = ""
 		for it := p.next(); it.typ != itemInlineTableEnd; it = p.next() {
-			if it.typ != itemKeyStart {
-				p.bug("Expected key start but instead found %q, around line %d",
-					it.val, p.approxLine)
-			}
 			if it.typ == itemCommentStart {
 				p.expect(itemText)
 				continue
 			}
+			if it.typ != itemKeyStart {
+				p.bug("Expected key start but instead found %q, around line %d",
+					it.val, p.approxLine)
+			}
 
-			// retrieve key
-			k := p.next()
-			p.approxLine = k.line
-			kname := p.keyString(k)
+			// retrieve key, the tables of a dotted key are created in the
+			// inline table
+			key := p.key()
+			h := hash
+			p.context = tableContext
+			for _, k := range key[:len(key)-1] {
+				p.context = p.context.add(k)
+				switch t := h[k].(type) {
+				case nil:
+					m := make(map[string]interface{})
+					h[k] = m
+					dotted[p.context.String()] = true
+					p.setType("", tomlHash)
+					h = m
+				case map[string]interface{}:
+					if !dotted[p.context.String()] {
+						p.panicf("Key '%s' has already been defined.", p.context)
+					}
+					h = t
+				default:
+					p.panicf("Key '%s' has already been defined.", p.context)
+				}
+			}
+			p.currentKey = key[len(key)-1]
+			if _, ok := h[p.currentKey]; ok {
+				p.panicf("Key '%s' has already been defined.",
+					p.context.add(p.currentKey))
+			}
 
 			// retrieve value
-			p.currentKey = kname
 			val, typ := p.value(p.next())
 			// make sure we keep metadata up to date
-			p.setType(kname, typ)
+			p.setType(p.currentKey, typ)
 			p.ordered = append(p.ordered, p.context.add(p.currentKey))
-			hash[kname] = val
+			h[p.currentKey] = val
 		}
 		p.context = outerContext
 		p.currentKey = outerKey
@@ -309,6 +310,161 @@ func (p *parser) value(it item) (interface{}, tomlType) {
 	panic("unreachable")
 }
 
+// valueInteger returns the value of a decimal, hexadecimal, octal or binary
+// integer.
+func (p *parser) valueInteger(it item) int64 {
+	if !numUnderscoresOK(it.val) {
+		p.panicf("Invalid integer %q: underscores must be surrounded by digits",
+			it.val)
+	}
+	val := strings.Replace(it.val, "_", "", -1)
+	base := 10
+	if len(val) > 2 && val[0] == '0' {
+		switch val[1] {
+		case 'x':
+			base = 16
+		case 'o':
+			base = 8
+		case 'b':
+			base = 2
+		}
+	}
+	if base != 10 {
+		if !numUnderscoresOK(it.val[2:]) {
+			p.panicf("Invalid integer %q: underscores must be surrounded by "+
+				"digits", it.val)
+		}
+		val = val[2:]
+	} else if numLeadingZero(val) {
+		p.panicf("Invalid integer %q: leading zeros are not allowed", it.val)
+	}
+	num, err := strconv.ParseInt(val, base, 64)
+	if err != nil {
+		// Distinguish integer values. The number may be out of range of valid
+		// values (which the lexer cannot determine), or not a number at all
+		// like '0x'.
+		if e, ok := err.(*strconv.NumError); ok &&
+			e.Err == strconv.ErrRange {
+
+			p.panicf("Integer '%s' is out of the range of 64-bit "+
+				"signed integers.", it.val)
+		}
+		p.panicf("Invalid integer %q", it.val)
+	}
+	return num
+}
+
+// valueFloat returns the value of a float, including inf and nan.
+func (p *parser) valueFloat(it item) float64 {
+	switch strings.TrimLeft(it.val, "+-") {
+	case "inf":
+		if it.val[0] == '-' {
+			return math.Inf(-1)
+		}
+		return math.Inf(1)
+	case "nan":
+		return math.NaN()
+	}
+
+	parts := strings.FieldsFunc(it.val, func(r rune) bool {
+		switch r {
+		case '.', 'e', 'E', '+', '-':
+			return true
+		}
+		return false
+	})
+	for _, part := range parts {
+		if !numUnderscoresOK(part) {
+			p.panicf("Invalid float %q: underscores must be "+
+				"surrounded by digits", it.val)
+		}
+	}
+	if !numPeriodsOK(it.val) {
+		// As a special case, numbers like '123.' or '1.e2',
+		// which are valid as far as Go/strconv are concerned,
+		// must be rejected because TOML says that a fractional
+		// part consists of '.' followed by 1+ digits.
+		p.panicf("Invalid float %q: '.' must be followed "+
+			"by one or more digits", it.val)
+	}
+	val := strings.Replace(it.val, "_", "", -1)
+	if numLeadingZero(strings.FieldsFunc(val, func(r rune) bool {
+		return r == '.' || r == 'e' || r == 'E'
+	})[0]) {
+		p.panicf("Invalid float %q: leading zeros are not allowed", it.val)
+	}
+	num, err := strconv.ParseFloat(val, 64)
+	if err != nil {
+		if e, ok := err.(*strconv.NumError); ok &&
+			e.Err == strconv.ErrRange {
+
+			p.panicf("Float '%s' is out of the range of 64-bit "+
+				"IEEE-754 floating-point numbers.", it.val)
+		} else {
+			p.panicf("Invalid float value: %q", it.val)
+		}
+	}
+	return num
+}
+
+// The formats of the offset datetime and the local types, a local type is
+// decoded into the local time zone, in a location named by the type.
+var datetimeFormats = []struct {
+	layout string
+	local  string
+	typ    tomlType
+}{
+	{"2006-01-02T15:04:05.999999999Z07:00", "", tomlDatetime},
+	{"2006-01-02T15:04:05.999999999", LocalDatetime, tomlDatetimeLocal},
+	{"2006-01-02", LocalDate, tomlDateLocal},
+	{"15:04:05.999999999", LocalTime, tomlTimeLocal},
+}
+
+// valueDatetime returns the value and type of an offset datetime, a local
+// datetime, a local date or a local time.
+func (p *parser) valueDatetime(it item) (time.Time, tomlType) {
+	val := strings.ToUpper(it.val)
+	if len(val) > 10 && val[10] == ' ' {
+		val = val[:10] + "T" + val[11:]
+	}
+	for _, dt := range datetimeFormats {
+		t, err := time.ParseInLocation(dt.layout, val, time.Local)
+		if err != nil || !datetimeDigitsOK(val, dt.layout) {
+			continue
+		}
+		if len(dt.local) > 0 {
+			t = Local(dt.local, t)
+		}
+		return t, dt.typ
+	}
+	p.panicf("Invalid TOML Datetime: %q.", it.val)
+	panic("unreachable")
+}
+
+// datetimeDigitsOK checks whether val has every digit of the date and time
+// of layout, time.Parse takes an hour like '7' as well as '07'.
+func datetimeDigitsOK(val, layout string) bool {
+	if i := strings.IndexAny(layout, ".Z"); i >= 0 {
+		layout = layout[:i]
+	}
+	if len(val) < len(layout) {
+		return false
+	}
+	for i := 0; i < len(layout); i++ {
+		if isDigit(rune(layout[i])) != isDigit(rune(val[i])) {
+			return false
+		}
+	}
+	return true
+}
+
+// numLeadingZero checks whether a decimal number, or the integer part of a
+// float, has a leading zero.
+func numLeadingZero(s string) bool {
+	s = strings.TrimLeft(s, "+-")
+	return len(s) > 1 && s[0] == '0'
+}
+
 // numUnderscoresOK checks whether each underscore in s is surrounded by
 // characters that are not underscores.
 func numUnderscoresOK(s string) bool {
@@ -362,6 +518,11 @@ func (p *parser) establishContext(key Key, array bool) {
 			hashContext[k] = make(map[string]interface{})
 		}
 
+		if p.inline[keyContext.String()] {
+			p.panicf("Key '%s' is an inline table and cannot be extended.",
+				keyContext)
+		}
+
 		// If the hash context is actually an array of tables, then set
 		// the hash context to the last element in that array.
 		//
@@ -390,6 +551,7 @@ func (p *parser) establishContext(key Key, array bool) {
 		// for something else.
 		if hash, ok := hashContext[k].([]map[string]interface{}); ok {
 			hashContext[k] = append(hash, make(map[string]interface{}))
+			p.resetTables(keyContext.add(k))
 		} else {
 			p.panicf("Key '%s' was already created and cannot be used as "+
 				"an array.", keyContext)
@@ -400,18 +562,65 @@ func (p *parser) establishContext(key Key, array bool) {
 	p.context = append(p.context, key[len(key)-1])
 }
 
-// setValue sets the given key to the given value in the current context.
-// It will make sure that the key hasn't already been defined, account for
-// implicit key groups.
-func (p *parser) setValue(key string, value interface{}) {
-	var tmpHash interface{}
-	var ok bool
+// establishDotted creates the tables of the parts of a dotted key in the
+// current context, and returns the context of the key. The tables created
+// implicitly by table headers can be extended, the ones defined by table
+// headers and the inline tables cannot.
+func (p *parser) establishDotted(parts Key) Key {
+	hash := p.contextHash()
+	context := p.context.add("")[:len(p.context)]
+	for _, k := range parts {
+		context = context.add(k)
+		switch t := hash[k].(type) {
+		case nil:
+			m := make(map[string]interface{})
+			hash[k] = m
+			p.dotted[context.String()] = true
+			p.types[context.String()] = tomlHash
+			p.ordered = append(p.ordered, context)
+			hash = m
+		case map[string]interface{}:
+			switch {
+			case p.inline[context.String()]:
+				p.panicf("Key '%s' is an inline table and cannot be extended.",
+					context)
+			case p.isImplicit(context):
+				p.removeImplicit(context)
+				p.dotted[context.String()] = true
+			case !p.dotted[context.String()]:
+				p.panicf("Key '%s' has already been defined by a table header.",
+					context)
+			}
+			hash = t
+		default:
+			p.panicf("Key '%s' has already been defined.", context)
+		}
+	}
+	return context
+}
 
+// resetTables forgets the kinds of the tables under the key of an array of
+// tables when a new element is added, they're tables of the previous element.
+func (p *parser) resetTables(key Key) {
+	prefix := key.String() + "."
+	for _, m := range []map[string]bool{p.implicits, p.dotted, p.inline} {
+		for k := range m {
+			if strings.HasPrefix(k, prefix) {
+				delete(m, k)
+			}
+		}
+	}
+}
+
+// contextHash returns the hash of the current context, the last table of an
+// array of tables.
+func (p *parser) contextHash() map[string]interface{} {
 	hash := p.mapping
 	keyContext := make(Key, 0)
 	for _, k := range p.context {
 		keyContext = append(keyContext, k)
-		if tmpHash, ok = hash[k]; !ok {
+		tmpHash, ok := hash[k]
+		if !ok {
 			p.bug("Context for key '%s' has not been established.", keyContext)
 		}
 		switch t := tmpHash.(type) {
@@ -426,7 +635,15 @@ func (p *parser) setValue(key string, value interface{}) {
 				"it has '%T' instead.", tmpHash)
 		}
 	}
-	keyContext = append(keyContext, key)
+	return hash
+}
+
+// setValue sets the given key to the given value in the current context.
+// It will make sure that the key hasn't already been defined, account for
+// implicit key groups.
+func (p *parser) setValue(key string, value interface{}) {
+	hash := p.contextHash()
+	keyContext := p.context.add(key)
 
 	if _, ok := hash[key]; ok {
 		// Typically, if the given key has already been set, then we have
@@ -497,20 +714,47 @@ func (p *parser) current() string {
 }
 
 func stripFirstNewline(s string) string {
+	if strings.HasPrefix(s, "\r\n") {
+		return s[2:]
+	}
 	if len(s) == 0 || s[0] != '\n' {
 		return s
 	}
 	return s[1:]
 }
 
-func stripEscapedWhitespace(s string) string {
-	esc := strings.Split(s, "\\\n")
-	if len(esc) > 1 {
-		for i := 1; i < len(esc); i++ {
-			esc[i] = strings.TrimLeftFunc(esc[i], unicode.IsSpace)
+// stripEscapedNewlines removes the line ending backslashes of a multi-line
+// string, with the whitespace and newlines after them. The other escape
+// sequences are kept for replaceEscapes.
+func stripEscapedNewlines(s string) string {
+	if !strings.Contains(s, "\\") {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' {
+			b.WriteByte(s[i])
+			continue
+		}
+		j := i + 1
+		for j < len(s) && (s[j] == ' ' || s[j] == '\t') {
+			j++
+		}
+		if j < len(s) && (s[j] == '\n' || s[j] == '\r') {
+			for j < len(s) && unicode.IsSpace(rune(s[j])) {
+				j++
+			}
+			i = j - 1
+			continue
+		}
+		// an escape sequence, the escaped character may be a backslash
+		b.WriteByte(s[i])
+		if i+1 < len(s) {
+			i++
+			b.WriteByte(s[i])
 		}
 	}
-	return strings.Join(esc, "")
+	return b.String()
 }
 
 func (p *parser) replaceEscapes(str string) string {
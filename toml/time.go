@@ -0,0 +1,33 @@
+package toml
+
+import "time"
+
+// The names of the locations of the local types of TOML 1.0. A local datetime,
+// date or time is decoded into a time.Time in the local time zone, with a
+// location of one of these names, so it can be told from an offset datetime
+// and encoded back to its own type.
+const (
+	LocalDatetime = "datetime-local"
+	LocalDate     = "date-local"
+	LocalTime     = "time-local"
+)
+
+// Local returns t as a local datetime, date or time, name is one of
+// LocalDatetime, LocalDate and LocalTime. The clock reading of t is kept, its
+// offset is the one of the local time zone at that instant.
+func Local(name string, t time.Time) time.Time {
+	t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(),
+		t.Second(), t.Nanosecond(), time.Local)
+	_, offset := t.Zone()
+	return t.In(time.FixedZone(name, offset))
+}
+
+// LocalType returns the name of the local type of t, or an empty string if t
+// is an offset datetime.
+func LocalType(t time.Time) string {
+	switch name := t.Location().String(); name {
+	case LocalDatetime, LocalDate, LocalTime:
+		return name
+	}
+	return ""
+}
@@ -0,0 +1,160 @@
+package toml_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yunfeiyang1916/toolkit/toml"
+	"github.com/yunfeiyang1916/toolkit/toml/internal/tag"
+)
+
+// The tests of toml-test for TOML 1.0 that don't hold, escape-esc is a test
+// of \e which was added after 1.0.
+var skipSuite = map[string]bool{
+	"valid/string/escape-esc": true,
+}
+
+// TestSuite runs the valid and invalid tests of toml-test
+// (https://github.com/toml-lang/toml-test) in the directory TOML_TEST_DIR,
+// the tests directory of a checkout of toml-test:
+//
+//	TOML_TEST_DIR=$HOME/toml-test/tests go test -run TestSuite
+func TestSuite(t *testing.T) {
+	dir := os.Getenv("TOML_TEST_DIR")
+	if dir == "" {
+		t.Skip("TOML_TEST_DIR is not set")
+	}
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() || filepath.Ext(path) != ".toml" {
+			return err
+		}
+		name, _ := filepath.Rel(dir, strings.TrimSuffix(path, ".toml"))
+		name = filepath.ToSlash(name)
+		if skipSuite[name] {
+			return nil
+		}
+		t.Run(name, func(t *testing.T) {
+			data, err := ioutil.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.HasPrefix(name, "invalid/") {
+				testInvalid(t, data)
+				return
+			}
+			want, err := ioutil.ReadFile(strings.TrimSuffix(path, ".toml") + ".json")
+			if err != nil {
+				t.Fatal(err)
+			}
+			testValid(t, data, want)
+		})
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func testInvalid(t *testing.T, data []byte) {
+	var v interface{}
+	if _, err := toml.Decode(string(data), &v); err == nil {
+		t.Fatalf("decoding succeeded:\n%s", data)
+	}
+}
+
+// testValid decodes the document and compares it with the tagged JSON, then
+// encodes the JSON and compares the decoded document with it again.
+func testValid(t *testing.T, data, tagged []byte) {
+	var j interface{}
+	if err := json.Unmarshal(tagged, &j); err != nil {
+		t.Fatal(err)
+	}
+	want, err := tag.Remove(j)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got interface{}
+	if _, err := toml.Decode(string(data), &got); err != nil {
+		t.Fatalf("decode: %v\n%s", err, data)
+	}
+	if err := compare("", want, untagged(t, got)); err != "" {
+		t.Fatalf("decode: %s\n%s", err, data)
+	}
+
+	var buf bytes.Buffer
+	if err := toml.NewEncoder(&buf).Encode(want); err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	got = nil
+	if _, err := toml.Decode(buf.String(), &got); err != nil {
+		t.Fatalf("decode the encoded document: %v\n%s", err, buf.String())
+	}
+	if err := compare("", want, untagged(t, got)); err != "" {
+		t.Fatalf("encode: %s\n%s", err, buf.String())
+	}
+}
+
+// untagged returns a decoded value as tag.Remove does, with the arrays of
+// tables as []interface{}.
+func untagged(t *testing.T, v interface{}) interface{} {
+	v, err := tag.Remove(tag.Add(v))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+func compare(key string, want, got interface{}) string {
+	switch w := want.(type) {
+	case map[string]interface{}:
+		g, ok := got.(map[string]interface{})
+		if !ok || len(g) != len(w) {
+			return key + ": got " + mustJSON(got) + ", want " + mustJSON(want)
+		}
+		for k := range w {
+			if err := compare(key+"."+k, w[k], g[k]); err != "" {
+				return err
+			}
+		}
+		return ""
+	case []interface{}:
+		g, ok := got.([]interface{})
+		if !ok || len(g) != len(w) {
+			return key + ": got " + mustJSON(got) + ", want " + mustJSON(want)
+		}
+		for i := range w {
+			if err := compare(key+"[]", w[i], g[i]); err != "" {
+				return err
+			}
+		}
+		return ""
+	case float64:
+		if g, ok := got.(float64); ok && (g == w || math.IsNaN(g) && math.IsNaN(w)) {
+			return ""
+		}
+	case time.Time:
+		if g, ok := got.(time.Time); ok && g.Equal(w) &&
+			toml.LocalType(g) == toml.LocalType(w) {
+			return ""
+		}
+	default:
+		if reflect.DeepEqual(got, want) {
+			return ""
+		}
+	}
+	return key + ": got " + mustJSON(tag.Add(got)) + ", want " + mustJSON(tag.Add(want))
+}
+
+func mustJSON(v interface{}) string {
+	b, _ := json.Marshal(v)
+	return string(b)
+}
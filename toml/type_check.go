@@ -31,14 +31,19 @@ func (btype tomlBaseType) String() string {
 }
 
 var (
-	tomlInteger   tomlBaseType = "Integer"
-	tomlFloat     tomlBaseType = "Float"
-	tomlDatetime  tomlBaseType = "Datetime"
-	tomlString    tomlBaseType = "String"
-	tomlBool      tomlBaseType = "Bool"
-	tomlArray     tomlBaseType = "Array"
-	tomlHash      tomlBaseType = "Hash"
-	tomlArrayHash tomlBaseType = "ArrayHash"
+	tomlInteger  tomlBaseType = "Integer"
+	tomlFloat    tomlBaseType = "Float"
+	tomlDatetime tomlBaseType = "Datetime"
+	// The local types of TOML 1.0, a datetime, date or time without an
+	// offset.
+	tomlDatetimeLocal tomlBaseType = "DatetimeLocal"
+	tomlDateLocal     tomlBaseType = "DateLocal"
+	tomlTimeLocal     tomlBaseType = "TimeLocal"
+	tomlString        tomlBaseType = "String"
+	tomlBool          tomlBaseType = "Bool"
+	tomlArray         tomlBaseType = "Array"
+	tomlHash          tomlBaseType = "Hash"
+	tomlArrayHash     tomlBaseType = "ArrayHash"
 )
 
 // typeOfPrimitive returns a tomlType of any primitive value in TOML.
@@ -72,20 +77,8 @@ func (p *parser) typeOfPrimitive(lexItem item) tomlType {
 // typeOfArray returns a tomlType for an array given a list of types of its
 // values.
 //
-// In the current spec, if an array is homogeneous, then its type is always
-// "Array". If the array is not homogeneous, an error is generated.
+// Since TOML 1.0 the values of an array may have different types, so its type
+// is always "Array".
 func (p *parser) typeOfArray(types []tomlType) tomlType {
-	// Empty arrays are cool.
-	if len(types) == 0 {
-		return tomlArray
-	}
-
-	theType := types[0]
-	for _, t := range types[1:] {
-		if !typeEqual(theType, t) {
-			p.panicf("Array contains values of type '%s' and '%s', but "+
-				"arrays must be homogeneous.", theType, t)
-		}
-	}
 	return tomlArray
 }
@@ -2,6 +2,7 @@ package tomlconfig
 
 import (
 	"strings"
+	"time"
 
 	"github.com/yunfeiyang1916/toolkit/toml"
 )
@@ -19,6 +20,11 @@ var (
 	Array     = "Array"
 	Hash      = "Hash"
 	ArrayHash = "ArrayHash"
+
+	// the local types of TOML 1.0, a datetime, date or time without an offset
+	DatetimeLocal = "DatetimeLocal"
+	DateLocal     = "DateLocal"
+	TimeLocal     = "TimeLocal"
 )
 
 func ParseTomlString(data string, v interface{}) error {
@@ -113,3 +119,20 @@ func (c *Config) Bool(key string, defaultValue bool) bool {
 
 	return defaultValue
 }
+
+// Time returns the value of a datetime, a local datetime, a local date or a
+// local time, toml.LocalType tells which local type it is.
+func (c *Config) Time(key string, defaultValue time.Time) (time.Time, bool) {
+
+	keys := strings.Split(key, ".")
+
+	if c.meta.IsDefined(keys...) {
+		switch c.meta.Type(keys...) {
+		case Datetime, DatetimeLocal, DateLocal, TimeLocal:
+			value := c.meta.FindValue(keys...)
+			return value.(time.Time), true
+		}
+	}
+
+	return defaultValue, false
+}
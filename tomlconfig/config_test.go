@@ -3,6 +3,9 @@ package tomlconfig
 import (
 	"fmt"
 	"testing"
+	"time"
+
+	"github.com/yunfeiyang1916/toolkit/toml"
 )
 
 type tomlDefaultConfig struct {
@@ -117,3 +120,22 @@ func TestBool(t *testing.T) {
 	value = confi.Bool("1redis.status.print", false)
 	fmt.Println(value)
 }
+
+func TestTime(t *testing.T) {
+	confi, err := NewTomlConfig("test.toml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	value, ok := confi.Time("log.rotate.at", time.Time{})
+	if !ok || value.Format("15:04:05") != "03:00:00" || toml.LocalType(value) != toml.LocalTime {
+		t.Fatalf("got %v, %v", value, ok)
+	}
+	value, ok = confi.Time("log.rotate.since", time.Time{})
+	if !ok || value.Format("2006-01-02") != "2021-06-01" || toml.LocalType(value) != toml.LocalDate {
+		t.Fatalf("got %v, %v", value, ok)
+	}
+	if _, ok = confi.Time("log.level", time.Time{}); ok {
+		t.Fatal("got a time of a string")
+	}
+}
@@ -0,0 +1,160 @@
+// configsecret encrypts, decrypts and rotates the ENC[...] config values.
+//
+//	configsecret genkey -id k2 >> keyfile
+//	configsecret encrypt -keyfile keyfile 'user:pass@tcp(127.0.0.1:3306)/db'
+//	configsecret decrypt -keyfile keyfile 'ENC[aes:k1:...]'
+//	configsecret rotate -keyfile keyfile -w config/config.toml
+//
+// rotate re-encrypts the values of a config file with the primary key, which
+// is the first key of the keyfile, the rest of the file is kept as is.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/yunfeiyang1916/toolkit/framework/config/secret"
+)
+
+var encRe = regexp.MustCompile(`ENC\[[^\]]*\]`)
+
+func usage() {
+	fmt.Fprintf(os.Stderr, `usage: configsecret <command> [flags] [args]
+
+commands:
+  genkey   print a new keyfile line
+  encrypt  encrypt the value args or stdin without the trailing newline
+  decrypt  decrypt the ENC[...] args
+  rotate   re-encrypt the ENC[...] values of files with the primary key
+`)
+	os.Exit(2)
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+	fs := flag.NewFlagSet(os.Args[1], flag.ExitOnError)
+	keyfile := fs.String("keyfile", os.Getenv(secret.KeyFileEnv), "the keyfile path, $"+secret.KeyFileEnv+" by default")
+	var err error
+	switch os.Args[1] {
+	case "genkey":
+		id := fs.String("id", "k1", "the key id")
+		fs.Parse(os.Args[2:])
+		var line string
+		if line, err = secret.GenerateKey(*id); err == nil {
+			fmt.Println(line)
+		}
+	case "encrypt":
+		fs.Parse(os.Args[2:])
+		err = encrypt(*keyfile, fs.Args())
+	case "decrypt":
+		fs.Parse(os.Args[2:])
+		err = decrypt(*keyfile, fs.Args())
+	case "rotate":
+		write := fs.Bool("w", false, "write the result to the file instead of stdout")
+		fs.Parse(os.Args[2:])
+		err = rotate(*keyfile, fs.Args(), *write)
+	default:
+		usage()
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func loadProvider(keyfile string) (*secret.AESProvider, error) {
+	if len(keyfile) == 0 {
+		return nil, fmt.Errorf("no keyfile, use -keyfile or $%s", secret.KeyFileEnv)
+	}
+	return secret.LoadKeyFile(keyfile)
+}
+
+func encrypt(keyfile string, values []string) error {
+	p, err := loadProvider(keyfile)
+	if err != nil {
+		return err
+	}
+	if len(values) == 0 {
+		b, err := ioutil.ReadAll(os.Stdin)
+		if err != nil {
+			return err
+		}
+		values = []string{strings.TrimSuffix(string(b), "\n")}
+	}
+	for _, v := range values {
+		enc, err := secret.EncryptWith(p, v)
+		if err != nil {
+			return err
+		}
+		fmt.Println(enc)
+	}
+	return nil
+}
+
+func decrypt(keyfile string, values []string) error {
+	p, err := loadProvider(keyfile)
+	if err != nil {
+		return err
+	}
+	for _, v := range values {
+		plain, err := secret.DecryptWith(p, v)
+		if err != nil {
+			return err
+		}
+		fmt.Println(plain)
+	}
+	return nil
+}
+
+func rotate(keyfile string, files []string, write bool) error {
+	p, err := loadProvider(keyfile)
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		b, err := ioutil.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		var n int
+		out := encRe.ReplaceAllFunc(b, func(v []byte) []byte {
+			if err != nil {
+				return v
+			}
+			if id, e := secret.KeyID(string(v)); e == nil && id == p.Primary() {
+				return v
+			}
+			var plain, enc string
+			if plain, err = secret.DecryptWith(p, string(v)); err != nil {
+				return v
+			}
+			if enc, err = secret.EncryptWith(p, plain); err != nil {
+				return v
+			}
+			n++
+			return []byte(enc)
+		})
+		if err != nil {
+			return fmt.Errorf("%s: %v", file, err)
+		}
+		if !write {
+			os.Stdout.Write(out)
+			continue
+		}
+		info, err := os.Stat(file)
+		if err != nil {
+			return err
+		}
+		if err = ioutil.WriteFile(file, out, info.Mode()); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "%s: %d values rotated\n", file, n)
+	}
+	return nil
+}
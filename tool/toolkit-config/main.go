@@ -0,0 +1,16 @@
+// toolkit-config renders, diffs and validates the merged config of the
+// sources a service loads.
+//
+//	toolkit-config render -f config/config.toml -remote /service_config/a/a.b.c/config.toml=remote.toml -p
+//	toolkit-config render -o json -f config/config.toml -env APP_ -set server.port=8080
+//	toolkit-config diff -f config/config.toml -f dev.toml -- -f config/config.toml -f prod.toml
+//
+// validate needs the config structs of a service, build the command in the
+// service with the structs registered, see the framework/config/cli package.
+package main
+
+import "github.com/yunfeiyang1916/toolkit/framework/config/cli"
+
+func main() {
+	cli.Main()
+}